@@ -14,11 +14,13 @@ import (
 	"github.com/gmonarque/lighthouse/internal/api/middleware"
 	"github.com/gmonarque/lighthouse/internal/comments"
 	"github.com/gmonarque/lighthouse/internal/config"
+	"github.com/gmonarque/lighthouse/internal/curator"
 	"github.com/gmonarque/lighthouse/internal/database"
 	"github.com/gmonarque/lighthouse/internal/decision"
 	"github.com/gmonarque/lighthouse/internal/indexer"
 	"github.com/gmonarque/lighthouse/internal/moderation"
 	"github.com/gmonarque/lighthouse/internal/nostr"
+	"github.com/gmonarque/lighthouse/internal/relay"
 	"github.com/gmonarque/lighthouse/internal/ruleset"
 	"github.com/gmonarque/lighthouse/internal/trust"
 	"github.com/rs/zerolog"
@@ -91,6 +93,15 @@ func main() {
 	// Initialize indexer
 	idx := indexer.New(relayManager)
 
+	// Initialize curator and run it inside the indexing pipeline
+	if cfg.Curator.Enabled {
+		if err := curator.InitGlobal(); err != nil {
+			log.Warn().Err(err).Msg("Failed to initialize curator")
+		} else {
+			idx.SetCurator(curator.Get())
+		}
+	}
+
 	// Wire up handlers to use the real indexer and relay manager
 	handlers.SetIndexerController(idx)
 	handlers.SetRelayLoader(relayManager)
@@ -107,6 +118,14 @@ func main() {
 	handlers.SetDecisionStorage(decision.NewStorage())
 	handlers.SetRulesetStorage(ruleset.NewStorage())
 
+	// Start the embedded relay, on its own address or mounted on the router.
+	// A failure is reported by the relay status endpoint.
+	relayErr := relay.InitGlobal()
+	if relayErr != nil {
+		log.Error().Err(relayErr).Msg("Failed to start relay server")
+	}
+	handlers.SetRelayServer(relay.Get(), relayErr)
+
 	// Create router
 	router := api.NewRouter(cfg)
 
@@ -160,6 +179,13 @@ func main() {
 	// Stop indexer
 	idx.Stop()
 
+	// Stop relay server, closing its WebSocket clients
+	if srv := relay.Get(); srv != nil {
+		if err := srv.Stop(); err != nil {
+			log.Error().Err(err).Msg("Relay server forced to shutdown")
+		}
+	}
+
 	// Graceful shutdown with timeout
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
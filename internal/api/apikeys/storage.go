@@ -35,13 +35,19 @@ func (s *Storage) Init() error {
 			last_used_at TEXT,
 			expires_at TEXT,
 			enabled INTEGER DEFAULT 1,
-			notes TEXT
+			notes TEXT,
+			show_borderline INTEGER DEFAULT 0
 		)
 	`)
 	if err != nil {
 		return fmt.Errorf("failed to create api_keys table: %w", err)
 	}
 
+	// Add columns introduced after the table was first created
+	if err := database.EnsureColumn("api_keys", "show_borderline", "INTEGER DEFAULT 0"); err != nil {
+		return err
+	}
+
 	// Create index on key_hash for fast lookups
 	_, err = db.Exec(`CREATE INDEX IF NOT EXISTS idx_api_keys_hash ON api_keys(key_hash)`)
 	if err != nil {
@@ -76,11 +82,17 @@ func (s *Storage) Save(key *APIKey) error {
 		enabled = 1
 	}
 
+	showBorderline := 0
+	if key.ShowBorderline {
+		showBorderline = 1
+	}
+
 	_, err = db.Exec(`
 		INSERT INTO api_keys (
 			id, name, key_hash, key_prefix, permissions, rate_limit,
-			created_by, created_at, last_used_at, expires_at, enabled, notes
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			created_by, created_at, last_used_at, expires_at, enabled, notes,
+			show_borderline
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 		ON CONFLICT(id) DO UPDATE SET
 			name = excluded.name,
 			permissions = excluded.permissions,
@@ -88,10 +100,11 @@ func (s *Storage) Save(key *APIKey) error {
 			last_used_at = excluded.last_used_at,
 			expires_at = excluded.expires_at,
 			enabled = excluded.enabled,
-			notes = excluded.notes
+			notes = excluded.notes,
+			show_borderline = excluded.show_borderline
 	`, key.ID, key.Name, key.KeyHash, key.KeyPrefix, string(permsJSON),
 		key.RateLimit, key.CreatedBy, key.CreatedAt.Format(time.RFC3339),
-		lastUsed, expires, enabled, key.Notes)
+		lastUsed, expires, enabled, key.Notes, showBorderline)
 
 	if err != nil {
 		return fmt.Errorf("failed to save API key: %w", err)
@@ -108,16 +121,17 @@ func (s *Storage) GetByID(id string) (*APIKey, error) {
 	var key APIKey
 	var permsJSON string
 	var lastUsed, expires sql.NullString
-	var enabled int
+	var enabled, showBorderline int
 
 	err := db.QueryRow(`
 		SELECT id, name, key_hash, key_prefix, permissions, rate_limit,
-		       created_by, created_at, last_used_at, expires_at, enabled, notes
+		       created_by, created_at, last_used_at, expires_at, enabled, notes,
+		       show_borderline
 		FROM api_keys WHERE id = ?
 	`, id).Scan(
 		&key.ID, &key.Name, &key.KeyHash, &key.KeyPrefix, &permsJSON,
 		&key.RateLimit, &key.CreatedBy, &key.CreatedAt, &lastUsed, &expires,
-		&enabled, &key.Notes,
+		&enabled, &key.Notes, &showBorderline,
 	)
 
 	if err == sql.ErrNoRows {
@@ -138,6 +152,7 @@ func (s *Storage) GetByID(id string) (*APIKey, error) {
 		key.ExpiresAt = &t
 	}
 	key.Enabled = enabled == 1
+	key.ShowBorderline = showBorderline == 1
 
 	return &key, nil
 }
@@ -149,17 +164,18 @@ func (s *Storage) GetByHash(keyHash string) (*APIKey, error) {
 	var key APIKey
 	var permsJSON string
 	var lastUsed, expires sql.NullString
-	var enabled int
+	var enabled, showBorderline int
 	var createdAt string
 
 	err := db.QueryRow(`
 		SELECT id, name, key_hash, key_prefix, permissions, rate_limit,
-		       created_by, created_at, last_used_at, expires_at, enabled, notes
+		       created_by, created_at, last_used_at, expires_at, enabled, notes,
+		       show_borderline
 		FROM api_keys WHERE key_hash = ?
 	`, keyHash).Scan(
 		&key.ID, &key.Name, &key.KeyHash, &key.KeyPrefix, &permsJSON,
 		&key.RateLimit, &key.CreatedBy, &createdAt, &lastUsed, &expires,
-		&enabled, &key.Notes,
+		&enabled, &key.Notes, &showBorderline,
 	)
 
 	if err == sql.ErrNoRows {
@@ -181,6 +197,7 @@ func (s *Storage) GetByHash(keyHash string) (*APIKey, error) {
 		key.ExpiresAt = &t
 	}
 	key.Enabled = enabled == 1
+	key.ShowBorderline = showBorderline == 1
 
 	return &key, nil
 }
@@ -219,7 +236,8 @@ func (s *Storage) List() ([]*APIKey, error) {
 
 	rows, err := db.Query(`
 		SELECT id, name, key_hash, key_prefix, permissions, rate_limit,
-		       created_by, created_at, last_used_at, expires_at, enabled, notes
+		       created_by, created_at, last_used_at, expires_at, enabled, notes,
+		       show_borderline
 		FROM api_keys ORDER BY created_at DESC
 	`)
 	if err != nil {
@@ -232,13 +250,13 @@ func (s *Storage) List() ([]*APIKey, error) {
 		var key APIKey
 		var permsJSON string
 		var lastUsed, expires sql.NullString
-		var enabled int
+		var enabled, showBorderline int
 		var createdAt string
 
 		err := rows.Scan(
 			&key.ID, &key.Name, &key.KeyHash, &key.KeyPrefix, &permsJSON,
 			&key.RateLimit, &key.CreatedBy, &createdAt, &lastUsed, &expires,
-			&enabled, &key.Notes,
+			&enabled, &key.Notes, &showBorderline,
 		)
 		if err != nil {
 			continue
@@ -255,6 +273,7 @@ func (s *Storage) List() ([]*APIKey, error) {
 			key.ExpiresAt = &t
 		}
 		key.Enabled = enabled == 1
+		key.ShowBorderline = showBorderline == 1
 
 		// Clear hash before returning
 		key.KeyHash = ""
@@ -22,6 +22,9 @@ type APIKey struct {
 	ExpiresAt   *time.Time   `json:"expires_at,omitempty"`
 	Enabled     bool         `json:"enabled"`
 	Notes       string       `json:"notes,omitempty"`
+
+	// ShowBorderline includes torrents rejected only for probabilistic reasons in Torznab results
+	ShowBorderline bool `json:"show_borderline"`
 }
 
 // Permission represents an API permission
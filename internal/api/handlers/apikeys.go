@@ -29,11 +29,12 @@ func GetAPIKeys(w http.ResponseWriter, r *http.Request) {
 // CreateAPIKey creates a new API key
 func CreateAPIKey(w http.ResponseWriter, r *http.Request) {
 	var req struct {
-		Name        string   `json:"name"`
-		Permissions []string `json:"permissions"`
-		RateLimit   int      `json:"rate_limit"`
-		ExpiresIn   int      `json:"expires_in"` // Days until expiry, 0 = never
-		Notes       string   `json:"notes"`
+		Name           string   `json:"name"`
+		Permissions    []string `json:"permissions"`
+		RateLimit      int      `json:"rate_limit"`
+		ExpiresIn      int      `json:"expires_in"` // Days until expiry, 0 = never
+		Notes          string   `json:"notes"`
+		ShowBorderline bool     `json:"show_borderline"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -63,6 +64,7 @@ func CreateAPIKey(w http.ResponseWriter, r *http.Request) {
 	// Set optional fields
 	key.RateLimit = req.RateLimit
 	key.Notes = req.Notes
+	key.ShowBorderline = req.ShowBorderline
 
 	if req.ExpiresIn > 0 {
 		expires := time.Now().AddDate(0, 0, req.ExpiresIn)
@@ -120,10 +122,11 @@ func UpdateAPIKey(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req struct {
-		Name        string   `json:"name"`
-		Permissions []string `json:"permissions"`
-		RateLimit   int      `json:"rate_limit"`
-		Notes       string   `json:"notes"`
+		Name           string   `json:"name"`
+		Permissions    []string `json:"permissions"`
+		RateLimit      int      `json:"rate_limit"`
+		Notes          string   `json:"notes"`
+		ShowBorderline *bool    `json:"show_borderline"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -156,6 +159,9 @@ func UpdateAPIKey(w http.ResponseWriter, r *http.Request) {
 	}
 	key.RateLimit = req.RateLimit
 	key.Notes = req.Notes
+	if req.ShowBorderline != nil {
+		key.ShowBorderline = *req.ShowBorderline
+	}
 
 	if err := storage.Save(key); err != nil {
 		respondError(w, http.StatusInternalServerError, "Failed to update API key")
@@ -1,11 +1,15 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gmonarque/lighthouse/internal/comments"
+	"github.com/gmonarque/lighthouse/internal/database"
+	"github.com/gmonarque/lighthouse/internal/nostr"
 	"github.com/go-chi/chi/v5"
 )
 
@@ -141,6 +145,17 @@ type AddCommentRequest struct {
 	RootID         string   `json:"root_id,omitempty"`
 	AuthorPubkey   string   `json:"author_pubkey,omitempty"`
 	Mentions       []string `json:"mentions,omitempty"`
+
+	// Publish signs the comment and sends it to relays
+	Publish  bool   `json:"publish,omitempty"`
+	Identity string `json:"identity,omitempty"` // npub of a stored identity, defaults to the node identity
+	RelayIDs []int  `json:"relay_ids,omitempty"`
+}
+
+// PublishCommentResponse is the response after publishing a comment
+type PublishCommentResponse struct {
+	EventID string                `json:"event_id"`
+	Results []nostr.PublishResult `json:"results"`
 }
 
 // AddComment adds a new comment
@@ -183,6 +198,11 @@ func AddComment(w http.ResponseWriter, r *http.Request) {
 	}
 	comment.Mentions = req.Mentions
 
+	if req.Publish {
+		publishComment(w, r, comment, req)
+		return
+	}
+
 	if err := commentStorage.Save(comment); err != nil {
 		respondError(w, http.StatusInternalServerError, "Failed to save comment: "+err.Error())
 		return
@@ -194,6 +214,52 @@ func AddComment(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// publishComment signs a comment, stores the signed copy and publishes it to relays
+func publishComment(w http.ResponseWriter, r *http.Request, comment *comments.Comment, req AddCommentRequest) {
+	if publisher == nil {
+		respondError(w, http.StatusInternalServerError, "Publisher not initialized")
+		return
+	}
+
+	nsec, err := resolveSigningKey(req.Identity)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	privateKey, err := nostr.NsecToHex(nsec)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Invalid signing key")
+		return
+	}
+
+	// Signing sets the real event ID and signature on the comment
+	event, err := comment.ToNostrEvent(privateKey)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to sign comment: "+err.Error())
+		return
+	}
+	comment.AuthorPubkey = event.PubKey
+
+	// Store before publishing so a comment on relays is never missing locally
+	if err := commentStorage.Save(comment); err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to save comment: "+err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	results := publisher.PublishToRelays(ctx, event, req.RelayIDs)
+
+	database.LogActivity("comment_published", comment.Infohash)
+
+	respondJSON(w, http.StatusOK, PublishCommentResponse{
+		EventID: event.ID,
+		Results: results,
+	})
+}
+
 // GetCommentThread returns a comment thread
 func GetCommentThread(w http.ResponseWriter, r *http.Request) {
 	eventID := chi.URLParam(r, "eventId")
@@ -24,6 +24,28 @@ type DecisionResponse struct {
 	Signature      string   `json:"signature,omitempty"`
 }
 
+// decisionResponse converts a decision for API responses
+func decisionResponse(d *decision.VerificationDecision) DecisionResponse {
+	reasonCodes := make([]string, len(d.ReasonCodes))
+	for i, rc := range d.ReasonCodes {
+		reasonCodes[i] = string(rc)
+	}
+
+	return DecisionResponse{
+		DecisionID:     d.DecisionID,
+		Decision:       string(d.Decision),
+		ReasonCodes:    reasonCodes,
+		TargetEventID:  d.TargetEventID,
+		TargetInfohash: d.TargetInfohash,
+		CuratorPubkey:  d.CuratorPubkey,
+		RulesetType:    d.RulesetType,
+		RulesetVersion: d.RulesetVersion,
+		RulesetHash:    d.RulesetHash,
+		CreatedAt:      d.CreatedAt.Format("2006-01-02T15:04:05Z"),
+		Signature:      d.Signature,
+	}
+}
+
 // decisionStorage is the storage instance
 var decisionStorage *decision.Storage
 
@@ -83,24 +105,7 @@ func GetDecisions(w http.ResponseWriter, r *http.Request) {
 
 	response := make([]DecisionResponse, 0, len(decisions))
 	for _, d := range decisions {
-		reasonCodes := make([]string, len(d.ReasonCodes))
-		for i, rc := range d.ReasonCodes {
-			reasonCodes[i] = string(rc)
-		}
-
-		response = append(response, DecisionResponse{
-			DecisionID:     d.DecisionID,
-			Decision:       string(d.Decision),
-			ReasonCodes:    reasonCodes,
-			TargetEventID:  d.TargetEventID,
-			TargetInfohash: d.TargetInfohash,
-			CuratorPubkey:  d.CuratorPubkey,
-			RulesetType:    d.RulesetType,
-			RulesetVersion: d.RulesetVersion,
-			RulesetHash:    d.RulesetHash,
-			CreatedAt:      d.CreatedAt.Format("2006-01-02T15:04:05Z"),
-			Signature:      d.Signature,
-		})
+		response = append(response, decisionResponse(d))
 	}
 
 	respondJSON(w, http.StatusOK, map[string]interface{}{
@@ -137,22 +142,7 @@ func GetDecisionsByInfohash(w http.ResponseWriter, r *http.Request) {
 	var hasLegalReject bool
 
 	for _, d := range decisions {
-		reasonCodes := make([]string, len(d.ReasonCodes))
-		for i, rc := range d.ReasonCodes {
-			reasonCodes[i] = string(rc)
-		}
-
-		response = append(response, DecisionResponse{
-			DecisionID:     d.DecisionID,
-			Decision:       string(d.Decision),
-			ReasonCodes:    reasonCodes,
-			TargetEventID:  d.TargetEventID,
-			TargetInfohash: d.TargetInfohash,
-			CuratorPubkey:  d.CuratorPubkey,
-			RulesetType:    d.RulesetType,
-			RulesetVersion: d.RulesetVersion,
-			CreatedAt:      d.CreatedAt.Format("2006-01-02T15:04:05Z"),
-		})
+		response = append(response, decisionResponse(d))
 
 		if d.Decision == decision.DecisionAccept {
 			acceptCount++
@@ -0,0 +1,233 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+
+	"github.com/gmonarque/lighthouse/internal/config"
+	"github.com/gmonarque/lighthouse/internal/database"
+	"github.com/gmonarque/lighthouse/internal/decision"
+	"github.com/gmonarque/lighthouse/internal/indexer"
+	"github.com/gmonarque/lighthouse/internal/nostr"
+	"github.com/gmonarque/lighthouse/internal/trust"
+	"github.com/go-chi/chi/v5"
+)
+
+// ExplainTrust explains why an npub is trusted or not: its whitelist and
+// blacklist entries, the follow paths from a trust root and its propagated score
+func ExplainTrust(w http.ResponseWriter, r *http.Request) {
+	npub := chi.URLParam(r, "npub")
+
+	if _, err := nostr.NpubToHex(npub); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid npub format")
+		return
+	}
+
+	explanation, err := trust.NewWebOfTrust().Explain(npub)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to explain trust")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, explanation)
+}
+
+// uploadExplanation explains whether an upload makes its torrent visible
+type uploadExplanation struct {
+	EventID        string             `json:"event_id"`
+	Uploader       string             `json:"uploader"`
+	Pubkey         string             `json:"pubkey"`
+	EventCreatedAt int64              `json:"event_created_at,omitempty"`
+	Status         string             `json:"status"`
+	Trust          *trust.Explanation `json:"trust,omitempty"`
+}
+
+// scoreComponent is the part of a torrent's trust score one uploader adds
+type scoreComponent struct {
+	Uploader     string   `json:"uploader"`
+	Score        *float64 `json:"score"`
+	Contribution float64  `json:"contribution"`
+}
+
+// ExplainTorrent explains why a torrent is shown or hidden: which uploads are
+// trusted and why, the curator decisions and their aggregation, and how its
+// trust score adds up
+func ExplainTorrent(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid torrent ID")
+		return
+	}
+
+	db := database.Get()
+
+	var infoHash, name string
+	var trustScore, uploadCount int64
+	var curationStatus sql.NullString
+	err = db.QueryRow(`
+		SELECT info_hash, name, trust_score, upload_count, curation_status
+		FROM torrents WHERE id = ?
+	`, id).Scan(&infoHash, &name, &trustScore, &uploadCount, &curationStatus)
+	if err == sql.ErrNoRows {
+		respondError(w, http.StatusNotFound, "Torrent not found")
+		return
+	} else if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to get torrent")
+		return
+	}
+
+	wot := trust.NewWebOfTrust()
+	policy, err := wot.GetUploaderPolicy()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to get trusted uploaders")
+		return
+	}
+
+	uploads, err := explainUploads(db, wot, policy, id)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to explain uploads")
+		return
+	}
+
+	trusted := false
+	for _, u := range uploads {
+		if u.Status == trust.UploadTrusted {
+			trusted = true
+		}
+	}
+
+	components, computed, err := trustScoreComponents(id)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to explain trust score")
+		return
+	}
+
+	// Search hides torrents rejected by the curator
+	status := curationStatus.String
+	hidden := status == "rejected" || status == "borderline"
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"id":           id,
+		"info_hash":    infoHash,
+		"name":         name,
+		"upload_count": uploadCount,
+		"visible":      trusted && !hidden,
+		"trusted":      trusted,
+		"uploads":      uploads,
+		"trust_score": map[string]interface{}{
+			"stored":     trustScore,
+			"computed":   computed,
+			"base":       indexer.TrustScoreBase,
+			"components": components,
+		},
+		"curation": explainCuration(infoHash, status),
+	})
+}
+
+// explainUploads judges every upload of a torrent against the uploader policy
+func explainUploads(db *sql.DB, wot *trust.WebOfTrust, policy *trust.UploaderPolicy, torrentID int64) ([]uploadExplanation, error) {
+	rows, err := db.Query(`
+		SELECT nostr_event_id, uploader_npub,
+			COALESCE(event_created_at, CAST(strftime('%s', uploaded_at) AS INTEGER))
+		FROM torrent_uploads WHERE torrent_id = ?
+		ORDER BY uploaded_at
+	`, torrentID)
+	if err != nil {
+		return nil, err
+	}
+
+	uploads := make([]uploadExplanation, 0)
+	for rows.Next() {
+		var u uploadExplanation
+		if err := rows.Scan(&u.EventID, &u.Pubkey, &u.EventCreatedAt); err != nil {
+			continue
+		}
+		u.Status = policy.Judge(u.Pubkey, u.EventCreatedAt)
+		uploads = append(uploads, u)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Explain each uploader once
+	explained := make(map[string]*trust.Explanation)
+	for i := range uploads {
+		npub, err := nostr.HexToNpub(uploads[i].Pubkey)
+		if err != nil {
+			continue
+		}
+		uploads[i].Uploader = npub
+
+		if _, ok := explained[npub]; !ok {
+			explained[npub], _ = wot.Explain(npub)
+		}
+		uploads[i].Trust = explained[npub]
+	}
+
+	return uploads, nil
+}
+
+// trustScoreComponents returns what each distinct uploader adds to a torrent's
+// trust score, and the score they add up to
+func trustScoreComponents(torrentID int64) ([]scoreComponent, int64, error) {
+	parts, computed, err := indexer.TrustScoreComponents(torrentID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	components := make([]scoreComponent, 0, len(parts))
+	for _, part := range parts {
+		c := scoreComponent{Uploader: part.Pubkey, Score: part.Score, Contribution: part.Contribution}
+		if npub, err := nostr.HexToNpub(part.Pubkey); err == nil {
+			c.Uploader = npub
+		}
+		components = append(components, c)
+	}
+	return components, computed, nil
+}
+
+// explainCuration returns the curator decisions on a torrent and the outcome
+// of their aggregation
+func explainCuration(infoHash, status string) map[string]interface{} {
+	cfg := config.Get()
+
+	curation := map[string]interface{}{
+		"status":           status,
+		"enabled":          cfg.Curator.Enabled,
+		"mode":             cfg.Curator.Mode,
+		"aggregation_mode": cfg.Curator.AggregationMode,
+		"quorum_required":  cfg.Curator.QuorumRequired,
+		"decisions":        []DecisionResponse{},
+	}
+
+	if decisionStorage == nil {
+		return curation
+	}
+
+	decisions, err := decisionStorage.GetByInfohash(infoHash)
+	if err != nil {
+		return curation
+	}
+
+	// Decisions not aggregated yet are picked up on the next aggregation run
+	responses := make([]DecisionResponse, 0, len(decisions))
+	pending := false
+	var aggregated *decision.Decision
+	for _, d := range decisions {
+		if d.AggregatedDecision == nil {
+			pending = true
+		} else if aggregated == nil {
+			aggregated = d.AggregatedDecision
+		}
+		responses = append(responses, decisionResponse(d))
+	}
+
+	curation["decisions"] = responses
+	curation["aggregation_pending"] = pending
+	if aggregated != nil {
+		curation["aggregated_decision"] = *aggregated
+	}
+	return curation
+}
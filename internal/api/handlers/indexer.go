@@ -2,10 +2,12 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/gmonarque/lighthouse/internal/database"
+	"github.com/gmonarque/lighthouse/internal/indexer"
 )
 
 // IndexerController interface for controlling the indexer
@@ -14,6 +16,10 @@ type IndexerController interface {
 	Stop()
 	IsRunning() bool
 	FetchHistorical(days int) error
+	Reindex() error
+	GetReindexProgress() indexer.ReindexProgress
+	RefreshTrustedAuthors()
+	SyncTrustLists()
 }
 
 // RelayLoader interface for loading relays from database
@@ -160,3 +166,35 @@ func ResyncIndexer(w http.ResponseWriter, r *http.Request) {
 		"days":    days,
 	})
 }
+
+// ReindexIndexer replays all stored torrent events through the current rules
+func ReindexIndexer(w http.ResponseWriter, r *http.Request) {
+	if indexerController == nil {
+		respondError(w, http.StatusInternalServerError, "Indexer not initialized")
+		return
+	}
+
+	if err := indexerController.Reindex(); err != nil {
+		if errors.Is(err, indexer.ErrReindexRunning) {
+			respondError(w, http.StatusConflict, "Reindex already running")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "Failed to start reindex: "+err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusAccepted, map[string]string{
+		"status":  "started",
+		"message": "Reindex started",
+	})
+}
+
+// GetReindexStatus returns the progress of the current or last reindex
+func GetReindexStatus(w http.ResponseWriter, r *http.Request) {
+	if indexerController == nil {
+		respondError(w, http.StatusInternalServerError, "Indexer not initialized")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, indexerController.GetReindexProgress())
+}
@@ -2,7 +2,9 @@ package handlers
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"regexp"
 	"time"
@@ -26,6 +28,26 @@ func SetPublisher(p Publisher) {
 	publisher = p
 }
 
+// resolveSigningKey returns the nsec for a stored identity, or the node identity if npub is empty
+func resolveSigningKey(npub string) (string, error) {
+	cfg := config.Get()
+
+	if npub == "" || npub == cfg.Nostr.Identity.Npub {
+		if cfg.Nostr.Identity.Nsec == "" {
+			return "", errors.New("no identity configured, generate or import an nsec in settings")
+		}
+		return cfg.Nostr.Identity.Nsec, nil
+	}
+
+	var nsec sql.NullString
+	err := database.Get().QueryRow("SELECT nsec FROM identities WHERE npub = ?", npub).Scan(&nsec)
+	if err != nil || !nsec.Valid || nsec.String == "" {
+		return "", errors.New("no signing key stored for identity " + npub)
+	}
+
+	return nsec.String, nil
+}
+
 // ParseTorrentFile handles parsing of uploaded .torrent files
 func ParseTorrentFile(w http.ResponseWriter, r *http.Request) {
 	// Parse multipart form with 10MB limit
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gmonarque/lighthouse/internal/config"
+	"github.com/gmonarque/lighthouse/internal/relay"
+)
+
+// relayServer is the embedded Nostr relay, nil when it is disabled or could not be created
+var relayServer *relay.Server
+
+// relayServerErr is why the embedded relay failed to start, if it did
+var relayServerErr error
+
+// SetRelayServer sets the embedded relay instance and the error it failed to start with
+func SetRelayServer(s *relay.Server, err error) {
+	relayServer = s
+	relayServerErr = err
+}
+
+// GetRelayServerStatus returns the status and statistics of the embedded relay
+func GetRelayServerStatus(w http.ResponseWriter, r *http.Request) {
+	cfg := config.Get()
+
+	status := map[string]interface{}{
+		"enabled": cfg.Relay.Enabled,
+		"mode":    cfg.Relay.Mode,
+	}
+	if relayServerErr != nil {
+		status["error"] = relayServerErr.Error()
+	}
+
+	if relayServer == nil {
+		status["stats"] = relay.RelayStats{}
+		respondJSON(w, http.StatusOK, status)
+		return
+	}
+
+	if relayServer.Mounted() {
+		status["path"] = relay.MountPath
+	} else {
+		status["listen"] = cfg.Relay.Listen
+	}
+	status["stats"] = relayServer.GetStats()
+
+	respondJSON(w, http.StatusOK, status)
+}
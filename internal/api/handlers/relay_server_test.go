@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/gmonarque/lighthouse/internal/config"
+)
+
+func TestGetRelayServerStatusError(t *testing.T) {
+	wd, _ := os.Getwd()
+	os.Chdir(t.TempDir())
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	if _, err := config.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if err := config.Update("relay.enabled", true); err != nil {
+		t.Fatal(err)
+	}
+
+	SetRelayServer(nil, errors.New(`invalid kind "x" in relay access rules`))
+	t.Cleanup(func() { SetRelayServer(nil, nil) })
+
+	rec := httptest.NewRecorder()
+	GetRelayServerStatus(rec, httptest.NewRequest(http.MethodGet, "/api/relay/status", nil))
+
+	var status map[string]interface{}
+	if err := json.NewDecoder(rec.Body).Decode(&status); err != nil {
+		t.Fatal(err)
+	}
+	if status["enabled"] != true {
+		t.Errorf("enabled = %v, want true", status["enabled"])
+	}
+	if status["error"] != `invalid kind "x" in relay access rules` {
+		t.Errorf("error = %v, want the start error", status["error"])
+	}
+	if stats, ok := status["stats"].(map[string]interface{}); !ok || stats["running"] != false {
+		t.Errorf("stats = %v, want a stopped relay", status["stats"])
+	}
+}
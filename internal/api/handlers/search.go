@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gmonarque/lighthouse/internal/database"
 	"github.com/gmonarque/lighthouse/internal/nostr"
@@ -17,6 +18,7 @@ func Search(w http.ResponseWriter, r *http.Request) {
 	category := r.URL.Query().Get("category")
 	limitParam := r.URL.Query().Get("limit")
 	offsetParam := r.URL.Query().Get("offset")
+	tags := parseTagParams(r)
 
 	limit := 50
 	if limitParam != "" {
@@ -46,16 +48,14 @@ func Search(w http.ResponseWriter, r *http.Request) {
 	db := database.Get()
 
 	// Get trusted uploaders for filtering
-	wot := trust.NewWebOfTrust()
-	trustedUploaders, err := wot.GetTrustedUploaders()
+	policy, err := trust.NewWebOfTrust().GetUploaderPolicy()
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, "Failed to get trusted uploaders")
 		return
 	}
 
-	// Build trust filter subquery
 	// If no trusted uploaders, return empty results
-	if len(trustedUploaders) == 0 {
+	if len(policy.Trusted) == 0 {
 		respondJSON(w, http.StatusOK, map[string]interface{}{
 			"results": []interface{}{},
 			"total":   0,
@@ -65,40 +65,8 @@ func Search(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Convert npubs to hex pubkeys for matching (uploads are stored as hex)
-	var trustedHexPubkeys []string
-	for _, npubOrHex := range trustedUploaders {
-		// Try to convert from npub to hex
-		if hexPk, err := nostr.NpubToHex(npubOrHex); err == nil {
-			trustedHexPubkeys = append(trustedHexPubkeys, hexPk)
-		} else {
-			// Already hex or invalid, use as-is
-			trustedHexPubkeys = append(trustedHexPubkeys, npubOrHex)
-		}
-	}
-
-	// If no valid pubkeys after conversion, return empty
-	if len(trustedHexPubkeys) == 0 {
-		respondJSON(w, http.StatusOK, map[string]interface{}{
-			"results": []interface{}{},
-			"total":   0,
-			"limit":   limit,
-			"offset":  offset,
-		})
-		return
-	}
-
-	// Build placeholders for IN clause
-	trustPlaceholders := "("
-	trustArgs := make([]interface{}, len(trustedHexPubkeys))
-	for i, u := range trustedHexPubkeys {
-		if i > 0 {
-			trustPlaceholders += ","
-		}
-		trustPlaceholders += "?"
-		trustArgs[i] = u
-	}
-	trustPlaceholders += ")"
+	// Uploads by trusted uploaders, within the time bounds of their entries
+	trustCondition, trustArgs := policy.UploadCondition("tu")
 
 	var rows *sql.Rows
 
@@ -106,9 +74,20 @@ func Search(w http.ResponseWriter, r *http.Request) {
 	trustExistsClause := `EXISTS (
 				SELECT 1 FROM torrent_uploads tu
 				WHERE tu.torrent_id = t.id
-				AND tu.uploader_npub IN ` + trustPlaceholders + `
+				AND ` + trustCondition + `
 			)`
 
+	// Hide torrents rejected by the curator
+	curationClause := ` AND (t.curation_status IS NULL OR t.curation_status NOT IN ('rejected', 'borderline'))`
+
+	// Require every requested tag
+	tagClause := ""
+	tagArgs := make([]interface{}, len(tags))
+	for i, tag := range tags {
+		tagClause += ` AND t.id IN (SELECT torrent_id FROM torrent_tags WHERE tag = ?)`
+		tagArgs[i] = tag
+	}
+
 	if query != "" {
 		// Full-text search with trust filtering
 		sqlQuery := `
@@ -117,10 +96,11 @@ func Search(w http.ResponseWriter, r *http.Request) {
 			FROM torrents t
 			JOIN torrents_fts fts ON t.id = fts.rowid
 			WHERE torrents_fts MATCH ?
-			AND ` + trustExistsClause
+			AND ` + trustExistsClause + curationClause + tagClause
 
 		args := []interface{}{query}
 		args = append(args, trustArgs...)
+		args = append(args, tagArgs...)
 
 		if category != "" {
 			if isBaseCategory {
@@ -147,9 +127,10 @@ func Search(w http.ResponseWriter, r *http.Request) {
 			SELECT t.id, t.info_hash, t.name, t.size, t.category, t.seeders, t.leechers,
 				   t.magnet_uri, t.title, t.year, t.poster_url, t.overview, t.trust_score, t.first_seen_at
 			FROM torrents t INDEXED BY idx_torrents_category_trust_seen
-			WHERE ` + trustExistsClause
+			WHERE ` + trustExistsClause + curationClause + tagClause
 
 		args := append([]interface{}{}, trustArgs...)
+		args = append(args, tagArgs...)
 
 		if isBaseCategory {
 			sqlQuery += " AND t.category >= ? AND t.category < ?"
@@ -169,10 +150,11 @@ func Search(w http.ResponseWriter, r *http.Request) {
 			SELECT t.id, t.info_hash, t.name, t.size, t.category, t.seeders, t.leechers,
 				   t.magnet_uri, t.title, t.year, t.poster_url, t.overview, t.trust_score, t.first_seen_at
 			FROM torrents t INDEXED BY idx_torrents_trust_first_seen
-			WHERE ` + trustExistsClause + `
+			WHERE ` + trustExistsClause + curationClause + tagClause + `
 			ORDER BY t.trust_score DESC, t.first_seen_at DESC LIMIT ? OFFSET ?`
 
 		args := append([]interface{}{}, trustArgs...)
+		args = append(args, tagArgs...)
 		args = append(args, limit, offset)
 
 		rows, err = db.Query(sqlQuery, args...)
@@ -223,10 +205,11 @@ func Search(w http.ResponseWriter, r *http.Request) {
 			SELECT COUNT(*) FROM torrents t
 			JOIN torrents_fts fts ON t.id = fts.rowid
 			WHERE torrents_fts MATCH ?
-			AND ` + trustExistsClause
+			AND ` + trustExistsClause + curationClause + tagClause
 
 		countArgs := []interface{}{query}
 		countArgs = append(countArgs, trustArgs...)
+		countArgs = append(countArgs, tagArgs...)
 		if category != "" {
 			if isBaseCategory {
 				countQuery += " AND t.category >= ? AND t.category < ?"
@@ -245,17 +228,21 @@ func Search(w http.ResponseWriter, r *http.Request) {
 		// but the indexer already filters by trusted authors at ingest time, so
 		// the difference is negligible in practice.
 		if isBaseCategory {
-			db.QueryRow(`SELECT COUNT(*) FROM torrents WHERE category >= ? AND category < ?`,
-				categoryNum, categoryNum+1000).Scan(&total)
+			countArgs := append([]interface{}{categoryNum, categoryNum + 1000}, tagArgs...)
+			db.QueryRow(`SELECT COUNT(*) FROM torrents t WHERE t.category >= ? AND t.category < ?`+curationClause+tagClause,
+				countArgs...).Scan(&total)
 		} else {
-			db.QueryRow(`SELECT COUNT(*) FROM torrents WHERE category = ?`,
-				categoryNum).Scan(&total)
+			countArgs := append([]interface{}{categoryNum}, tagArgs...)
+			db.QueryRow(`SELECT COUNT(*) FROM torrents t WHERE t.category = ?`+curationClause+tagClause,
+				countArgs...).Scan(&total)
 		}
 	} else {
 		// No filters: count distinct torrents from trusted uploaders
-		countQuery := `SELECT COUNT(DISTINCT torrent_id) FROM torrent_uploads
-			WHERE uploader_npub IN ` + trustPlaceholders
-		db.QueryRow(countQuery, trustArgs...).Scan(&total)
+		countQuery := `SELECT COUNT(DISTINCT tu.torrent_id) FROM torrent_uploads tu
+			JOIN torrents t ON t.id = tu.torrent_id
+			WHERE ` + trustCondition + curationClause + tagClause
+		countArgs := append(append([]interface{}{}, trustArgs...), tagArgs...)
+		db.QueryRow(countQuery, countArgs...).Scan(&total)
 	}
 
 	respondJSON(w, http.StatusOK, map[string]interface{}{
@@ -266,6 +253,20 @@ func Search(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// parseTagParams returns the tags requested with repeated or comma-separated tag parameters
+func parseTagParams(r *http.Request) []string {
+	var tags []string
+	for _, value := range r.URL.Query()["tag"] {
+		for _, tag := range strings.Split(value, ",") {
+			tag = strings.ToLower(strings.TrimSpace(tag))
+			if tag != "" {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	return tags
+}
+
 // ListTorrents returns paginated list of torrents
 func ListTorrents(w http.ResponseWriter, r *http.Request) {
 	Search(w, r)
@@ -283,8 +284,9 @@ func GetTorrent(w http.ResponseWriter, r *http.Request) {
 	db := database.Get()
 	row := db.QueryRow(`
 		SELECT id, info_hash, name, size, category, seeders, leechers,
-			   magnet_uri, files, title, year, tmdb_id, imdb_id, poster_url,
+			   magnet_uri, files, title, year, tmdb_id, imdb_id, tvdb_id, poster_url,
 			   backdrop_url, overview, genres, rating, trust_score, upload_count,
+			   curation_status, external_id_conflict, description, trackers, metadata_conflict,
 			   first_seen_at, updated_at
 		FROM torrents WHERE id = ?
 	`, id)
@@ -303,6 +305,7 @@ func GetTorrent(w http.ResponseWriter, r *http.Request) {
 		Year        sql.NullInt64
 		TmdbID      sql.NullInt64
 		ImdbID      sql.NullString
+		TvdbID      sql.NullInt64
 		PosterURL   sql.NullString
 		BackdropURL sql.NullString
 		Overview    sql.NullString
@@ -310,6 +313,11 @@ func GetTorrent(w http.ResponseWriter, r *http.Request) {
 		Rating      sql.NullFloat64
 		TrustScore  int64
 		UploadCount int64
+		Curation    sql.NullString
+		IDConflict  sql.NullBool
+		Description sql.NullString
+		Trackers    sql.NullString
+		Conflict    sql.NullBool
 		FirstSeenAt string
 		UpdatedAt   string
 	}
@@ -318,9 +326,10 @@ func GetTorrent(w http.ResponseWriter, r *http.Request) {
 		&torrent.ID, &torrent.InfoHash, &torrent.Name, &torrent.Size,
 		&torrent.Category, &torrent.Seeders, &torrent.Leechers, &torrent.MagnetURI,
 		&torrent.Files, &torrent.Title, &torrent.Year, &torrent.TmdbID,
-		&torrent.ImdbID, &torrent.PosterURL, &torrent.BackdropURL, &torrent.Overview,
+		&torrent.ImdbID, &torrent.TvdbID, &torrent.PosterURL, &torrent.BackdropURL, &torrent.Overview,
 		&torrent.Genres, &torrent.Rating, &torrent.TrustScore, &torrent.UploadCount,
-		&torrent.FirstSeenAt, &torrent.UpdatedAt,
+		&torrent.Curation, &torrent.IDConflict, &torrent.Description, &torrent.Trackers,
+		&torrent.Conflict, &torrent.FirstSeenAt, &torrent.UpdatedAt,
 	); err != nil {
 		if err == sql.ErrNoRows {
 			respondError(w, http.StatusNotFound, "Torrent not found")
@@ -332,7 +341,7 @@ func GetTorrent(w http.ResponseWriter, r *http.Request) {
 
 	// Get uploaders
 	rows, err := db.Query(`
-		SELECT uploader_npub, nostr_event_id, relay_url, uploaded_at
+		SELECT uploader_npub, nostr_event_id, relay_url, name, uploaded_at
 		FROM torrent_uploads
 		WHERE torrent_id = ?
 		ORDER BY uploaded_at ASC
@@ -346,7 +355,8 @@ func GetTorrent(w http.ResponseWriter, r *http.Request) {
 	uploaders := make([]map[string]interface{}, 0)
 	for rows.Next() {
 		var hexPubkey, eventID, relayURL, uploadedAt string
-		if err := rows.Scan(&hexPubkey, &eventID, &relayURL, &uploadedAt); err != nil {
+		var uploadName sql.NullString
+		if err := rows.Scan(&hexPubkey, &eventID, &relayURL, &uploadName, &uploadedAt); err != nil {
 			continue
 		}
 		// Convert hex pubkey to npub format
@@ -358,10 +368,57 @@ func GetTorrent(w http.ResponseWriter, r *http.Request) {
 			"npub":        npub,
 			"event_id":    eventID,
 			"relay_url":   relayURL,
+			"name":        uploadName.String,
 			"uploaded_at": uploadedAt,
 		})
 	}
 
+	// Get external IDs supplied by uploaders or found by enrichment
+	externalIDs := make([]map[string]interface{}, 0)
+	idRows, err := db.Query(`
+		SELECT id_type, media_type, value, source
+		FROM torrent_external_ids
+		WHERE torrent_id = ?
+		ORDER BY id ASC
+	`, id)
+	if err == nil {
+		defer idRows.Close()
+		for idRows.Next() {
+			var idType, value, source string
+			var mediaType sql.NullString
+			if err := idRows.Scan(&idType, &mediaType, &value, &source); err != nil {
+				continue
+			}
+			// Uploader sources are hex pubkeys
+			if npub, err := nostr.HexToNpub(source); err == nil {
+				source = npub
+			}
+			externalIDs = append(externalIDs, map[string]interface{}{
+				"type":       idType,
+				"media_type": mediaType.String,
+				"value":      value,
+				"source":     source,
+			})
+		}
+	}
+
+	// Get content tags merged across uploads
+	tags := make([]string, 0)
+	tagRows, err := db.Query(`
+		SELECT tag FROM torrent_tags
+		WHERE torrent_id = ?
+		ORDER BY upload_count DESC, tag ASC
+	`, id)
+	if err == nil {
+		defer tagRows.Close()
+		for tagRows.Next() {
+			var tag string
+			if err := tagRows.Scan(&tag); err == nil {
+				tags = append(tags, tag)
+			}
+		}
+	}
+
 	respondJSON(w, http.StatusOK, map[string]interface{}{
 		"id":            torrent.ID,
 		"info_hash":     torrent.InfoHash,
@@ -376,6 +433,7 @@ func GetTorrent(w http.ResponseWriter, r *http.Request) {
 		"year":          torrent.Year.Int64,
 		"tmdb_id":       torrent.TmdbID.Int64,
 		"imdb_id":       torrent.ImdbID.String,
+		"tvdb_id":       torrent.TvdbID.Int64,
 		"poster_url":    torrent.PosterURL.String,
 		"backdrop_url":  torrent.BackdropURL.String,
 		"overview":      torrent.Overview.String,
@@ -383,7 +441,14 @@ func GetTorrent(w http.ResponseWriter, r *http.Request) {
 		"rating":        torrent.Rating.Float64,
 		"trust_score":   torrent.TrustScore,
 		"upload_count":  torrent.UploadCount,
+		"curation":      torrent.Curation.String,
 		"uploaders":     uploaders,
+		"tags":          tags,
+		"external_ids":  externalIDs,
+		"id_conflict":   torrent.IDConflict.Bool,
+		"description":   torrent.Description.String,
+		"trackers":      torrent.Trackers.String,
+		"meta_conflict": torrent.Conflict.Bool,
 		"first_seen_at": torrent.FirstSeenAt,
 		"updated_at":    torrent.UpdatedAt,
 	})
@@ -35,7 +35,9 @@ func GetSettings(w http.ResponseWriter, r *http.Request) {
 			"relays": cfg.Nostr.Relays,
 		},
 		"trust": map[string]interface{}{
-			"depth": cfg.Trust.Depth,
+			"depth":         cfg.Trust.Depth,
+			"min_score":     cfg.Trust.MinScore,
+			"publish_lists": cfg.Trust.PublishLists,
 		},
 		"enrichment": map[string]interface{}{
 			"enabled":      cfg.Enrichment.Enabled,
@@ -53,6 +55,14 @@ func GetSettings(w http.ResponseWriter, r *http.Request) {
 			"require_curation": cfg.Relay.RequireCuration,
 			"sync_with":        cfg.Relay.SyncWith,
 			"enable_discovery": cfg.Relay.EnableDiscovery,
+			"name":             cfg.Relay.Name,
+			"description":      cfg.Relay.Description,
+			"contact":          cfg.Relay.Contact,
+			"access": map[string]interface{}{
+				"read":  cfg.Relay.Access.Read,
+				"write": cfg.Relay.Access.Write,
+				"kinds": cfg.Relay.Access.Kinds,
+			},
 		},
 	})
 }
@@ -74,6 +84,9 @@ func UpdateSettings(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// Trust depth or identity may have changed
+	refreshTrustedAuthors()
+
 	respondJSON(w, http.StatusOK, map[string]string{"status": "updated"})
 }
 
@@ -200,6 +213,7 @@ func ImportConfig(w http.ResponseWriter, r *http.Request) {
 	}
 
 	database.LogActivity("config_imported", "")
+	refreshTrustedAuthors()
 
 	respondJSON(w, http.StatusOK, map[string]string{"status": "imported"})
 }
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"context"
 	"encoding/xml"
 	"net/http"
 	"strconv"
@@ -9,6 +10,7 @@ import (
 	"github.com/gmonarque/lighthouse/internal/api/middleware"
 	"github.com/gmonarque/lighthouse/internal/config"
 	"github.com/gmonarque/lighthouse/internal/torznab"
+	"github.com/gmonarque/lighthouse/internal/trust"
 )
 
 // Torznab handles all Torznab API requests
@@ -33,6 +35,8 @@ func Torznab(w http.ResponseWriter, r *http.Request) {
 		if key, err := storage.ValidateKey(apiKey); err == nil && key != nil {
 			if key.HasAnyPermission(apikeys.PermissionTorznab, apikeys.PermissionAdmin) {
 				authenticated = true
+				// Keep the key so per-key search settings apply
+				r = r.WithContext(context.WithValue(r.Context(), middleware.APIKeyContextKey, key))
 			}
 		}
 	}
@@ -93,7 +97,19 @@ func handleTVSearch(w http.ResponseWriter, r *http.Request) {
 		params.Season, _ = strconv.Atoi(s)
 	}
 	if e := r.URL.Query().Get("ep"); e != "" {
-		params.Episode, _ = strconv.Atoi(e)
+		if airDate, ok := torznab.ParseDailyEpisode(params.Season, e); ok {
+			params.AirDate = airDate
+		} else {
+			params.Episode, _ = strconv.Atoi(e)
+		}
+	}
+
+	// Parse IMDB/TVDB IDs
+	if imdb := r.URL.Query().Get("imdbid"); imdb != "" {
+		params.ImdbID = torznab.NormalizeImdbID(imdb)
+	}
+	if tvdb := r.URL.Query().Get("tvdbid"); tvdb != "" {
+		params.TvdbID, _ = strconv.Atoi(tvdb)
 	}
 
 	executeSearch(w, r, params)
@@ -205,6 +221,18 @@ func parseSearchParams(r *http.Request) torznab.SearchParams {
 func executeSearch(w http.ResponseWriter, r *http.Request, params torznab.SearchParams) {
 	service := torznab.NewService()
 
+	// Apply the same trust filter as the UI search
+	policy, err := trust.NewWebOfTrust().GetUploaderPolicy()
+	if err != nil {
+		respondTorznabError(w, torznab.ErrorNoResults, "Search failed")
+		return
+	}
+	params.TrustedUploads, params.TrustedUploadsArgs = policy.UploadCondition("tu")
+
+	if key := middleware.GetAPIKeyFromContext(r.Context()); key != nil {
+		params.ShowBorderline = key.ShowBorderline
+	}
+
 	results, total, err := service.Search(params)
 	if err != nil {
 		respondTorznabError(w, torznab.ErrorNoResults, "Search failed")
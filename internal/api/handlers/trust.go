@@ -4,19 +4,23 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"strconv"
 
 	"github.com/gmonarque/lighthouse/internal/config"
 	"github.com/gmonarque/lighthouse/internal/database"
 	"github.com/gmonarque/lighthouse/internal/nostr"
+	"github.com/gmonarque/lighthouse/internal/trust"
 	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
 )
 
 // GetWhitelist returns all whitelisted npubs
 func GetWhitelist(w http.ResponseWriter, r *http.Request) {
 	db := database.Get()
 	rows, err := db.Query(`
-		SELECT id, npub, alias, notes, added_at
+		SELECT id, npub, alias, notes, COALESCE(source, 'manual'), list_id, added_at, effective_from, effective_until
 		FROM trust_whitelist
 		ORDER BY added_at DESC
 	`)
@@ -29,20 +33,27 @@ func GetWhitelist(w http.ResponseWriter, r *http.Request) {
 	entries := make([]map[string]interface{}, 0)
 	for rows.Next() {
 		var id int64
-		var npub, addedAt string
+		var npub, source, addedAt string
 		var alias, notes sql.NullString
+		var listID, effectiveFrom, effectiveUntil sql.NullInt64
 
-		if err := rows.Scan(&id, &npub, &alias, &notes, &addedAt); err != nil {
+		if err := rows.Scan(&id, &npub, &alias, &notes, &source, &listID, &addedAt, &effectiveFrom, &effectiveUntil); err != nil {
 			continue
 		}
 
-		entries = append(entries, map[string]interface{}{
+		entry := map[string]interface{}{
 			"id":       id,
 			"npub":     npub,
 			"alias":    alias.String,
 			"notes":    notes.String,
+			"source":   source,
 			"added_at": addedAt,
-		})
+		}
+		if listID.Valid {
+			entry["list_id"] = listID.Int64
+		}
+		addEffectiveBounds(entry, trust.Window{From: effectiveFrom.Int64, Until: effectiveUntil.Int64})
+		entries = append(entries, entry)
 	}
 
 	respondJSON(w, http.StatusOK, entries)
@@ -54,6 +65,7 @@ func AddToWhitelist(w http.ResponseWriter, r *http.Request) {
 		Npub  string `json:"npub"`
 		Alias string `json:"alias"`
 		Notes string `json:"notes"`
+		trust.Window
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -65,35 +77,39 @@ func AddToWhitelist(w http.ResponseWriter, r *http.Request) {
 		respondError(w, http.StatusBadRequest, "npub is required")
 		return
 	}
+	if err := req.Window.Validate(); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 
-	db := database.Get()
-	result, err := db.Exec(`
-		INSERT INTO trust_whitelist (npub, alias, notes)
-		VALUES (?, ?, ?)
-		ON CONFLICT(npub) DO UPDATE SET
-			alias = excluded.alias,
-			notes = excluded.notes
-	`, req.Npub, req.Alias, req.Notes)
-
-	if err != nil {
+	if err := trust.NewWhitelist().Add(req.Npub, req.Alias, req.Notes, req.Window); err != nil {
 		respondError(w, http.StatusInternalServerError, "Failed to add to whitelist")
 		return
 	}
 
-	id, _ := result.LastInsertId()
-	database.LogActivity("whitelist_add", req.Npub)
+	var id int64
+	database.Get().QueryRow("SELECT id FROM trust_whitelist WHERE npub = ?", req.Npub).Scan(&id)
+	resyncTrustLists()
+	refreshTrustedAuthors()
 
-	respondJSON(w, http.StatusCreated, map[string]interface{}{
+	entry := map[string]interface{}{
 		"id":    id,
 		"npub":  req.Npub,
 		"alias": req.Alias,
-	})
+	}
+	addEffectiveBounds(entry, req.Window)
+	respondJSON(w, http.StatusCreated, entry)
 }
 
 // RemoveFromWhitelist removes an npub from the whitelist and deletes their torrents
 func RemoveFromWhitelist(w http.ResponseWriter, r *http.Request) {
 	npub := chi.URLParam(r, "npub")
 
+	if trust.IsListEntry("trust_whitelist", npub) {
+		respondError(w, http.StatusConflict, "npub is synced from a follow set; remove it from the list or unsubscribe from the list")
+		return
+	}
+
 	db := database.Get()
 
 	// Begin transaction
@@ -161,6 +177,8 @@ func RemoveFromWhitelist(w http.ResponseWriter, r *http.Request) {
 	}
 
 	database.LogActivity("whitelist_remove", npub)
+	resyncTrustLists()
+	refreshTrustedAuthors()
 	respondJSON(w, http.StatusOK, map[string]string{"status": "removed"})
 }
 
@@ -168,7 +186,7 @@ func RemoveFromWhitelist(w http.ResponseWriter, r *http.Request) {
 func GetBlacklist(w http.ResponseWriter, r *http.Request) {
 	db := database.Get()
 	rows, err := db.Query(`
-		SELECT id, npub, reason, added_at
+		SELECT id, npub, reason, COALESCE(source, 'manual'), list_id, added_at, effective_from, effective_until
 		FROM trust_blacklist
 		ORDER BY added_at DESC
 	`)
@@ -181,29 +199,39 @@ func GetBlacklist(w http.ResponseWriter, r *http.Request) {
 	entries := make([]map[string]interface{}, 0)
 	for rows.Next() {
 		var id int64
-		var npub, addedAt string
+		var npub, source, addedAt string
 		var reason sql.NullString
+		var listID, effectiveFrom, effectiveUntil sql.NullInt64
 
-		if err := rows.Scan(&id, &npub, &reason, &addedAt); err != nil {
+		if err := rows.Scan(&id, &npub, &reason, &source, &listID, &addedAt, &effectiveFrom, &effectiveUntil); err != nil {
 			continue
 		}
 
-		entries = append(entries, map[string]interface{}{
+		entry := map[string]interface{}{
 			"id":       id,
 			"npub":     npub,
 			"reason":   reason.String,
+			"source":   source,
 			"added_at": addedAt,
-		})
+		}
+		if listID.Valid {
+			entry["list_id"] = listID.Int64
+		}
+		addEffectiveBounds(entry, trust.Window{From: effectiveFrom.Int64, Until: effectiveUntil.Int64})
+		entries = append(entries, entry)
 	}
 
 	respondJSON(w, http.StatusOK, entries)
 }
 
-// AddToBlacklist adds an npub to the blacklist and removes their content
+// AddToBlacklist adds an npub to the blacklist and removes their content.
+// With effective_from or effective_until only the uploads created within
+// those bounds are blocked and removed, for keys that were compromised.
 func AddToBlacklist(w http.ResponseWriter, r *http.Request) {
 	var req struct {
 		Npub   string `json:"npub"`
 		Reason string `json:"reason"`
+		trust.Window
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -215,87 +243,50 @@ func AddToBlacklist(w http.ResponseWriter, r *http.Request) {
 		respondError(w, http.StatusBadRequest, "npub is required")
 		return
 	}
-
-	db := database.Get()
-
-	// Convert npub to hex for torrent_uploads queries (uploads are stored as hex pubkey)
-	hexPubkey, err := nostr.NpubToHex(req.Npub)
-	if err != nil {
-		// If conversion fails, try using as-is (might already be hex)
-		hexPubkey = req.Npub
-	}
-
-	// Begin transaction
-	tx, err := db.Begin()
-	if err != nil {
-		respondError(w, http.StatusInternalServerError, "Failed to start transaction")
+	if err := req.Window.Validate(); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
 		return
 	}
-	defer tx.Rollback()
 
-	// Add to blacklist
-	_, err = tx.Exec(`
-		INSERT INTO trust_blacklist (npub, reason)
-		VALUES (?, ?)
-		ON CONFLICT(npub) DO UPDATE SET reason = excluded.reason
-	`, req.Npub, req.Reason)
+	// Trust scores are recalculated once the blacklist change reaches the trust graph
+	deleted, err := trust.NewBlacklist().Add(req.Npub, req.Reason, req.Window)
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, "Failed to add to blacklist")
 		return
 	}
 
-	// Remove from whitelist if present
-	tx.Exec("DELETE FROM trust_whitelist WHERE npub = ?", req.Npub)
+	resyncTrustLists()
+	refreshTrustedAuthors()
 
-	// Delete all torrents from this uploader
-	// First, get torrent IDs that only have this uploader
-	_, err = tx.Exec(`
-		DELETE FROM torrents
-		WHERE id IN (
-			SELECT torrent_id FROM torrent_uploads
-			WHERE uploader_npub = ?
-			GROUP BY torrent_id
-			HAVING COUNT(DISTINCT uploader_npub) = 1
-		)
-	`, hexPubkey)
-	if err != nil {
-		respondError(w, http.StatusInternalServerError, "Failed to delete torrents")
-		return
+	entry := map[string]interface{}{
+		"npub":             req.Npub,
+		"reason":           req.Reason,
+		"status":           "blocked",
+		"torrents_deleted": deleted,
 	}
+	addEffectiveBounds(entry, req.Window)
+	respondJSON(w, http.StatusCreated, entry)
+}
 
-	// Remove upload records
-	_, err = tx.Exec("DELETE FROM torrent_uploads WHERE uploader_npub = ?", hexPubkey)
-	if err != nil {
-		respondError(w, http.StatusInternalServerError, "Failed to delete upload records")
-		return
+// addEffectiveBounds adds the time bounds of a whitelist or blacklist entry to its JSON
+func addEffectiveBounds(entry map[string]interface{}, window trust.Window) {
+	if window.From != 0 {
+		entry["effective_from"] = window.From
 	}
-
-	// Update upload counts for remaining torrents
-	_, err = tx.Exec(`
-		UPDATE torrents SET
-			upload_count = (SELECT COUNT(*) FROM torrent_uploads WHERE torrent_id = torrents.id),
-			trust_score = trust_score - 10
-		WHERE id IN (SELECT DISTINCT torrent_id FROM torrent_uploads)
-	`)
-
-	if err := tx.Commit(); err != nil {
-		respondError(w, http.StatusInternalServerError, "Failed to commit transaction")
-		return
+	if window.Until != 0 {
+		entry["effective_until"] = window.Until
 	}
-
-	database.LogActivity("blacklist_add", req.Npub)
-
-	respondJSON(w, http.StatusCreated, map[string]interface{}{
-		"npub":   req.Npub,
-		"reason": req.Reason,
-		"status": "blocked",
-	})
 }
 
 // RemoveFromBlacklist removes an npub from the blacklist
 func RemoveFromBlacklist(w http.ResponseWriter, r *http.Request) {
 	npub := chi.URLParam(r, "npub")
 
+	if trust.IsListEntry("trust_blacklist", npub) {
+		respondError(w, http.StatusConflict, "npub is synced from a mute list; remove it from the list or unsubscribe from the list")
+		return
+	}
+
 	db := database.Get()
 	result, err := db.Exec("DELETE FROM trust_blacklist WHERE npub = ?", npub)
 	if err != nil {
@@ -310,6 +301,8 @@ func RemoveFromBlacklist(w http.ResponseWriter, r *http.Request) {
 	}
 
 	database.LogActivity("blacklist_remove", npub)
+	resyncTrustLists()
+	refreshTrustedAuthors()
 	respondJSON(w, http.StatusOK, map[string]string{"status": "removed"})
 }
 
@@ -318,14 +311,18 @@ func GetTrustSettings(w http.ResponseWriter, r *http.Request) {
 	cfg := config.Get()
 
 	respondJSON(w, http.StatusOK, map[string]interface{}{
-		"depth": cfg.Trust.Depth,
+		"depth":         cfg.Trust.Depth,
+		"min_score":     cfg.Trust.MinScore,
+		"publish_lists": cfg.Trust.PublishLists,
 	})
 }
 
 // UpdateTrustSettings updates trust configuration
 func UpdateTrustSettings(w http.ResponseWriter, r *http.Request) {
 	var req struct {
-		Depth int `json:"depth"`
+		Depth        int      `json:"depth"`
+		MinScore     *float64 `json:"min_score"`
+		PublishLists *bool    `json:"publish_lists"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -338,16 +335,202 @@ func UpdateTrustSettings(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if req.MinScore != nil && (*req.MinScore < 0 || *req.MinScore >= 100) {
+		respondError(w, http.StatusBadRequest, "Minimum score must be between 0 and 100")
+		return
+	}
+
 	if err := config.Update("trust.depth", req.Depth); err != nil {
 		respondError(w, http.StatusInternalServerError, "Failed to update settings")
 		return
 	}
+	if req.MinScore != nil {
+		if err := config.Update("trust.min_score", *req.MinScore); err != nil {
+			respondError(w, http.StatusInternalServerError, "Failed to update settings")
+			return
+		}
+	}
+	if req.PublishLists != nil {
+		if *req.PublishLists && config.Get().Nostr.Identity.Nsec == "" {
+			respondError(w, http.StatusBadRequest, "Publishing lists requires a node identity")
+			return
+		}
+		if err := config.Update("trust.publish_lists", *req.PublishLists); err != nil {
+			respondError(w, http.StatusInternalServerError, "Failed to update settings")
+			return
+		}
+	}
+	refreshTrustedAuthors()
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"depth":         req.Depth,
+		"min_score":     config.Get().Trust.MinScore,
+		"publish_lists": config.Get().Trust.PublishLists,
+	})
+}
+
+// GetTrustScores returns propagated trust scores from highest to lowest
+func GetTrustScores(w http.ResponseWriter, r *http.Request) {
+	limit := 50
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 && l <= 500 {
+		limit = l
+	}
+	offset := 0
+	if o, err := strconv.Atoi(r.URL.Query().Get("offset")); err == nil && o >= 0 {
+		offset = o
+	}
+
+	scores, total, err := trust.NewWebOfTrust().GetScores(limit, offset)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to get trust scores")
+		return
+	}
 
 	respondJSON(w, http.StatusOK, map[string]interface{}{
-		"depth": req.Depth,
+		"scores":    scores,
+		"total":     total,
+		"limit":     limit,
+		"offset":    offset,
+		"min_score": config.Get().Trust.MinScore,
 	})
 }
 
+// GetTrustScore returns the propagated trust score of an npub and the largest contributions to it
+func GetTrustScore(w http.ResponseWriter, r *http.Request) {
+	npub := chi.URLParam(r, "npub")
+
+	if _, err := nostr.NpubToHex(npub); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid npub format")
+		return
+	}
+
+	score, err := trust.NewWebOfTrust().GetScore(npub)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to get trust score")
+		return
+	}
+	if score == nil {
+		respondError(w, http.StatusNotFound, "npub is not in the trust graph")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, score)
+}
+
+// GetTrustLists returns the NIP-51 lists synced into the whitelist and blacklist
+func GetTrustLists(w http.ResponseWriter, r *http.Request) {
+	lists, err := trust.NewListSubscriptions().GetAll()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to get trust lists")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, lists)
+}
+
+// AddTrustList subscribes to a NIP-51 mute list, follow set or mute set. The list owner
+// defaults to our own identity.
+func AddTrustList(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Npub string `json:"npub"`
+		Kind int    `json:"kind"`
+		DTag string `json:"d_tag"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.Npub == "" {
+		req.Npub = config.Get().Nostr.Identity.Npub
+		if req.Npub == "" {
+			respondError(w, http.StatusBadRequest, "npub is required when no identity is configured")
+			return
+		}
+	}
+	if _, err := nostr.NpubToHex(req.Npub); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid npub format")
+		return
+	}
+	if _, ok := trust.ListTarget(req.Kind); !ok {
+		respondError(w, http.StatusBadRequest, "kind must be 10000 (mute list), 30000 (follow set) or 30007 (mute set)")
+		return
+	}
+	if req.Kind != nostr.KindMuteList && req.DTag == "" {
+		respondError(w, http.StatusBadRequest, "d_tag is required for follow sets and mute sets")
+		return
+	}
+
+	list, err := trust.NewListSubscriptions().Add(req.Npub, req.Kind, req.DTag)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to add trust list")
+		return
+	}
+
+	database.LogActivity("trust_list_added", fmt.Sprintf("%s list of %s", list.Target, list.OwnerNpub))
+	syncTrustLists()
+
+	respondJSON(w, http.StatusCreated, list)
+}
+
+// RemoveTrustList unsubscribes from a NIP-51 list and removes the entries it imported
+func RemoveTrustList(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid list ID")
+		return
+	}
+
+	found, err := trust.NewListSubscriptions().Remove(id)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to remove trust list")
+		return
+	}
+	if !found {
+		respondError(w, http.StatusNotFound, "Trust list not found")
+		return
+	}
+
+	database.LogActivity("trust_list_removed", strconv.FormatInt(id, 10))
+	refreshTrustedAuthors()
+	syncTrustLists()
+
+	respondJSON(w, http.StatusOK, map[string]string{"status": "removed"})
+}
+
+// SyncTrustLists fetches the latest version of all subscribed lists
+func SyncTrustLists(w http.ResponseWriter, r *http.Request) {
+	if indexerController == nil || !indexerController.IsRunning() {
+		respondError(w, http.StatusServiceUnavailable, "Indexer is not running")
+		return
+	}
+
+	syncTrustLists()
+	respondJSON(w, http.StatusAccepted, map[string]string{"status": "syncing"})
+}
+
+// resyncTrustLists reapplies the subscribed lists after a manual whitelist or blacklist change
+func resyncTrustLists() {
+	if err := trust.NewListSubscriptions().Resync(); err != nil {
+		log.Error().Err(err).Msg("Failed to resync trust lists")
+	}
+}
+
+// syncTrustLists lets a running indexer fetch and follow the subscribed lists
+func syncTrustLists() {
+	if indexerController != nil {
+		go indexerController.SyncTrustLists()
+	}
+}
+
+// refreshTrustedAuthors lets a running indexer pick up a changed trusted uploader set
+func refreshTrustedAuthors() {
+	if indexerController != nil {
+		go indexerController.RefreshTrustedAuthors()
+	}
+}
+
 // RelayDiscoverer interface for discovering user relays
 type RelayDiscoverer interface {
 	DiscoverAndAddUserRelays(ctx context.Context, npub string) (int, error)
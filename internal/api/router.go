@@ -9,6 +9,7 @@ import (
 	"github.com/gmonarque/lighthouse/internal/api/handlers"
 	apiMiddleware "github.com/gmonarque/lighthouse/internal/api/middleware"
 	"github.com/gmonarque/lighthouse/internal/config"
+	"github.com/gmonarque/lighthouse/internal/relay"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
@@ -42,6 +43,11 @@ func NewRouter(cfg *config.Config) *chi.Mux {
 	// Health check (no auth required)
 	r.Get("/health", handlers.HealthCheck)
 
+	// Embedded Nostr relay, when it has no listen address of its own
+	if srv := relay.Get(); srv != nil && srv.Mounted() {
+		r.Mount(relay.MountPath, srv.Handler(relay.MountPath))
+	}
+
 	// API routes
 	r.Route("/api", func(r chi.Router) {
 		// Rate limiting by IP (applies to all API requests)
@@ -75,6 +81,7 @@ func NewRouter(cfg *config.Config) *chi.Mux {
 			r.Get("/search", handlers.Search)
 			r.Get("/torrents", handlers.ListTorrents)
 			r.Get("/torrents/{id}", handlers.GetTorrent)
+			r.Get("/torrents/{id}/explain", handlers.ExplainTorrent)
 			r.Delete("/torrents/{id}", handlers.DeleteTorrent)
 
 			// Trust management
@@ -92,6 +99,15 @@ func NewRouter(cfg *config.Config) *chi.Mux {
 				r.Get("/settings", handlers.GetTrustSettings)
 				r.Put("/settings", handlers.UpdateTrustSettings)
 
+				r.Get("/scores", handlers.GetTrustScores)
+				r.Get("/scores/{npub}", handlers.GetTrustScore)
+				r.Get("/explain/{npub}", handlers.ExplainTrust)
+
+				r.Get("/lists", handlers.GetTrustLists)
+				r.Post("/lists", handlers.AddTrustList)
+				r.Post("/lists/sync", handlers.SyncTrustLists)
+				r.Delete("/lists/{id}", handlers.RemoveTrustList)
+
 				// Curator management (federated trust)
 				r.Get("/curators", handlers.GetCurators)
 				r.Post("/curators", handlers.AddCurator)
@@ -158,6 +174,9 @@ func NewRouter(cfg *config.Config) *chi.Mux {
 				r.Post("/{id}/disconnect", handlers.DisconnectRelay)
 			})
 
+			// Embedded relay
+			r.Get("/relay/status", handlers.GetRelayServerStatus)
+
 			// Settings
 			r.Route("/settings", func(r chi.Router) {
 				r.Get("/", handlers.GetSettings)
@@ -194,6 +213,8 @@ func NewRouter(cfg *config.Config) *chi.Mux {
 			r.Post("/indexer/stop", handlers.StopIndexer)
 			r.Post("/indexer/resync", handlers.ResyncIndexer)
 			r.Get("/indexer/status", handlers.GetIndexerStatus)
+			r.Post("/indexer/reindex", handlers.ReindexIndexer)
+			r.Get("/indexer/reindex", handlers.GetReindexStatus)
 
 			// Publish torrent
 			r.Post("/publish/parse-torrent", handlers.ParseTorrentFile)
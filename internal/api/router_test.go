@@ -0,0 +1,70 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gmonarque/lighthouse/internal/config"
+	"github.com/gmonarque/lighthouse/internal/relay"
+	"github.com/gorilla/websocket"
+)
+
+func TestRelayMount(t *testing.T) {
+	wd, _ := os.Getwd()
+	os.Chdir(t.TempDir())
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	if _, err := config.Load(); err != nil {
+		t.Fatal(err)
+	}
+	for key, value := range map[string]interface{}{"relay.enabled": true, "relay.listen": ""} {
+		if err := config.Update(key, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := relay.InitGlobal(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { relay.Get().Stop() })
+
+	ts := httptest.NewServer(NewRouter(config.Get()))
+	t.Cleanup(ts.Close)
+
+	// The NIP-11 document is served with and without a trailing slash
+	for _, path := range []string{relay.MountPath, relay.MountPath + "/"} {
+		req, _ := http.NewRequest(http.MethodGet, ts.URL+path, nil)
+		req.Header.Set("Accept", "application/nostr+json")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var info relay.RelayInfo
+		err = json.NewDecoder(resp.Body).Decode(&info)
+		resp.Body.Close()
+		if err != nil || resp.StatusCode != http.StatusOK {
+			t.Fatalf("GET %s: status %d, %v", path, resp.StatusCode, err)
+		}
+		if info.Name != config.Get().Relay.Name {
+			t.Errorf("GET %s: name = %q, want %q", path, info.Name, config.Get().Relay.Name)
+		}
+	}
+
+	// WebSocket clients reach the relay, which opens with a NIP-42 challenge
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+relay.MountPath, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	var msg []interface{}
+	if err := conn.ReadJSON(&msg); err != nil {
+		t.Fatal(err)
+	}
+	if len(msg) != 2 || msg[0] != "AUTH" {
+		t.Errorf("first message = %v, want an AUTH challenge", msg)
+	}
+}
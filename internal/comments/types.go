@@ -140,10 +140,13 @@ func FromNostrEvent(event *nostr.Event) (*Comment, error) {
 			if len(tag) >= 4 {
 				switch tag[3] {
 				case "root":
+					// The first root marker references the torrent event,
+					// a second one the root comment of the thread
 					if c.TorrentEventID == "" {
 						c.TorrentEventID = tag[1]
+					} else {
+						c.RootID = tag[1]
 					}
-					c.RootID = tag[1]
 				case "reply":
 					c.ParentID = tag[1]
 				default:
@@ -165,6 +168,11 @@ func FromNostrEvent(event *nostr.Event) (*Comment, error) {
 		}
 	}
 
+	// Direct replies carry no separate root marker
+	if c.ParentID != "" && c.RootID == "" {
+		c.RootID = c.ParentID
+	}
+
 	return c, nil
 }
 
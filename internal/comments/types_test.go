@@ -0,0 +1,55 @@
+package comments
+
+import (
+	"testing"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+func TestFromNostrEvent_Threading(t *testing.T) {
+	sk := nostr.GeneratePrivateKey()
+
+	tests := []struct {
+		name     string
+		parentID string
+		rootID   string
+		wantRoot string
+	}{
+		{"top level", "", "", ""},
+		{"direct reply", "parent", "", "parent"},
+		{"nested reply", "parent", "root", "root"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewComment("abc123", "hello", "")
+			c.SetTorrentEvent("torrent")
+			if tt.parentID != "" {
+				c.SetParent(tt.parentID, tt.rootID)
+			}
+
+			event, err := c.ToNostrEvent(sk)
+			if err != nil {
+				t.Fatalf("ToNostrEvent failed: %v", err)
+			}
+
+			parsed, err := FromNostrEvent(event)
+			if err != nil {
+				t.Fatalf("FromNostrEvent failed: %v", err)
+			}
+
+			if parsed.TorrentEventID != "torrent" {
+				t.Errorf("TorrentEventID = %q, want %q", parsed.TorrentEventID, "torrent")
+			}
+			if parsed.ParentID != tt.parentID {
+				t.Errorf("ParentID = %q, want %q", parsed.ParentID, tt.parentID)
+			}
+			if parsed.RootID != tt.wantRoot {
+				t.Errorf("RootID = %q, want %q", parsed.RootID, tt.wantRoot)
+			}
+			if parsed.Infohash != "abc123" {
+				t.Errorf("Infohash = %q, want %q", parsed.Infohash, "abc123")
+			}
+		})
+	}
+}
@@ -50,6 +50,18 @@ type RelayConfig struct {
 
 type TrustConfig struct {
 	Depth int `mapstructure:"depth"`
+	// CrawlInterval is the number of hours between follow graph crawls
+	CrawlInterval int `mapstructure:"crawl_interval"`
+	// CrawlWhitelist also crawls the follows of whitelisted users
+	CrawlWhitelist bool `mapstructure:"crawl_whitelist"`
+	// MaxFollowsPerUser caps the follows read from a single contact list
+	MaxFollowsPerUser int `mapstructure:"max_follows_per_user"`
+	// MaxGraphSize caps the total number of follow edges stored
+	MaxGraphSize int `mapstructure:"max_graph_size"`
+	// MinScore is the propagated trust score an uploader must exceed to be indexed
+	MinScore float64 `mapstructure:"min_score"`
+	// PublishLists publishes the whitelist and blacklist as NIP-51 lists signed with the node identity
+	PublishLists bool `mapstructure:"publish_lists"`
 }
 
 type EnrichmentConfig struct {
@@ -93,6 +105,26 @@ type RelayServerConfig struct {
 	SyncWith []string `mapstructure:"sync_with"`
 	// EnableDiscovery enable relay discovery via Nostr
 	EnableDiscovery bool `mapstructure:"enable_discovery"`
+	// Name of the relay in its NIP-11 document
+	Name string `mapstructure:"name"`
+	// Description of the relay in its NIP-11 document
+	Description string `mapstructure:"description"`
+	// Contact of the operator in the NIP-11 document
+	Contact string `mapstructure:"contact"`
+	// Access rules of a community relay
+	Access RelayAccessConfig `mapstructure:"access"`
+}
+
+// RelayAccessConfig decides who may use a community relay. Levels are "open",
+// "authenticated" (any NIP-42 authenticated pubkey) or "trusted" (authenticated
+// whitelisted or WoT-trusted pubkeys).
+type RelayAccessConfig struct {
+	// Read level for subscriptions
+	Read string `mapstructure:"read"`
+	// Write level for publishing events
+	Write string `mapstructure:"write"`
+	// Kinds overrides Write per event kind
+	Kinds map[string]string `mapstructure:"kinds"`
 }
 
 var cfg *Config
@@ -180,6 +212,12 @@ func setDefaults() {
 
 	// Trust defaults
 	viper.SetDefault("trust.depth", 1)
+	viper.SetDefault("trust.crawl_interval", 6)
+	viper.SetDefault("trust.crawl_whitelist", false)
+	viper.SetDefault("trust.max_follows_per_user", 1000)
+	viper.SetDefault("trust.max_graph_size", 100000)
+	viper.SetDefault("trust.min_score", 0)
+	viper.SetDefault("trust.publish_lists", false)
 
 	// Enrichment defaults
 	viper.SetDefault("enrichment.tmdb_api_key", "")
@@ -204,6 +242,12 @@ func setDefaults() {
 	viper.SetDefault("relay.require_curation", true)
 	viper.SetDefault("relay.sync_with", []string{})
 	viper.SetDefault("relay.enable_discovery", false)
+	viper.SetDefault("relay.name", "Lighthouse Relay")
+	viper.SetDefault("relay.description", "Torrent metadata relay run by a Lighthouse node")
+	viper.SetDefault("relay.contact", "")
+	viper.SetDefault("relay.access.read", "open")
+	viper.SetDefault("relay.access.write", "trusted")
+	viper.SetDefault("relay.access.kinds", map[string]string{})
 }
 
 func createDefaultConfig() error {
@@ -0,0 +1,160 @@
+package database
+
+import (
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+)
+
+// columnMigration describes a column added to a table after its creation
+type columnMigration struct {
+	Table      string
+	Column     string
+	Definition string
+}
+
+// columnMigrations lists columns added to existing tables. New databases get
+// them from schema.sql, older ones are altered on startup.
+var columnMigrations = []columnMigration{
+	{"torrents", "season", "INTEGER"},
+	{"torrents", "season_end", "INTEGER"},
+	{"torrents", "episode", "INTEGER"},
+	{"torrents", "episode_end", "INTEGER"},
+	{"torrents", "air_date", "TEXT"},
+	{"torrents", "tvdb_id", "INTEGER"},
+	{"torrents", "external_id_conflict", "INTEGER DEFAULT 0"},
+	{"torrents", "trackers", "TEXT"},
+	{"torrents", "description", "TEXT"},
+	{"torrents", "metadata_conflict", "INTEGER DEFAULT 0"},
+	{"torrent_uploads", "name", "TEXT"},
+	{"torrent_uploads", "size", "INTEGER"},
+	{"torrent_uploads", "category", "INTEGER"},
+	{"torrent_uploads", "description", "TEXT"},
+	{"torrent_uploads", "magnet_uri", "TEXT"},
+	{"torrent_uploads", "trackers", "TEXT"},
+	{"torrent_uploads", "files", "TEXT"},
+	{"torrent_uploads", "tags", "TEXT"},
+	{"torrent_uploads", "event_created_at", "INTEGER"},
+	{"trust_follows", "root_npub", "TEXT"},
+	{"trust_follows", "source_event_id", "TEXT"},
+	{"trust_follows", "source_created_at", "INTEGER"},
+	{"trust_follows", "updated_at", "DATETIME"},
+	{"trust_follows", "source", "TEXT DEFAULT 'crawl'"},
+	{"trust_whitelist", "source", "TEXT DEFAULT 'manual'"},
+	{"trust_whitelist", "list_id", "INTEGER"},
+	{"trust_blacklist", "source", "TEXT DEFAULT 'manual'"},
+	{"trust_blacklist", "list_id", "INTEGER"},
+	{"trust_whitelist", "effective_from", "INTEGER"},
+	{"trust_whitelist", "effective_until", "INTEGER"},
+	{"trust_blacklist", "effective_from", "INTEGER"},
+	{"trust_blacklist", "effective_until", "INTEGER"},
+}
+
+// migrationIndexes reference migrated columns, so they run after columnMigrations
+var migrationIndexes = []string{
+	"CREATE INDEX IF NOT EXISTS idx_torrents_season_episode ON torrents(season, episode)",
+	"CREATE INDEX IF NOT EXISTS idx_torrents_air_date ON torrents(air_date)",
+	"CREATE INDEX IF NOT EXISTS idx_torrents_tvdb ON torrents(tvdb_id)",
+	"CREATE INDEX IF NOT EXISTS idx_relay_events_infohash ON relay_events(infohash)",
+	"CREATE INDEX IF NOT EXISTS idx_relay_events_kind_created ON relay_events(kind, created_at)",
+	"CREATE INDEX IF NOT EXISTS idx_trust_follows_root ON trust_follows(root_npub, depth)",
+}
+
+// dataMigrations fill migrated columns of existing rows. They must be safe to
+// run on every start.
+var dataMigrations = []string{
+	// Uploads indexed before their event's created_at was recorded
+	`UPDATE torrent_uploads SET event_created_at = (
+		SELECT created_at FROM torrent_events WHERE event_id = torrent_uploads.nostr_event_id
+	) WHERE event_created_at IS NULL
+	AND nostr_event_id IN (SELECT event_id FROM torrent_events)`,
+}
+
+// runMigrations brings tables created by older schema versions up to date
+func runMigrations() error {
+	if err := rebuildLegacyRelayEvents(); err != nil {
+		return err
+	}
+
+	for _, m := range columnMigrations {
+		if err := EnsureColumn(m.Table, m.Column, m.Definition); err != nil {
+			return err
+		}
+	}
+
+	for _, stmt := range migrationIndexes {
+		if _, err := db.Exec(stmt); err != nil {
+			return fmt.Errorf("failed to create index: %w", err)
+		}
+	}
+
+	for _, stmt := range dataMigrations {
+		if _, err := db.Exec(stmt); err != nil {
+			return fmt.Errorf("failed to migrate data: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// rebuildLegacyRelayEvents replaces the original relay_events table, whose
+// columns never matched the relay storage. No event could be stored in it,
+// so it is dropped and recreated from the schema.
+func rebuildLegacyRelayEvents() error {
+	current, err := columnExists("relay_events", "raw_json")
+	if err != nil || current {
+		return err
+	}
+
+	if _, err := db.Exec("DROP TABLE relay_events"); err != nil {
+		return fmt.Errorf("failed to drop legacy relay_events: %w", err)
+	}
+	if err := runSchema(); err != nil {
+		return err
+	}
+
+	log.Info().Msg("Rebuilt relay_events table")
+	return nil
+}
+
+// EnsureColumn adds a column to a table if it does not exist yet
+func EnsureColumn(table, column, definition string) error {
+	exists, err := columnExists(table, column)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return nil
+	}
+
+	_, err = db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
+	if err != nil {
+		return fmt.Errorf("failed to add column %s.%s: %w", table, column, err)
+	}
+
+	log.Info().Str("table", table).Str("column", column).Msg("Added database column")
+	return nil
+}
+
+// columnExists checks if a table has a column with the given name
+func columnExists(table, column string) (bool, error) {
+	rows, err := db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return false, fmt.Errorf("failed to read table info for %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, colType string
+		var defaultValue interface{}
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &defaultValue, &pk); err != nil {
+			return false, err
+		}
+		if name == column {
+			return true, nil
+		}
+	}
+
+	return false, rows.Err()
+}
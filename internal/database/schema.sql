@@ -34,6 +34,29 @@ CREATE TABLE IF NOT EXISTS relays (
     created_at DATETIME DEFAULT CURRENT_TIMESTAMP
 );
 
+-- Sync cursors per relay and subscription filter. The range between
+-- oldest_created_at and newest_created_at (event timestamps) has been fully fetched.
+CREATE TABLE IF NOT EXISTS relay_sync_state (
+    relay_url TEXT NOT NULL,
+    filter_key TEXT NOT NULL,  -- Filter kinds, see relay_sync_authors for its authors
+    kinds TEXT,  -- Comma-separated kinds, for display
+    author_count INTEGER DEFAULT 0,
+    newest_created_at INTEGER NOT NULL DEFAULT 0,
+    oldest_created_at INTEGER NOT NULL DEFAULT 0,
+    backfill_complete BOOLEAN DEFAULT FALSE,  -- Oldest end reached the start of the relay's history
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (relay_url, filter_key)
+);
+
+-- Authors whose events are covered by a relay's sync cursor. Authors missing
+-- here are backfilled separately before being added.
+CREATE TABLE IF NOT EXISTS relay_sync_authors (
+    relay_url TEXT NOT NULL,
+    filter_key TEXT NOT NULL,
+    pubkey TEXT NOT NULL,  -- Hex pubkey
+    PRIMARY KEY (relay_url, filter_key, pubkey)
+);
+
 -- =====================================================
 -- TORRENTS
 -- =====================================================
@@ -49,12 +72,15 @@ CREATE TABLE IF NOT EXISTS torrents (
     leechers INTEGER DEFAULT 0,
     magnet_uri TEXT NOT NULL,
     files TEXT,  -- JSON array of files
+    trackers TEXT,  -- JSON array, union of all uploads
+    description TEXT,  -- Uploader description
 
     -- Enriched metadata from TMDB/OMDB
     title TEXT,  -- Clean title
     year INTEGER,
     tmdb_id INTEGER,
     imdb_id TEXT,
+    tvdb_id INTEGER,
     poster_url TEXT,
     backdrop_url TEXT,
     overview TEXT,
@@ -71,6 +97,15 @@ CREATE TABLE IF NOT EXISTS torrents (
     infohash_version TEXT DEFAULT 'v1',
     infohash_v2 TEXT,
     comment_count INTEGER DEFAULT 0,
+    external_id_conflict INTEGER DEFAULT 0,  -- Uploader/enrichment IDs disagree
+    metadata_conflict INTEGER DEFAULT 0,  -- Uploaders disagree on name, size or files
+
+    -- Parsed episode information (NULL for non-episodic releases)
+    season INTEGER,
+    season_end INTEGER,  -- Last season of a multi-season pack
+    episode INTEGER,  -- NULL for season packs
+    episode_end INTEGER,  -- Last episode of a multi-episode release
+    air_date TEXT,  -- YYYY-MM-DD for daily shows
 
     first_seen_at DATETIME DEFAULT CURRENT_TIMESTAMP,
     updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
@@ -110,9 +145,62 @@ CREATE TABLE IF NOT EXISTS torrent_uploads (
     uploader_npub TEXT NOT NULL,
     nostr_event_id TEXT UNIQUE NOT NULL,
     relay_url TEXT,
+
+    -- Metadata as published in this upload
+    name TEXT,
+    size INTEGER,
+    category INTEGER,
+    description TEXT,
+    magnet_uri TEXT,
+    trackers TEXT,  -- JSON array
+    files TEXT,  -- JSON array
+    tags TEXT,  -- JSON array
+
+    event_created_at INTEGER,  -- created_at of the upload event
     uploaded_at DATETIME DEFAULT CURRENT_TIMESTAMP
 );
 
+-- Content tags (NIP-35 "t" tags) merged across all uploads of a torrent
+CREATE TABLE IF NOT EXISTS torrent_tags (
+    torrent_id INTEGER NOT NULL REFERENCES torrents(id) ON DELETE CASCADE,
+    tag TEXT NOT NULL,
+    upload_count INTEGER DEFAULT 1,  -- Number of uploads carrying this tag
+    PRIMARY KEY (torrent_id, tag)
+);
+
+-- External database IDs from NIP-35 "i" tags and enrichment
+CREATE TABLE IF NOT EXISTS torrent_external_ids (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    torrent_id INTEGER NOT NULL REFERENCES torrents(id) ON DELETE CASCADE,
+    id_type TEXT NOT NULL,  -- imdb, tmdb, tvdb, mal, ...
+    media_type TEXT,  -- movie or tv for TMDB IDs
+    value TEXT NOT NULL,
+    source TEXT NOT NULL,  -- Uploader pubkey (hex) or 'enricher'
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(torrent_id, id_type, value, source)
+);
+
+-- Raw Kind 2003 events accepted by the indexer, kept verbatim for reindexing
+CREATE TABLE IF NOT EXISTS torrent_events (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    event_id TEXT UNIQUE NOT NULL,
+    pubkey TEXT NOT NULL,  -- hex
+    created_at INTEGER NOT NULL,  -- Event timestamp (unix)
+    raw_json TEXT NOT NULL,
+    relay_url TEXT,
+    received_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Events their authors deleted with a NIP-09 request (Kind 5)
+CREATE TABLE IF NOT EXISTS deleted_events (
+    event_id TEXT NOT NULL,
+    pubkey TEXT NOT NULL,  -- hex, deletions only apply to the author's own events
+    created_at INTEGER,  -- Timestamp of the deleted event, if it was held
+    deletion_event_id TEXT NOT NULL,
+    deleted_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (event_id, pubkey)
+);
+
 -- Torrent comments (Kind 2004)
 CREATE TABLE IF NOT EXISTS torrent_comments (
     id INTEGER PRIMARY KEY AUTOINCREMENT,
@@ -139,6 +227,10 @@ CREATE TABLE IF NOT EXISTS trust_whitelist (
     npub TEXT UNIQUE NOT NULL,
     alias TEXT,
     notes TEXT,
+    source TEXT DEFAULT 'manual',  -- 'manual' or 'list'
+    list_id INTEGER,  -- trust_lists entry the npub was imported from
+    effective_from INTEGER,  -- Applies to events created at or after (unix seconds)
+    effective_until INTEGER,  -- Applies to events created before (unix seconds)
     added_at DATETIME DEFAULT CURRENT_TIMESTAMP
 );
 
@@ -147,6 +239,10 @@ CREATE TABLE IF NOT EXISTS trust_blacklist (
     id INTEGER PRIMARY KEY AUTOINCREMENT,
     npub TEXT UNIQUE NOT NULL,
     reason TEXT,
+    source TEXT DEFAULT 'manual',  -- 'manual' or 'list'
+    list_id INTEGER,  -- trust_lists entry the npub was imported from
+    effective_from INTEGER,  -- Applies to events created at or after (unix seconds)
+    effective_until INTEGER,  -- Applies to events created before (unix seconds)
     added_at DATETIME DEFAULT CURRENT_TIMESTAMP
 );
 
@@ -156,10 +252,60 @@ CREATE TABLE IF NOT EXISTS trust_follows (
     follower_npub TEXT NOT NULL,
     followed_npub TEXT NOT NULL,
     depth INTEGER DEFAULT 1,  -- 1 = direct follow, 2 = friend of friend
+    root_npub TEXT,  -- Trust root (own identity or whitelisted user) the follower was reached from
+    source TEXT DEFAULT 'crawl',  -- 'crawl' (replaced by each crawl) or 'import' (imported contact list)
+    source_event_id TEXT,  -- Contact list (Kind 3) the edge was read from
+    source_created_at INTEGER,
     discovered_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
     UNIQUE(follower_npub, followed_npub)
 );
 
+-- Web of Trust - NIP-51 lists synced into the whitelist or blacklist
+CREATE TABLE IF NOT EXISTS trust_lists (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    owner_npub TEXT NOT NULL,
+    kind INTEGER NOT NULL,  -- 10000 mute list (blacklist), 30000 follow set (whitelist)
+    d_tag TEXT NOT NULL DEFAULT '',  -- Follow set identifier
+    event_id TEXT,  -- Newest list event applied
+    event_created_at INTEGER,
+    entry_count INTEGER DEFAULT 0,
+    synced_at DATETIME,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(owner_npub, kind, d_tag)
+);
+
+CREATE TABLE IF NOT EXISTS trust_list_entries (
+    list_id INTEGER NOT NULL REFERENCES trust_lists(id) ON DELETE CASCADE,
+    npub TEXT NOT NULL,
+    PRIMARY KEY (list_id, npub)
+);
+
+-- Web of Trust - Mute graph (public entries of NIP-51 mute lists)
+CREATE TABLE IF NOT EXISTS trust_mutes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    muter_npub TEXT NOT NULL,
+    muted_npub TEXT NOT NULL,
+    root_npub TEXT,  -- Trust root the muter was reached from
+    source_event_id TEXT,  -- Mute list (Kind 10000) the edge was read from
+    source_created_at INTEGER,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(muter_npub, muted_npub)
+);
+
+-- Web of Trust - Propagated trust scores, recomputed when the graph changes
+CREATE TABLE IF NOT EXISTS trust_scores (
+    pubkey TEXT PRIMARY KEY,  -- hex
+    npub TEXT NOT NULL,
+    score REAL NOT NULL,  -- -100 to 100
+    trust REAL DEFAULT 0,  -- Rank received from the seeds, relative to a seed's share
+    distrust REAL DEFAULT 0,  -- Rank lost to mutes and follows of blacklisted users
+    is_seed INTEGER DEFAULT 0,
+    is_blacklisted INTEGER DEFAULT 0,
+    contributions TEXT,  -- JSON: largest contributions to the score
+    computed_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
 -- =====================================================
 -- CURATION
 -- =====================================================
@@ -283,9 +429,12 @@ CREATE TABLE IF NOT EXISTS relay_events (
     pubkey TEXT NOT NULL,
     kind INTEGER NOT NULL,
     content TEXT NOT NULL,
-    tags TEXT NOT NULL,
+    tags_json TEXT NOT NULL,
     sig TEXT NOT NULL,
     created_at INTEGER NOT NULL,
+    infohash TEXT,  -- From x/btih tags, for #x queries
+    d_tag TEXT,  -- Identifier of parameterized replaceable events
+    raw_json TEXT NOT NULL,
     received_at DATETIME DEFAULT CURRENT_TIMESTAMP
 );
 
@@ -314,11 +463,20 @@ CREATE INDEX IF NOT EXISTS idx_torrents_curation ON torrents(curation_status);
 CREATE INDEX IF NOT EXISTS idx_torrents_dedup_group ON torrents(dedup_group_id);
 CREATE INDEX IF NOT EXISTS idx_torrent_uploads_torrent ON torrent_uploads(torrent_id);
 CREATE INDEX IF NOT EXISTS idx_torrent_uploads_uploader ON torrent_uploads(uploader_npub);
+CREATE INDEX IF NOT EXISTS idx_torrent_events_pubkey ON torrent_events(pubkey);
+CREATE INDEX IF NOT EXISTS idx_deleted_events_pubkey ON deleted_events(pubkey);
+CREATE INDEX IF NOT EXISTS idx_torrent_tags_tag ON torrent_tags(tag, torrent_id);
+CREATE INDEX IF NOT EXISTS idx_external_ids_torrent ON torrent_external_ids(torrent_id);
+CREATE INDEX IF NOT EXISTS idx_external_ids_value ON torrent_external_ids(id_type, value);
 CREATE INDEX IF NOT EXISTS idx_comments_torrent ON torrent_comments(infohash);
 CREATE INDEX IF NOT EXISTS idx_comments_author ON torrent_comments(author_pubkey);
 CREATE INDEX IF NOT EXISTS idx_comments_created ON torrent_comments(created_at DESC);
 CREATE INDEX IF NOT EXISTS idx_trust_follows_follower ON trust_follows(follower_npub);
 CREATE INDEX IF NOT EXISTS idx_trust_follows_followed ON trust_follows(followed_npub);
+CREATE INDEX IF NOT EXISTS idx_trust_mutes_muted ON trust_mutes(muted_npub);
+CREATE INDEX IF NOT EXISTS idx_trust_list_entries_npub ON trust_list_entries(npub);
+CREATE INDEX IF NOT EXISTS idx_trust_scores_npub ON trust_scores(npub);
+CREATE INDEX IF NOT EXISTS idx_trust_scores_score ON trust_scores(score DESC);
 CREATE INDEX IF NOT EXISTS idx_rulesets_active ON rulesets(is_active, type);
 CREATE INDEX IF NOT EXISTS idx_rulesets_hash ON rulesets(hash);
 CREATE INDEX IF NOT EXISTS idx_decisions_infohash ON verification_decisions(target_infohash);
@@ -53,6 +53,11 @@ func Init(dbPath string) error {
 		return fmt.Errorf("failed to run schema: %w", err)
 	}
 
+	// Apply column migrations for databases created by older versions
+	if err := runMigrations(); err != nil {
+		return fmt.Errorf("failed to run migrations: %w", err)
+	}
+
 	// Register setup checker with config package
 	config.SetupCompletedChecker = IsSetupCompleted
 
@@ -313,22 +318,6 @@ func GetTorrentsPerDay(days int) ([]map[string]interface{}, error) {
 	return stats, nil
 }
 
-// GetLatestEventTimestamp returns a unix timestamp to resume historical fetch from.
-// Uses MAX(first_seen_at) from torrents (indexed, fast) minus a 1-hour buffer to
-// account for clock skew between local time and relay event timestamps, and to
-// catch any late-arriving events. This means a small overlap is re-processed on
-// restart, which is safe since the deduplicator handles duplicates.
-func GetLatestEventTimestamp() (int64, error) {
-	var ts int64
-	err := db.QueryRow(`
-		SELECT COALESCE(MAX(strftime('%s', first_seen_at)), 0) FROM torrents
-	`).Scan(&ts)
-	if ts > 3600 {
-		ts -= 3600 // 1-hour buffer for clock skew and late arrivals
-	}
-	return ts, err
-}
-
 // LogActivity logs an activity event
 func LogActivity(eventType string, details string) error {
 	_, err := db.Exec(`
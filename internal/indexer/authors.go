@@ -0,0 +1,203 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/gmonarque/lighthouse/internal/database"
+	"github.com/gmonarque/lighthouse/internal/trust"
+	gonostr "github.com/nbd-wtf/go-nostr"
+	"github.com/rs/zerolog/log"
+)
+
+// trustedAuthors returns the sorted hex pubkeys of trusted uploaders that are
+// not blacklisted for good. Uploaders with time bounded entries stay subscribed;
+// their events are checked against the bounds as they arrive.
+func trustedAuthors() ([]string, error) {
+	policy, err := trust.NewWebOfTrust().GetUploaderPolicy()
+	if err != nil {
+		return nil, err
+	}
+	return policy.Pubkeys(), nil
+}
+
+// subscribeAuthors replaces the torrent, deletion and comment subscriptions
+// with ones for the given authors
+func (idx *Indexer) subscribeAuthors(pubkeys []string) error {
+	if idx.authorsCancel != nil {
+		idx.authorsCancel()
+		idx.authorsCancel = nil
+	}
+
+	if len(pubkeys) == 0 {
+		idx.authors = nil
+		idx.subscribeComments(nil)
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(idx.ctx)
+	idx.authorsCancel = cancel
+
+	log.Info().Int("trusted_uploaders", len(pubkeys)).Msg("Subscribing to trusted uploaders")
+
+	// Subscribe to torrent events from trusted uploaders only (real-time from each relay's cursor)
+	handler := func(event *gonostr.Event, relayURL string) {
+		idx.processEvent(event, relayURL)
+	}
+	if err := idx.relayManager.SubscribeTrustedTorrents(ctx, pubkeys, handler); err != nil {
+		// Keep the previous set so the next refresh retries
+		cancel()
+		return err
+	}
+	idx.authors = pubkeys
+
+	// Subscribe to deletion requests so uploaders can retract their torrents,
+	// including uploaders that are no longer trusted
+	if err := idx.relayManager.SubscribeDeletions(ctx, deletionAuthors(pubkeys), func(event *gonostr.Event, relayURL string) {
+		idx.processDeletionEvent(event, relayURL)
+	}); err != nil {
+		log.Warn().Err(err).Msg("Failed to subscribe to deletion requests")
+	}
+
+	// Subscribe to comments from the new authors and on indexed torrents
+	idx.subscribeComments(pubkeys)
+
+	return nil
+}
+
+// RefreshTrustedAuthors checks whether the trusted uploader set changed through
+// the whitelist, blacklist, follows, mutes or trust settings, and if so updates
+// the live subscriptions and backfills history for newly trusted authors
+func (idx *Indexer) RefreshTrustedAuthors() {
+	idx.authorsMu.Lock()
+	defer idx.authorsMu.Unlock()
+
+	idx.refreshTrustScores()
+
+	if !idx.IsRunning() {
+		return
+	}
+
+	pubkeys, err := trustedAuthors()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get trusted uploaders")
+		return
+	}
+
+	previous := idx.authors
+	if slices.Equal(pubkeys, previous) {
+		return
+	}
+
+	added := len(pubkeys) - countShared(previous, pubkeys)
+	removed := len(previous) - countShared(previous, pubkeys)
+
+	// Apply the new set to incoming events right away
+	idx.cacheMu.Lock()
+	idx.cacheExpiry = time.Time{}
+	idx.cacheMu.Unlock()
+
+	if err := idx.subscribeAuthors(pubkeys); err != nil {
+		log.Error().Err(err).Msg("Failed to resubscribe to trusted uploaders")
+		return
+	}
+
+	log.Info().Int("added", added).Int("removed", removed).Int("trusted_uploaders", len(pubkeys)).Msg("Trusted uploaders changed")
+	database.LogActivity("trusted_authors_changed", fmt.Sprintf("%d added, %d removed", added, removed))
+
+	if len(pubkeys) == 0 {
+		return
+	}
+
+	idx.syncHistory()
+}
+
+// syncHistory fetches the torrent history of the trusted uploaders in the
+// background. Relay cursors only cover the authors already synced, so this
+// backfills new authors and catches up on the others. A fetch requested while
+// one is running is folded into a single rerun once it finishes, so fetches
+// never overlap.
+func (idx *Indexer) syncHistory() {
+	idx.syncMu.Lock()
+	defer idx.syncMu.Unlock()
+
+	if idx.syncing {
+		idx.syncPending = true
+		return
+	}
+	idx.syncing = true
+
+	go func() {
+		for {
+			idx.mu.RLock()
+			ctx := idx.ctx
+			idx.mu.RUnlock()
+			idx.authorsMu.Lock()
+			pubkeys := idx.authors
+			idx.authorsMu.Unlock()
+
+			if len(pubkeys) > 0 {
+				if err := idx.relayManager.FetchAllHistoricalTorrents(ctx, pubkeys, func(event *gonostr.Event, relayURL string) {
+					idx.processEvent(event, relayURL)
+				}); err != nil {
+					log.Error().Err(err).Msg("Historical fetch failed")
+				}
+			}
+
+			idx.syncMu.Lock()
+			if !idx.syncPending {
+				idx.syncing = false
+				idx.syncMu.Unlock()
+				return
+			}
+			idx.syncPending = false
+			idx.syncMu.Unlock()
+		}
+	}()
+}
+
+// refreshTrustScores recomputes propagated trust scores if the trust graph
+// changed, and rescores all torrents when they were recomputed
+func (idx *Indexer) refreshTrustScores() {
+	generation, err := trust.NewWebOfTrust().RefreshScores()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to refresh trust scores")
+		return
+	}
+	if generation == idx.scoreGeneration {
+		return
+	}
+
+	if err := idx.deduplicator.RecalculateAllTrustScores(); err != nil {
+		log.Error().Err(err).Msg("Failed to recalculate torrent trust scores")
+		return
+	}
+	idx.scoreGeneration = generation
+}
+
+// deletionAuthors returns the sorted hex pubkeys whose deletion requests are
+// applied: the trusted uploaders and everyone with an indexed upload
+func deletionAuthors(trusted []string) []string {
+	uploaders, err := uploaderPubkeys()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to list uploaders for deletion requests")
+		return trusted
+	}
+
+	authors := append(slices.Clone(trusted), uploaders...)
+	slices.Sort(authors)
+	return slices.Compact(authors)
+}
+
+// countShared returns the number of pubkeys present in both sorted sets
+func countShared(a, b []string) int {
+	n := 0
+	for _, pk := range b {
+		if _, found := slices.BinarySearch(a, pk); found {
+			n++
+		}
+	}
+	return n
+}
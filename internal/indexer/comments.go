@@ -0,0 +1,147 @@
+package indexer
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/gmonarque/lighthouse/internal/comments"
+	"github.com/gmonarque/lighthouse/internal/database"
+	"github.com/gmonarque/lighthouse/internal/nostr"
+	gonostr "github.com/nbd-wtf/go-nostr"
+	"github.com/rs/zerolog/log"
+)
+
+// commentInfohashLimit caps the recently indexed torrents whose comments are
+// subscribed to by infohash, keeping the subscription within relay limits
+const commentInfohashLimit = 2000
+
+// subscribeComments replaces the comment subscription with one for comments
+// written by the given trusted authors or on recently indexed torrents
+func (idx *Indexer) subscribeComments(authors []string) {
+	idx.commentsMu.Lock()
+	defer idx.commentsMu.Unlock()
+
+	if idx.commentsCancel != nil {
+		idx.commentsCancel()
+		idx.commentsCancel = nil
+	}
+
+	infohashes, err := recentInfohashes(commentInfohashLimit)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to list infohashes for comments")
+	}
+	if len(authors) == 0 && len(infohashes) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(idx.ctx)
+	if err := idx.relayManager.SubscribeComments(ctx, authors, infohashes, idx.processCommentEvent); err != nil {
+		cancel()
+		log.Warn().Err(err).Msg("Failed to subscribe to comments")
+		return
+	}
+	idx.commentsCancel = cancel
+}
+
+// refreshComments renews the comment subscription so it covers newly indexed torrents
+func (idx *Indexer) refreshComments() {
+	if !idx.IsRunning() {
+		return
+	}
+
+	idx.authorsMu.Lock()
+	authors := idx.authors
+	idx.authorsMu.Unlock()
+
+	idx.subscribeComments(authors)
+}
+
+// recentInfohashes returns the infohashes of the most recently indexed torrents
+func recentInfohashes(limit int) ([]string, error) {
+	db := database.Get()
+	rows, err := db.Query(`
+		SELECT info_hash FROM torrents ORDER BY first_seen_at DESC, id DESC LIMIT ?
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var infohashes []string
+	for rows.Next() {
+		var infohash string
+		if err := rows.Scan(&infohash); err != nil {
+			return nil, err
+		}
+		infohashes = append(infohashes, infohash)
+	}
+	return infohashes, rows.Err()
+}
+
+// processCommentEvent verifies and stores a Kind 2004 comment that references
+// an indexed torrent or was written by a trusted author
+func (idx *Indexer) processCommentEvent(event *gonostr.Event, relayURL string) {
+	if event.Kind != nostr.KindComment {
+		return
+	}
+
+	if idx.isBlacklisted(event.PubKey, int64(event.CreatedAt)) {
+		return
+	}
+
+	if ok, err := event.CheckSignature(); err != nil || !ok {
+		log.Debug().Str("event_id", event.ID).Str("relay", relayURL).Msg("Skipping comment with invalid signature")
+		return
+	}
+
+	comment, err := comments.FromNostrEvent(event)
+	if err != nil {
+		return
+	}
+	comment.Infohash = strings.ToLower(comment.Infohash)
+
+	db := database.Get()
+
+	// Comments may reference the torrent event instead of the infohash
+	if comment.Infohash == "" && comment.TorrentEventID != "" {
+		db.QueryRow(`
+			SELECT t.info_hash FROM torrent_uploads tu
+			JOIN torrents t ON t.id = tu.torrent_id
+			WHERE tu.nostr_event_id = ?
+		`, comment.TorrentEventID).Scan(&comment.Infohash)
+	}
+
+	if comment.Infohash == "" {
+		return
+	}
+
+	if !isIndexed(db, comment.Infohash) && !idx.isTrusted(event.PubKey, int64(event.CreatedAt)) {
+		return
+	}
+
+	// Resolve the thread root from the stored parent so deep replies stay threaded
+	if comment.ParentID != "" && comment.RootID == comment.ParentID {
+		if parent, err := idx.comments.GetByID(comment.ParentID); err == nil && parent != nil && parent.RootID != "" {
+			comment.RootID = parent.RootID
+		}
+	}
+
+	if err := idx.comments.Save(comment); err != nil {
+		log.Error().Err(err).Str("event_id", event.ID).Msg("Failed to save comment")
+		return
+	}
+
+	log.Debug().
+		Str("event_id", event.ID).
+		Str("infohash", comment.Infohash).
+		Str("relay", relayURL).
+		Msg("Stored comment")
+}
+
+// isIndexed checks if a torrent with the given infohash is in the index
+func isIndexed(db *sql.DB, infoHash string) bool {
+	var exists int
+	err := db.QueryRow("SELECT 1 FROM torrents WHERE info_hash = ?", infoHash).Scan(&exists)
+	return err == nil
+}
@@ -0,0 +1,514 @@
+package indexer
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/gmonarque/lighthouse/internal/config"
+	"github.com/gmonarque/lighthouse/internal/database"
+	"github.com/gmonarque/lighthouse/internal/nostr"
+	gonostr "github.com/nbd-wtf/go-nostr"
+	"github.com/rs/zerolog/log"
+)
+
+// Sources of follow graph edges
+const (
+	// followSourceCrawl edges are replaced by each crawl
+	followSourceCrawl = "crawl"
+	// followSourceImport edges were imported by hand and are never pruned by a crawl
+	followSourceImport = "import"
+)
+
+// crawlRetryDelay is the first delay before retrying a crawl that fetched no
+// contact list, doubled on each retry up to the crawl interval
+const crawlRetryDelay = time.Minute
+
+// contactList is the set of pubkeys read from one user's Kind 3 contact list
+// or Kind 10000 mute list
+type contactList struct {
+	Follower  string // hex
+	Root      string // hex pubkey of the trust root the follower was reached from
+	Depth     int    // depth of the followed users
+	Source    string // source of follow edges, followSourceCrawl if empty
+	EventID   string
+	CreatedAt int64
+	Pubkeys   []string // hex
+}
+
+// followGraph collects contact and mute lists during a crawl while enforcing
+// the fan-out and total size limits
+type followGraph struct {
+	maxFollows int
+	maxEdges   int
+	edges      int
+	truncated  bool
+	lists      []contactList
+	mutes      []contactList
+	nodes      []followNode    // crawled followers in crawl order
+	crawled    map[string]bool // followers whose edges are kept
+}
+
+// followNode is a user reached by the crawl whose contact list is fetched next
+type followNode struct {
+	Pubkey string
+	Root   string
+}
+
+// newFollowGraph creates an empty follow graph with the given limits
+func newFollowGraph(maxFollows, maxEdges int) *followGraph {
+	return &followGraph{
+		maxFollows: maxFollows,
+		maxEdges:   maxEdges,
+		crawled:    make(map[string]bool),
+	}
+}
+
+// add records the contact list of a follower. event is nil if the follower
+// has no contact list on our relays, in which case stored edges are kept.
+// Returns false once the graph is full.
+func (g *followGraph) add(follower, root string, depth int, event *gonostr.Event) bool {
+	if g.full() {
+		return false
+	}
+
+	g.crawled[follower] = true
+	g.nodes = append(g.nodes, followNode{Pubkey: follower, Root: root})
+	if event != nil {
+		g.lists = append(g.lists, g.newList(follower, root, depth, event))
+	}
+	return true
+}
+
+// addMutes records the mute list of a crawled follower. Returns false once the graph is full.
+func (g *followGraph) addMutes(follower, root string, event *gonostr.Event) bool {
+	if g.full() {
+		return false
+	}
+
+	if event != nil {
+		g.mutes = append(g.mutes, g.newList(follower, root, 0, event))
+	}
+	return true
+}
+
+// full reports whether the graph reached its size limit
+func (g *followGraph) full() bool {
+	if g.edges >= g.maxEdges {
+		g.truncated = true
+		return true
+	}
+	return false
+}
+
+// newList reads the pubkeys of a list event, truncated to the room left in the graph
+func (g *followGraph) newList(follower, root string, depth int, event *gonostr.Event) contactList {
+	pubkeys := parseListPubkeys(event, g.maxFollows)
+	if g.edges+len(pubkeys) > g.maxEdges {
+		pubkeys = pubkeys[:g.maxEdges-g.edges]
+		g.truncated = true
+	}
+	g.edges += len(pubkeys)
+
+	return contactList{
+		Follower:  follower,
+		Root:      root,
+		Depth:     depth,
+		EventID:   event.ID,
+		CreatedAt: int64(event.CreatedAt),
+		Pubkeys:   pubkeys,
+	}
+}
+
+// frontier returns the users followed at the given depth that have not been crawled yet
+func (g *followGraph) frontier(depth int) []followNode {
+	seen := make(map[string]bool)
+	var nodes []followNode
+	for _, list := range g.lists {
+		if list.Depth != depth {
+			continue
+		}
+		for _, pk := range list.Pubkeys {
+			if g.crawled[pk] || seen[pk] {
+				continue
+			}
+			seen[pk] = true
+			nodes = append(nodes, followNode{Pubkey: pk, Root: list.Root})
+		}
+	}
+	return nodes
+}
+
+// parseListPubkeys returns the valid, distinct pubkeys of a contact list, mute
+// list, follow set or mute set, at most max
+func parseListPubkeys(event *gonostr.Event, max int) []string {
+	var listed []string
+	switch event.Kind {
+	case nostr.KindMuteList:
+		listed = nostr.ParseMuteList(event)
+	case nostr.KindFollowSet:
+		listed = nostr.ParseFollowSet(event)
+	case nostr.KindMuteSet:
+		listed = nostr.ParseMuteSet(event)
+	default:
+		listed = nostr.ParseContactList(event)
+	}
+
+	seen := make(map[string]bool)
+	var pubkeys []string
+	for _, pk := range listed {
+		if len(pubkeys) >= max {
+			break
+		}
+		if seen[pk] || pk == event.PubKey || !gonostr.IsValid32ByteHex(pk) {
+			continue
+		}
+		seen[pk] = true
+		pubkeys = append(pubkeys, pk)
+	}
+	return pubkeys
+}
+
+// crawlRoots returns the hex pubkeys the follow graph is crawled from: our own
+// identity, and whitelisted users if configured
+func (idx *Indexer) crawlRoots() []string {
+	cfg := config.Get()
+
+	var roots []string
+	own, err := nostr.NpubToHex(cfg.Nostr.Identity.Npub)
+	if err == nil {
+		roots = append(roots, own)
+	}
+
+	if !cfg.Trust.CrawlWhitelist {
+		return roots
+	}
+
+	rows, err := database.Get().Query("SELECT npub FROM trust_whitelist")
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to load whitelist for crawl")
+		return roots
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var npub string
+		if rows.Scan(&npub) != nil {
+			continue
+		}
+		pk, err := nostr.NpubToHex(npub)
+		if err != nil || pk == own || idx.isBlacklisted(pk, time.Now().Unix()) {
+			continue
+		}
+		roots = append(roots, pk)
+	}
+	return roots
+}
+
+// crawlTrustGraph fetches the contact and mute lists of the trust roots and,
+// at depth 2, of everyone they follow, then replaces the stored trust graph
+func (idx *Indexer) crawlTrustGraph() {
+	cfg := config.Get()
+	if cfg.Trust.Depth == 0 {
+		return
+	}
+
+	// Skip if the previous crawl is still running
+	if !idx.crawlMu.TryLock() {
+		return
+	}
+	defer idx.crawlMu.Unlock()
+
+	roots := idx.crawlRoots()
+	if len(roots) == 0 {
+		return
+	}
+
+	started := time.Now()
+	graph := newFollowGraph(cfg.Trust.MaxFollowsPerUser, cfg.Trust.MaxGraphSize)
+
+	// Relays may not be connected yet on startup. Without any root's list the
+	// graph cannot be rebuilt, so keep the stored one and try again soon.
+	lists, err := idx.relayManager.FetchContactLists(idx.ctx, roots)
+	if err != nil || !anyListed(lists, roots) {
+		idx.retryCrawl(err)
+		return
+	}
+	idx.crawlRetry = 0
+	for _, root := range roots {
+		graph.add(root, root, 1, lists[root])
+	}
+
+	if cfg.Trust.Depth >= 2 {
+		var nodes []followNode
+		for _, node := range graph.frontier(1) {
+			if !idx.isBlacklisted(node.Pubkey, time.Now().Unix()) {
+				nodes = append(nodes, node)
+			}
+		}
+
+		lists, err := idx.relayManager.FetchContactLists(idx.ctx, nodePubkeys(nodes))
+		if err != nil {
+			log.Warn().Err(err).Msg("Follow graph crawl failed")
+			return
+		}
+		for _, node := range nodes {
+			if !graph.add(node.Pubkey, node.Root, 2, lists[node.Pubkey]) {
+				break
+			}
+		}
+	}
+
+	// Mutes only take trust away, so a failed fetch keeps the stored ones
+	muteLists, err := idx.relayManager.FetchMuteLists(idx.ctx, nodePubkeys(graph.nodes))
+	mutesFetched := err == nil
+	if mutesFetched {
+		for _, node := range graph.nodes {
+			if !graph.addMutes(node.Pubkey, node.Root, muteLists[node.Pubkey]) {
+				break
+			}
+		}
+	} else {
+		log.Warn().Err(err).Msg("Mute list crawl failed")
+	}
+
+	db := database.Get()
+	stored := 0
+	for _, list := range graph.lists {
+		if storeContactList(db, list) {
+			stored++
+		}
+	}
+	for _, list := range graph.mutes {
+		storeMuteList(db, list)
+	}
+	pruned := pruneEdges(db, "trust_follows", "follower_npub", "source = '"+followSourceCrawl+"'", graph.crawled)
+	if mutesFetched {
+		pruned += pruneEdges(db, "trust_mutes", "muter_npub", "1 = 1", graph.crawled)
+	}
+
+	if graph.truncated {
+		log.Warn().Int("max_graph_size", cfg.Trust.MaxGraphSize).Msg("Follow graph truncated")
+	}
+	log.Info().
+		Int("contact_lists", len(graph.lists)).
+		Int("mute_lists", len(graph.mutes)).
+		Int("updated", stored).
+		Int("edges", graph.edges).
+		Int64("pruned", pruned).
+		Dur("took", time.Since(started)).
+		Msg("Follow graph crawled")
+	database.LogActivity("wot_crawled", fmt.Sprintf("%d contact lists, %d mute lists, %d edges, %d pruned",
+		len(graph.lists), len(graph.mutes), graph.edges, pruned))
+
+	idx.RefreshTrustedAuthors()
+}
+
+// anyListed reports whether a contact list was fetched for any of the pubkeys
+func anyListed(lists map[string]*gonostr.Event, pubkeys []string) bool {
+	for _, pk := range pubkeys {
+		if lists[pk] != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// crawlInterval returns the time between follow graph crawls
+func crawlInterval() time.Duration {
+	return time.Duration(max(config.Get().Trust.CrawlInterval, 1)) * time.Hour
+}
+
+// retryCrawl schedules another crawl after one that fetched no contact list.
+// Must be called with crawlMu held.
+func (idx *Indexer) retryCrawl(err error) {
+	idx.crawlRetry = min(max(idx.crawlRetry*2, crawlRetryDelay), crawlInterval())
+
+	event := log.Warn()
+	if err == nil {
+		event = log.Info()
+	}
+	event.Err(err).Dur("retry_in", idx.crawlRetry).Msg("No contact lists fetched for the follow graph crawl")
+
+	time.AfterFunc(idx.crawlRetry, func() {
+		if idx.ctx.Err() == nil {
+			idx.crawlTrustGraph()
+		}
+	})
+}
+
+// storeContactList replaces a follower's edges from the same source with
+// those of a contact list. An edge imported by hand keeps its source when a
+// crawl finds it too. Lists older than the one already stored are ignored.
+// Returns true if stored.
+func storeContactList(db *sql.DB, list contactList) bool {
+	follower, err := nostr.HexToNpub(list.Follower)
+	if err != nil {
+		return false
+	}
+	root, err := nostr.HexToNpub(list.Root)
+	if err != nil {
+		return false
+	}
+
+	source := list.Source
+	if source == "" {
+		source = followSourceCrawl
+	}
+
+	var newest sql.NullInt64
+	db.QueryRow("SELECT MAX(source_created_at) FROM trust_follows WHERE follower_npub = ?", follower).Scan(&newest)
+	if newest.Valid && newest.Int64 > list.CreatedAt {
+		return false
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to start transaction")
+		return false
+	}
+	defer tx.Rollback()
+
+	for _, pk := range list.Pubkeys {
+		followed, err := nostr.HexToNpub(pk)
+		if err != nil {
+			continue
+		}
+		_, err = tx.Exec(`
+			INSERT INTO trust_follows (follower_npub, followed_npub, depth, root_npub, source,
+				source_event_id, source_created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+			ON CONFLICT(follower_npub, followed_npub) DO UPDATE SET
+				depth = excluded.depth,
+				root_npub = excluded.root_npub,
+				source = CASE WHEN trust_follows.source = ? THEN trust_follows.source ELSE excluded.source END,
+				source_event_id = excluded.source_event_id,
+				source_created_at = excluded.source_created_at,
+				updated_at = CURRENT_TIMESTAMP
+		`, follower, followed, list.Depth, root, source, list.EventID, list.CreatedAt, followSourceImport)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to store follow")
+			return false
+		}
+	}
+
+	// Drop follows from the same source that are no longer in the contact list
+	_, err = tx.Exec(`
+		DELETE FROM trust_follows
+		WHERE follower_npub = ? AND source = ? AND (source_event_id IS NULL OR source_event_id != ?)
+	`, follower, source, list.EventID)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to remove stale follows")
+		return false
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Error().Err(err).Msg("Failed to commit follows")
+		return false
+	}
+	return true
+}
+
+// storeMuteList replaces a muter's edges with those of a mute list.
+// Lists older than the one already stored are ignored. Returns true if stored.
+func storeMuteList(db *sql.DB, list contactList) bool {
+	muter, err := nostr.HexToNpub(list.Follower)
+	if err != nil {
+		return false
+	}
+	root, err := nostr.HexToNpub(list.Root)
+	if err != nil {
+		return false
+	}
+
+	var newest sql.NullInt64
+	db.QueryRow("SELECT MAX(source_created_at) FROM trust_mutes WHERE muter_npub = ?", muter).Scan(&newest)
+	if newest.Valid && newest.Int64 > list.CreatedAt {
+		return false
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to start transaction")
+		return false
+	}
+	defer tx.Rollback()
+
+	for _, pk := range list.Pubkeys {
+		muted, err := nostr.HexToNpub(pk)
+		if err != nil {
+			continue
+		}
+		_, err = tx.Exec(`
+			INSERT INTO trust_mutes (muter_npub, muted_npub, root_npub, source_event_id, source_created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+			ON CONFLICT(muter_npub, muted_npub) DO UPDATE SET
+				root_npub = excluded.root_npub,
+				source_event_id = excluded.source_event_id,
+				source_created_at = excluded.source_created_at,
+				updated_at = CURRENT_TIMESTAMP
+		`, muter, muted, root, list.EventID, list.CreatedAt)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to store mute")
+			return false
+		}
+	}
+
+	// Drop mutes that are no longer in the mute list
+	_, err = tx.Exec(`
+		DELETE FROM trust_mutes
+		WHERE muter_npub = ? AND (source_event_id IS NULL OR source_event_id != ?)
+	`, muter, list.EventID)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to remove stale mutes")
+		return false
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Error().Err(err).Msg("Failed to commit mutes")
+		return false
+	}
+	return true
+}
+
+// pruneEdges removes the edges of a trust graph table matching condition
+// whose source user the crawl no longer reaches
+func pruneEdges(db *sql.DB, table, column, condition string, crawled map[string]bool) int64 {
+	rows, err := db.Query(fmt.Sprintf("SELECT DISTINCT %s FROM %s WHERE %s", column, table, condition))
+	if err != nil {
+		return 0
+	}
+
+	var stale []string
+	for rows.Next() {
+		var npub string
+		if rows.Scan(&npub) != nil {
+			continue
+		}
+		pk, err := nostr.NpubToHex(npub)
+		if err != nil || !crawled[pk] {
+			stale = append(stale, npub)
+		}
+	}
+	rows.Close()
+
+	var pruned int64
+	for _, npub := range stale {
+		result, err := db.Exec(fmt.Sprintf("DELETE FROM %s WHERE %s = ? AND %s", table, column, condition), npub)
+		if err != nil {
+			continue
+		}
+		n, _ := result.RowsAffected()
+		pruned += n
+	}
+	return pruned
+}
+
+// nodePubkeys returns the pubkeys of crawl nodes
+func nodePubkeys(nodes []followNode) []string {
+	pubkeys := make([]string, len(nodes))
+	for i, node := range nodes {
+		pubkeys[i] = node.Pubkey
+	}
+	return pubkeys
+}
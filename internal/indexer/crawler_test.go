@@ -0,0 +1,69 @@
+package indexer
+
+import (
+	"fmt"
+	"testing"
+
+	gonostr "github.com/nbd-wtf/go-nostr"
+)
+
+func testPubkey(n int) string {
+	return fmt.Sprintf("%064x", n)
+}
+
+func testContactList(author int, follows ...int) *gonostr.Event {
+	event := &gonostr.Event{Kind: 3, PubKey: testPubkey(author)}
+	for _, f := range follows {
+		event.Tags = append(event.Tags, gonostr.Tag{"p", testPubkey(f)})
+	}
+	return event
+}
+
+func TestParseListPubkeys(t *testing.T) {
+	event := testContactList(1, 2, 3, 2, 1, 4)
+	event.Tags = append(event.Tags, gonostr.Tag{"p", "not-a-pubkey"})
+
+	got := parseListPubkeys(event, 10)
+	if len(got) != 3 || got[0] != testPubkey(2) || got[2] != testPubkey(4) {
+		t.Errorf("parseListPubkeys() = %v, want follows 2, 3 and 4", got)
+	}
+
+	if got := parseListPubkeys(event, 2); len(got) != 2 {
+		t.Errorf("parseListPubkeys() with fan-out 2 returned %d follows", len(got))
+	}
+}
+
+func TestFollowGraph(t *testing.T) {
+	root := testPubkey(1)
+	g := newFollowGraph(100, 5)
+
+	g.add(root, root, 1, testContactList(1, 2, 3))
+
+	nodes := g.frontier(1)
+	if len(nodes) != 2 || nodes[0].Root != root {
+		t.Fatalf("frontier(1) = %v, want users 2 and 3 reached from the root", nodes)
+	}
+
+	// User 2 has no contact list, user 3's list only partly fits
+	if !g.add(nodes[0].Pubkey, root, 2, nil) {
+		t.Error("add() should accept a user without contact list")
+	}
+	if !g.add(nodes[1].Pubkey, root, 2, testContactList(3, 4, 5, 6, 7)) {
+		t.Error("add() should accept a list while the graph has room")
+	}
+	if g.edges != 5 || !g.truncated {
+		t.Errorf("edges = %d, truncated = %v, want 5 edges and truncated", g.edges, g.truncated)
+	}
+	if g.add(testPubkey(8), root, 2, testContactList(8, 9)) {
+		t.Error("add() should refuse lists once the graph is full")
+	}
+
+	for _, pk := range []string{root, testPubkey(2), testPubkey(3)} {
+		if !g.crawled[pk] {
+			t.Errorf("%s should be marked as crawled", pk[60:])
+		}
+	}
+	if g.crawled[testPubkey(8)] {
+		t.Error("users past the size limit should not be marked as crawled")
+	}
+}
@@ -0,0 +1,136 @@
+package indexer
+
+import (
+	"github.com/gmonarque/lighthouse/internal/config"
+	"github.com/gmonarque/lighthouse/internal/curator"
+	"github.com/gmonarque/lighthouse/internal/database"
+	"github.com/gmonarque/lighthouse/internal/decision"
+	"github.com/gmonarque/lighthouse/internal/ruleset"
+	gonostr "github.com/nbd-wtf/go-nostr"
+	"github.com/rs/zerolog/log"
+)
+
+// Curation status values stored in torrents.curation_status
+const (
+	CurationStatusUnknown  = "unknown"
+	CurationStatusAccepted = "accepted"
+	CurationStatusRejected = "rejected"
+	// CurationStatusBorderline marks rejects based only on probabilistic reasons
+	CurationStatusBorderline = "borderline"
+)
+
+// SetCurator sets the curator used to evaluate incoming torrent events
+func (idx *Indexer) SetCurator(c *curator.Curator) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.curator = c
+}
+
+// curate runs the local curator on an event and persists its decision.
+// Returns nil if local curation is disabled or failed.
+func (idx *Indexer) curate(event *gonostr.Event) *decision.VerificationDecision {
+	idx.mu.RLock()
+	c := idx.curator
+	idx.mu.RUnlock()
+
+	if c == nil || !c.IsEnabled() {
+		return nil
+	}
+
+	// In remote mode decisions come from external curators only
+	if config.Get().Curator.Mode == "remote" {
+		return nil
+	}
+
+	d, err := c.ProcessEvent(event)
+	if err != nil {
+		log.Warn().Err(err).Str("event_id", event.ID).Msg("Curation failed")
+		return nil
+	}
+
+	if err := c.SaveDecision(d); err != nil {
+		log.Error().Err(err).Str("event_id", event.ID).Msg("Failed to save curation decision")
+	}
+
+	return d
+}
+
+// setCurationStatus writes a curation decision to the torrent row
+func setCurationStatus(infoHash string, d decision.Decision, reasons []ruleset.ReasonCode) {
+	writeCurationStatus(infoHash, curationStatusFor(d, reasons))
+}
+
+// applyLocalCuration writes the curation status derived from every decision
+// the local curator made on the torrent's uploads, so that it does not depend
+// on which upload was processed last
+func (idx *Indexer) applyLocalCuration(infoHash string, verdict *decision.VerificationDecision) {
+	decisions, err := idx.decisions.GetByInfohash(infoHash)
+	if err != nil {
+		log.Error().Err(err).Str("info_hash", infoHash).Msg("Failed to load curation decisions")
+	}
+	writeCurationStatus(infoHash, localCurationStatus(append(decisions, verdict), verdict.CuratorPubkey))
+}
+
+// writeCurationStatus writes a curation status to the torrent row
+func writeCurationStatus(infoHash, status string) {
+	_, err := database.Get().Exec(`
+		UPDATE torrents SET curation_status = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE info_hash = ?
+	`, status, infoHash)
+	if err != nil {
+		log.Error().Err(err).Str("info_hash", infoHash).Msg("Failed to update curation status")
+	}
+}
+
+// curationStatusRank orders curation statuses from least to most strict
+var curationStatusRank = map[string]int{
+	CurationStatusUnknown:    0,
+	CurationStatusAccepted:   1,
+	CurationStatusBorderline: 2,
+	CurationStatusRejected:   3,
+}
+
+// localCurationStatus takes the newest decision of a curator on each upload
+// event and returns the strictest of their statuses. The result is the same
+// whatever order the decisions were made in.
+func localCurationStatus(decisions []*decision.VerificationDecision, curatorPubkey string) string {
+	newest := make(map[string]*decision.VerificationDecision)
+	for _, d := range decisions {
+		if d == nil || d.CuratorPubkey != curatorPubkey {
+			continue
+		}
+		existing, ok := newest[d.TargetEventID]
+		if !ok || d.CreatedAt.After(existing.CreatedAt) ||
+			(d.CreatedAt.Equal(existing.CreatedAt) && d.DecisionID > existing.DecisionID) {
+			newest[d.TargetEventID] = d
+		}
+	}
+
+	status := CurationStatusUnknown
+	for _, d := range newest {
+		if s := curationStatusFor(d.Decision, d.ReasonCodes); curationStatusRank[s] > curationStatusRank[status] {
+			status = s
+		}
+	}
+	return status
+}
+
+// curationStatusFor maps a decision and its reasons to a curation status value
+func curationStatusFor(d decision.Decision, reasons []ruleset.ReasonCode) string {
+	switch d {
+	case decision.DecisionAccept:
+		return CurationStatusAccepted
+	case decision.DecisionReject:
+		if len(reasons) == 0 {
+			return CurationStatusRejected
+		}
+		for _, code := range reasons {
+			if code.IsDeterministic() {
+				return CurationStatusRejected
+			}
+		}
+		return CurationStatusBorderline
+	default:
+		return CurationStatusUnknown
+	}
+}
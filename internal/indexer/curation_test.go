@@ -0,0 +1,57 @@
+package indexer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gmonarque/lighthouse/internal/decision"
+	"github.com/gmonarque/lighthouse/internal/ruleset"
+)
+
+func TestCurationStatusFor(t *testing.T) {
+	tests := []struct {
+		name     string
+		decision decision.Decision
+		reasons  []ruleset.ReasonCode
+		expected string
+	}{
+		{"accept", decision.DecisionAccept, nil, CurationStatusAccepted},
+		{"reject without reasons", decision.DecisionReject, nil, CurationStatusRejected},
+		{"legal reject", decision.DecisionReject, []ruleset.ReasonCode{ruleset.ReasonLegalDMCA}, CurationStatusRejected},
+		{"probabilistic reject", decision.DecisionReject, []ruleset.ReasonCode{ruleset.ReasonSemLowQuality}, CurationStatusBorderline},
+		{"mixed reject", decision.DecisionReject, []ruleset.ReasonCode{ruleset.ReasonSemBadMeta, ruleset.ReasonAbuseSpam}, CurationStatusRejected},
+		{"unknown", decision.Decision(""), nil, CurationStatusUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := curationStatusFor(tt.decision, tt.reasons); got != tt.expected {
+				t.Errorf("curationStatusFor() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestLocalCurationStatus(t *testing.T) {
+	now := time.Now()
+	decisions := []*decision.VerificationDecision{
+		{DecisionID: "1", TargetEventID: "upload-a", CuratorPubkey: "local", Decision: decision.DecisionReject, ReasonCodes: []ruleset.ReasonCode{ruleset.ReasonSemLowQuality}, CreatedAt: now.Add(-time.Hour)},
+		{DecisionID: "2", TargetEventID: "upload-a", CuratorPubkey: "local", Decision: decision.DecisionAccept, CreatedAt: now},
+		{DecisionID: "3", TargetEventID: "upload-b", CuratorPubkey: "local", Decision: decision.DecisionReject, ReasonCodes: []ruleset.ReasonCode{ruleset.ReasonSemLowQuality}, CreatedAt: now.Add(-time.Minute)},
+		{DecisionID: "4", TargetEventID: "upload-c", CuratorPubkey: "remote", Decision: decision.DecisionReject, ReasonCodes: []ruleset.ReasonCode{ruleset.ReasonLegalDMCA}, CreatedAt: now},
+	}
+
+	// The strictest upload wins regardless of order; superseded and other curators' decisions are ignored
+	want := CurationStatusBorderline
+	if got := localCurationStatus(decisions, "local"); got != want {
+		t.Errorf("localCurationStatus() = %q, want %q", got, want)
+	}
+	reversed := []*decision.VerificationDecision{decisions[3], decisions[2], decisions[1], decisions[0]}
+	if got := localCurationStatus(reversed, "local"); got != want {
+		t.Errorf("localCurationStatus() in reverse order = %q, want %q", got, want)
+	}
+
+	if got := localCurationStatus(decisions[:2], "local"); got != CurationStatusAccepted {
+		t.Errorf("localCurationStatus() = %q, want %q", got, CurationStatusAccepted)
+	}
+}
@@ -0,0 +1,182 @@
+package indexer
+
+import (
+	"github.com/gmonarque/lighthouse/internal/config"
+	"github.com/gmonarque/lighthouse/internal/database"
+	"github.com/gmonarque/lighthouse/internal/decision"
+	"github.com/gmonarque/lighthouse/internal/nostr"
+	"github.com/gmonarque/lighthouse/internal/trust"
+	gonostr "github.com/nbd-wtf/go-nostr"
+	"github.com/rs/zerolog/log"
+)
+
+// aggregationBatchSize is the number of infohashes aggregated per run
+const aggregationBatchSize = 200
+
+// remoteCurationEnabled returns true if decisions from external curators are consumed
+func remoteCurationEnabled() bool {
+	cfg := config.Get()
+	if !cfg.Curator.Enabled {
+		return false
+	}
+	return cfg.Curator.Mode == "remote" || cfg.Curator.Mode == "hybrid"
+}
+
+// subscribeDecisions subscribes to Kind 30175 decisions from approved curators
+func (idx *Indexer) subscribeDecisions() error {
+	curators, err := trust.NewPolicyStorage().GetApprovedCurators()
+	if err != nil {
+		return err
+	}
+
+	if len(curators) == 0 {
+		log.Warn().Msg("No approved curators in trust policy - remote decisions will not be fetched")
+		return nil
+	}
+
+	return idx.relayManager.SubscribeCuratorDecisions(idx.ctx, curators, func(event *gonostr.Event, relayURL string) {
+		idx.processDecisionEvent(event, relayURL)
+	})
+}
+
+// processDecisionEvent verifies and stores a remote curator decision
+func (idx *Indexer) processDecisionEvent(event *gonostr.Event, relayURL string) {
+	if event.Kind != nostr.KindDecision {
+		return
+	}
+
+	// Verify the Nostr event itself
+	if ok, err := event.CheckSignature(); err != nil || !ok {
+		log.Debug().Str("event_id", event.ID).Str("relay", relayURL).Msg("Skipping decision with invalid event signature")
+		return
+	}
+
+	d, err := decision.NostrEventToDecision(event)
+	if err != nil {
+		log.Debug().Err(err).Str("event_id", event.ID).Msg("Failed to parse decision event")
+		return
+	}
+
+	// Verify the decision payload signature
+	if err := decision.VerifyAndValidate(d); err != nil {
+		log.Debug().Err(err).Str("event_id", event.ID).Msg("Skipping invalid decision")
+		return
+	}
+
+	if err := idx.decisions.Save(d); err != nil {
+		log.Error().Err(err).Str("decision_id", d.DecisionID).Msg("Failed to save remote decision")
+		return
+	}
+
+	log.Debug().
+		Str("infohash", d.TargetInfohash).
+		Str("curator", d.CuratorPubkey).
+		Str("decision", string(d.Decision)).
+		Msg("Stored remote decision")
+}
+
+// newAggregator builds a decision aggregator from the current configuration
+func (idx *Indexer) newAggregator() *trust.Aggregator {
+	cfg := config.Get()
+	policyStorage := trust.NewPolicyStorage()
+
+	aggregator := trust.NewAggregator(policyStorage, &trust.AggregationPolicy{
+		Mode:           trust.AggregationMode(cfg.Curator.AggregationMode),
+		QuorumRequired: cfg.Curator.QuorumRequired,
+	})
+
+	// Curator weights come from the active trust policy
+	if policy, err := policyStorage.GetCurrent(); err == nil && policy != nil {
+		aggregator.SetPolicy(policy)
+	}
+
+	// In hybrid mode our own decisions count alongside remote ones
+	idx.mu.RLock()
+	c := idx.curator
+	idx.mu.RUnlock()
+	if c != nil && cfg.Curator.Mode == "hybrid" {
+		aggregator.SetLocalCurator(c.GetPublicKey())
+	}
+
+	return aggregator
+}
+
+// aggregatePendingDecisions aggregates new decisions and updates torrent curation status
+func (idx *Indexer) aggregatePendingDecisions() {
+	infohashes, err := idx.decisions.GetPendingAggregation(aggregationBatchSize)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get pending decisions")
+		return
+	}
+
+	if len(infohashes) == 0 {
+		return
+	}
+
+	aggregator := idx.newAggregator()
+	updated := 0
+
+	for _, infohash := range infohashes {
+		decisions, err := idx.decisions.GetByInfohash(infohash)
+		if err != nil {
+			log.Error().Err(err).Str("infohash", infohash).Msg("Failed to load decisions")
+			continue
+		}
+
+		result := aggregator.Aggregate(infohash, latestPerCurator(decisions))
+
+		if err := idx.decisions.UpdateAggregatedDecision(infohash, result.Decision); err != nil {
+			log.Error().Err(err).Str("infohash", infohash).Msg("Failed to store aggregated decision")
+			continue
+		}
+
+		// Without any valid curator decision the aggregate is only a default
+		if result.TotalCurators == 0 {
+			continue
+		}
+
+		setCurationStatus(infohash, result.Decision, result.AllReasons)
+		updated++
+	}
+
+	log.Info().
+		Int("infohashes", len(infohashes)).
+		Int("updated", updated).
+		Msg("Aggregated curator decisions")
+}
+
+// requeueAggregation marks decisions for a newly indexed torrent for
+// re-aggregation, so decisions that arrived before the torrent are applied
+func (idx *Indexer) requeueAggregation(infoHash string) {
+	_, err := database.Get().Exec(`
+		UPDATE verification_decisions SET aggregated_decision = NULL
+		WHERE target_infohash = ? AND aggregated_decision IS NOT NULL
+	`, infoHash)
+	if err != nil {
+		log.Error().Err(err).Str("info_hash", infoHash).Msg("Failed to requeue decision aggregation")
+	}
+}
+
+// latestPerCurator keeps only the most recent decision from each curator
+func latestPerCurator(decisions []*decision.VerificationDecision) []*decision.VerificationDecision {
+	latest := make(map[string]*decision.VerificationDecision)
+	var order []string
+
+	for _, d := range decisions {
+		existing, ok := latest[d.CuratorPubkey]
+		if !ok {
+			order = append(order, d.CuratorPubkey)
+			latest[d.CuratorPubkey] = d
+			continue
+		}
+		if d.CreatedAt.After(existing.CreatedAt) {
+			latest[d.CuratorPubkey] = d
+		}
+	}
+
+	result := make([]*decision.VerificationDecision, 0, len(order))
+	for _, pubkey := range order {
+		result = append(result, latest[pubkey])
+	}
+	return result
+}
@@ -0,0 +1,28 @@
+package indexer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gmonarque/lighthouse/internal/decision"
+)
+
+func TestLatestPerCurator(t *testing.T) {
+	now := time.Now()
+	decisions := []*decision.VerificationDecision{
+		{CuratorPubkey: "a", Decision: decision.DecisionAccept, CreatedAt: now.Add(-time.Hour)},
+		{CuratorPubkey: "b", Decision: decision.DecisionReject, CreatedAt: now},
+		{CuratorPubkey: "a", Decision: decision.DecisionReject, CreatedAt: now},
+	}
+
+	latest := latestPerCurator(decisions)
+	if len(latest) != 2 {
+		t.Fatalf("expected 2 decisions, got %d", len(latest))
+	}
+	if latest[0].CuratorPubkey != "a" || latest[0].Decision != decision.DecisionReject {
+		t.Errorf("expected latest reject from curator a, got %s from %s", latest[0].Decision, latest[0].CuratorPubkey)
+	}
+	if latest[1].CuratorPubkey != "b" {
+		t.Errorf("expected curator b second, got %s", latest[1].CuratorPubkey)
+	}
+}
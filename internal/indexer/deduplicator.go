@@ -6,6 +6,7 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/gmonarque/lighthouse/internal/database"
@@ -69,10 +70,15 @@ func (d *Deduplicator) Process(event *nostr.TorrentEvent, relayURL string) (bool
 			Int("file_count", len(event.Files)).
 			Msg("Categorizing new torrent")
 
+		// Parse season/episode information for TV searches
+		ep := ParseEpisodeInfo(event.Name)
+
 		result, err := db.Exec(`
-			INSERT OR IGNORE INTO torrents (info_hash, name, size, category, magnet_uri, files, trust_score, upload_count)
-			VALUES (?, ?, ?, ?, ?, ?, 10, 1)
-		`, event.InfoHash, event.Name, event.Size, category, event.MagnetURI, filesJSON)
+			INSERT OR IGNORE INTO torrents (info_hash, name, size, category, magnet_uri, files, trust_score, upload_count,
+				season, season_end, episode, episode_end, air_date)
+			VALUES (?, ?, ?, ?, ?, ?, 0, 1, ?, ?, ?, ?, ?)
+		`, event.InfoHash, event.Name, event.Size, category, event.MagnetURI, filesJSON,
+			nullInt(ep.Season), nullInt(ep.SeasonEnd), nullInt(ep.Episode), nullInt(ep.EpisodeEnd), nullString(ep.AirDate))
 
 		if err != nil {
 			return false, err
@@ -87,24 +93,16 @@ func (d *Deduplicator) Process(event *nostr.TorrentEvent, relayURL string) (bool
 				return false, nil // Silently skip
 			}
 			// Record this upload for the existing torrent
-			db.Exec(`
-				INSERT OR IGNORE INTO torrent_uploads (torrent_id, uploader_npub, nostr_event_id, relay_url)
-				VALUES (?, ?, ?, ?)
-			`, torrentID, event.Pubkey, event.EventID, relayURL)
+			d.recordUpload(db, torrentID, event, relayURL)
+			updateTrustScore(db, torrentID)
 			return false, nil
 		}
 
 		torrentID, _ = result.LastInsertId()
 
 		// Record the upload
-		_, err = db.Exec(`
-			INSERT OR IGNORE INTO torrent_uploads (torrent_id, uploader_npub, nostr_event_id, relay_url)
-			VALUES (?, ?, ?, ?)
-		`, torrentID, event.Pubkey, event.EventID, relayURL)
-
-		if err != nil {
-			log.Error().Err(err).Msg("Failed to record upload")
-		}
+		d.recordUpload(db, torrentID, event, relayURL)
+		updateTrustScore(db, torrentID)
 
 		log.Debug().
 			Str("info_hash", event.InfoHash).
@@ -130,21 +128,13 @@ func (d *Deduplicator) Process(event *nostr.TorrentEvent, relayURL string) (bool
 	}
 
 	// New upload of existing torrent
-	_, err = db.Exec(`
-		INSERT INTO torrent_uploads (torrent_id, uploader_npub, nostr_event_id, relay_url)
-		VALUES (?, ?, ?, ?)
-		ON CONFLICT(nostr_event_id) DO NOTHING
-	`, torrentID, event.Pubkey, event.EventID, relayURL)
-
-	if err != nil {
-		log.Error().Err(err).Msg("Failed to record upload")
-	}
+	d.recordUpload(db, torrentID, event, relayURL)
 
 	// Update torrent stats
 	_, err = db.Exec(`
 		UPDATE torrents SET
 			upload_count = (SELECT COUNT(DISTINCT uploader_npub) FROM torrent_uploads WHERE torrent_id = ?),
-			trust_score = trust_score + 10,
+			trust_score = `+torrentTrustScore+`,
 			updated_at = CURRENT_TIMESTAMP
 		WHERE id = ?
 	`, torrentID, torrentID)
@@ -161,58 +151,72 @@ func (d *Deduplicator) Process(event *nostr.TorrentEvent, relayURL string) (bool
 	return false, nil
 }
 
-// CalculateTrustScore calculates the trust score for a torrent
-func (d *Deduplicator) CalculateTrustScore(torrentID int64, userNpub string, trustDepth int) (int, error) {
-	db := database.Get()
+// recordUpload stores an upload with its metadata and merges it into the torrent
+func (d *Deduplicator) recordUpload(db *sql.DB, torrentID int64, event *nostr.TorrentEvent, relayURL string) {
+	result, err := db.Exec(`
+		INSERT INTO torrent_uploads (torrent_id, uploader_npub, nostr_event_id, relay_url,
+			name, size, category, description, magnet_uri, trackers, files, tags, event_created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(nostr_event_id) DO NOTHING
+	`, torrentID, event.Pubkey, event.EventID, relayURL,
+		event.Name, event.Size, determineCategoryCode(event), event.Description, event.MagnetURI,
+		marshalJSON(uploadTrackers(event)), marshalJSON(event.Files), marshalJSON(normalizeTags(event.ContentTags)),
+		event.CreatedAt)
 
-	// Base score
-	var baseScore int
-	err := db.QueryRow("SELECT trust_score FROM torrents WHERE id = ?", torrentID).Scan(&baseScore)
 	if err != nil {
-		return 0, err
+		log.Error().Err(err).Msg("Failed to record upload")
+		return
 	}
 
-	if trustDepth == 0 {
-		// Only count whitelisted uploaders
-		var trustedCount int
-		err = db.QueryRow(`
-			SELECT COUNT(DISTINCT tu.uploader_npub)
-			FROM torrent_uploads tu
-			JOIN trust_whitelist tw ON tu.uploader_npub = tw.npub
-			WHERE tu.torrent_id = ?
-		`, torrentID).Scan(&trustedCount)
+	// Already recorded by a concurrent insert
+	if n, _ := result.RowsAffected(); n == 0 {
+		return
+	}
 
-		if err != nil {
-			return baseScore, nil
-		}
+	storeTags(db, torrentID, event.ContentTags)
+	storeExternalIDs(db, torrentID, event)
+	mergeUploads(db, torrentID)
+}
+
+// Reprocess re-applies a stored event to the index. A known upload is updated
+// in place with freshly parsed metadata and its torrent is re-merged; unknown
+// events are processed as new uploads.
+func (d *Deduplicator) Reprocess(event *nostr.TorrentEvent, relayURL string) (bool, error) {
+	db := database.Get()
 
-		return baseScore + (trustedCount * 50), nil
+	var torrentID int64
+	err := db.QueryRow("SELECT torrent_id FROM torrent_uploads WHERE nostr_event_id = ?", event.EventID).Scan(&torrentID)
+	if err == sql.ErrNoRows {
+		return d.Process(event, relayURL)
+	} else if err != nil {
+		return false, err
 	}
 
-	if trustDepth >= 1 {
-		// Count uploaders who are followed by the user or in whitelist
-		var trustedCount int
-		err = db.QueryRow(`
-			SELECT COUNT(DISTINCT tu.uploader_npub)
-			FROM torrent_uploads tu
-			WHERE tu.torrent_id = ?
-			AND (
-				tu.uploader_npub IN (SELECT npub FROM trust_whitelist)
-				OR tu.uploader_npub IN (
-					SELECT followed_npub FROM trust_follows
-					WHERE follower_npub = ? AND depth <= ?
-				)
-			)
-		`, torrentID, userNpub, trustDepth).Scan(&trustedCount)
+	_, err = db.Exec(`
+		UPDATE torrent_uploads SET
+			name = ?, size = ?, category = ?, description = ?, magnet_uri = ?,
+			trackers = ?, files = ?, tags = ?, event_created_at = ?
+		WHERE nostr_event_id = ?
+	`, event.Name, event.Size, determineCategoryCode(event), event.Description, event.MagnetURI,
+		marshalJSON(uploadTrackers(event)), marshalJSON(event.Files), marshalJSON(normalizeTags(event.ContentTags)),
+		event.CreatedAt, event.EventID)
+	if err != nil {
+		return false, err
+	}
 
-		if err != nil {
-			return baseScore, nil
-		}
+	rebuildTags(db, torrentID)
+	storeExternalIDs(db, torrentID, event)
+	mergeUploads(db, torrentID)
 
-		return baseScore + (trustedCount * 50), nil
-	}
+	return false, nil
+}
 
-	return baseScore, nil
+// uploadTrackers returns the trackers of an upload, from tags or from the uploader's magnet URI
+func uploadTrackers(event *nostr.TorrentEvent) []string {
+	if len(event.Trackers) > 0 {
+		return event.Trackers
+	}
+	return nostr.MagnetTrackers(event.MagnetURI)
 }
 
 // RecalculateAllTrustScores recalculates trust scores for all torrents
@@ -228,15 +232,84 @@ func (d *Deduplicator) RecalculateAllTrustScores() error {
 		return err
 	}
 
-	// Recalculate base trust scores
-	_, err = db.Exec(`
-		UPDATE torrents SET
-			trust_score = 10 + (upload_count * 10)
-	`)
+	// Recalculate trust scores from the uploaders' propagated trust
+	_, err = db.Exec(`UPDATE torrents SET trust_score = ` + torrentTrustScore)
 
 	return err
 }
 
+// TrustScoreBase is the trust score of a torrent before its uploaders count
+const TrustScoreBase = 10
+
+// uploaderContribution is the SQL expression for what an uploader whose trust
+// score row is aliased s adds to a torrent's trust score, before rounding.
+// Uploaders with a negative score add nothing.
+const uploaderContribution = "MAX(s.score, 0) / 10.0"
+
+// torrentUploaders selects the distinct uploaders of the torrent given by the
+// %s expression, aliased u
+const torrentUploaders = "(SELECT DISTINCT uploader_npub FROM torrent_uploads WHERE torrent_id = %s) u"
+
+// torrentTrustScore is the SQL expression for a torrent's trust score:
+// TrustScoreBase, plus a tenth of the propagated trust score of each distinct
+// uploader
+var torrentTrustScore = strconv.Itoa(TrustScoreBase) + ` + COALESCE((
+	SELECT CAST(ROUND(SUM(` + uploaderContribution + `)) AS INTEGER)
+	FROM ` + fmt.Sprintf(torrentUploaders, "torrents.id") + `
+	JOIN trust_scores s ON s.pubkey = u.uploader_npub
+), 0)`
+
+// TrustScoreComponent is what one distinct uploader adds to a torrent's trust score
+type TrustScoreComponent struct {
+	Pubkey       string   // hex
+	Score        *float64 // propagated trust score, nil if the uploader has none
+	Contribution float64
+}
+
+// TrustScoreComponents returns what each distinct uploader adds to a torrent's
+// trust score, and the score they add up to
+func TrustScoreComponents(torrentID int64) ([]TrustScoreComponent, int64, error) {
+	db := database.Get()
+
+	rows, err := db.Query(`
+		SELECT u.uploader_npub, s.score, COALESCE(`+uploaderContribution+`, 0)
+		FROM `+fmt.Sprintf(torrentUploaders, "?")+`
+		LEFT JOIN trust_scores s ON s.pubkey = u.uploader_npub
+		ORDER BY s.score DESC
+	`, torrentID)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	components := make([]TrustScoreComponent, 0)
+	for rows.Next() {
+		var c TrustScoreComponent
+		var score sql.NullFloat64
+		if err := rows.Scan(&c.Pubkey, &score, &c.Contribution); err != nil {
+			continue
+		}
+		if score.Valid {
+			c.Score = &score.Float64
+		}
+		components = append(components, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	var computed int64
+	err = db.QueryRow("SELECT "+torrentTrustScore+" FROM torrents WHERE id = ?", torrentID).Scan(&computed)
+	return components, computed, err
+}
+
+// updateTrustScore recalculates the trust score of a torrent from its uploaders
+func updateTrustScore(db *sql.DB, torrentID int64) {
+	if _, err := db.Exec("UPDATE torrents SET trust_score = "+torrentTrustScore+" WHERE id = ?", torrentID); err != nil {
+		log.Error().Err(err).Int64("torrent_id", torrentID).Msg("Failed to update trust score")
+	}
+}
+
 // PurgeTorrentsFromUploader removes all torrents that only have uploads from a specific uploader
 func (d *Deduplicator) PurgeTorrentsFromUploader(npub string) (int64, error) {
 	db := database.Get()
@@ -0,0 +1,203 @@
+package indexer
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+
+	"github.com/gmonarque/lighthouse/internal/database"
+	"github.com/gmonarque/lighthouse/internal/nostr"
+	gonostr "github.com/nbd-wtf/go-nostr"
+	"github.com/rs/zerolog/log"
+)
+
+// processDeletionEvent applies a NIP-09 deletion request to the author's torrent uploads
+func (idx *Indexer) processDeletionEvent(event *gonostr.Event, relayURL string) {
+	if event.Kind != nostr.KindDeletion || !deletesTorrents(event) {
+		return
+	}
+
+	if ok, err := event.CheckSignature(); err != nil || !ok {
+		log.Debug().Str("event_id", event.ID).Str("relay", relayURL).Msg("Skipping deletion with invalid signature")
+		return
+	}
+
+	var ids []string
+	for _, tag := range event.Tags {
+		if len(tag) >= 2 && tag[0] == "e" && len(tag[1]) == 64 {
+			ids = append(ids, tag[1])
+		}
+	}
+
+	// Uploaders may retract what they uploaded whatever their current trust;
+	// only trusted authors may delete events that were not indexed yet
+	db := database.Get()
+	if !idx.isTrusted(event.PubKey, int64(event.CreatedAt)) {
+		ids = heldEventIDs(db, event.PubKey, ids)
+	}
+	if len(ids) == 0 {
+		return
+	}
+
+	removed, purged := applyDeletion(db, event.PubKey, event.ID, ids)
+	if removed == 0 {
+		return
+	}
+
+	log.Info().
+		Str("pubkey", event.PubKey).
+		Int("uploads_removed", removed).
+		Int("torrents_deleted", purged).
+		Msg("Applied deletion request")
+
+	database.LogActivity("upload_deleted", fmt.Sprintf("%s removed %d uploads, %d torrents deleted", event.PubKey, removed, purged))
+}
+
+// deletesTorrents reports whether a deletion may target torrent events.
+// Deletions that list their kinds in k tags are only applied if they include Kind 2003.
+func deletesTorrents(event *gonostr.Event) bool {
+	hasKinds := false
+	for _, tag := range event.Tags {
+		if len(tag) >= 2 && tag[0] == "k" {
+			hasKinds = true
+			if tag[1] == strconv.Itoa(nostr.KindTorrent) {
+				return true
+			}
+		}
+	}
+	return !hasKinds
+}
+
+// applyDeletion removes the author's uploads for the deleted events and
+// records them so they are never indexed again. Torrents left without uploads
+// are deleted, the others are re-merged. Returns the number of uploads removed
+// and torrents deleted.
+func applyDeletion(db *sql.DB, pubkey, deletionID string, eventIDs []string) (int, int) {
+	removed := 0
+	affected := make(map[int64]bool)
+
+	for _, eventID := range eventIDs {
+		// Keep the timestamp of events we held so relay sync doesn't fetch them again
+		var createdAt sql.NullInt64
+		db.QueryRow("SELECT created_at FROM torrent_events WHERE event_id = ? AND pubkey = ?", eventID, pubkey).Scan(&createdAt)
+
+		_, err := db.Exec(`
+			INSERT OR IGNORE INTO deleted_events (event_id, pubkey, created_at, deletion_event_id)
+			VALUES (?, ?, ?, ?)
+		`, eventID, pubkey, createdAt, deletionID)
+		if err != nil {
+			log.Error().Err(err).Str("event_id", eventID).Msg("Failed to record deletion")
+			continue
+		}
+
+		// Only the author may delete an event
+		db.Exec("DELETE FROM torrent_events WHERE event_id = ? AND pubkey = ?", eventID, pubkey)
+
+		var torrentID int64
+		err = db.QueryRow(`
+			SELECT torrent_id FROM torrent_uploads WHERE nostr_event_id = ? AND uploader_npub = ?
+		`, eventID, pubkey).Scan(&torrentID)
+		if err != nil {
+			continue
+		}
+
+		if _, err := db.Exec("DELETE FROM torrent_uploads WHERE nostr_event_id = ?", eventID); err != nil {
+			log.Error().Err(err).Str("event_id", eventID).Msg("Failed to remove deleted upload")
+			continue
+		}
+
+		affected[torrentID] = true
+		removed++
+	}
+
+	purged := 0
+	for torrentID := range affected {
+		if refreshAfterDeletion(db, torrentID, pubkey) {
+			purged++
+		}
+	}
+
+	return removed, purged
+}
+
+// refreshAfterDeletion deletes a torrent without uploads, or recounts its
+// uploads, rescores it and re-merges its metadata. Returns true if the torrent was deleted.
+func refreshAfterDeletion(db *sql.DB, torrentID int64, pubkey string) bool {
+	var remaining int
+	db.QueryRow("SELECT COUNT(*) FROM torrent_uploads WHERE torrent_id = ?", torrentID).Scan(&remaining)
+
+	if remaining == 0 {
+		if _, err := db.Exec("DELETE FROM torrents WHERE id = ?", torrentID); err != nil {
+			log.Error().Err(err).Int64("torrent_id", torrentID).Msg("Failed to delete torrent")
+			return false
+		}
+		return true
+	}
+
+	_, err := db.Exec(`
+		UPDATE torrents SET
+			upload_count = (SELECT COUNT(DISTINCT uploader_npub) FROM torrent_uploads WHERE torrent_id = ?),
+			trust_score = `+torrentTrustScore+`,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?
+	`, torrentID, torrentID)
+	if err != nil {
+		log.Error().Err(err).Int64("torrent_id", torrentID).Msg("Failed to recount uploads")
+	}
+
+	// Drop IDs the author supplied if they no longer upload this torrent
+	db.Exec(`
+		DELETE FROM torrent_external_ids
+		WHERE torrent_id = ? AND source = ?
+		AND NOT EXISTS (SELECT 1 FROM torrent_uploads WHERE torrent_id = ? AND uploader_npub = ?)
+	`, torrentID, pubkey, torrentID, pubkey)
+
+	rebuildTags(db, torrentID)
+	applyExternalIDs(db, torrentID)
+	mergeUploads(db, torrentID)
+
+	return false
+}
+
+// heldEventIDs returns the event IDs of a pubkey's uploads that are stored or indexed
+func heldEventIDs(db *sql.DB, pubkey string, eventIDs []string) []string {
+	var held []string
+	for _, eventID := range eventIDs {
+		var n int
+		db.QueryRow(`
+			SELECT (SELECT COUNT(*) FROM torrent_events WHERE event_id = ? AND pubkey = ?)
+				+ (SELECT COUNT(*) FROM torrent_uploads WHERE nostr_event_id = ? AND uploader_npub = ?)
+		`, eventID, pubkey, eventID, pubkey).Scan(&n)
+		if n > 0 {
+			held = append(held, eventID)
+		}
+	}
+	return held
+}
+
+// uploaderPubkeys returns the sorted hex pubkeys of everyone with an indexed upload
+func uploaderPubkeys() ([]string, error) {
+	rows, err := database.Get().Query("SELECT DISTINCT uploader_npub FROM torrent_uploads ORDER BY uploader_npub")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pubkeys []string
+	for rows.Next() {
+		var pk string
+		if rows.Scan(&pk) == nil && gonostr.IsValid32ByteHex(pk) {
+			pubkeys = append(pubkeys, pk)
+		}
+	}
+	return pubkeys, rows.Err()
+}
+
+// isDeleted reports whether the author of an event has asked to delete it
+func isDeleted(eventID, pubkey string) bool {
+	var n int
+	database.Get().QueryRow(`
+		SELECT COUNT(*) FROM deleted_events WHERE event_id = ? AND pubkey = ?
+	`, eventID, pubkey).Scan(&n)
+	return n > 0
+}
@@ -0,0 +1,100 @@
+//go:build fts5
+
+package indexer
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gmonarque/lighthouse/internal/config"
+	"github.com/gmonarque/lighthouse/internal/database"
+)
+
+// openTestDB initializes a database in a temporary directory
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	if _, err := config.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.Init(filepath.Join(dir, "lighthouse.db")); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { database.Close() })
+	return database.Get()
+}
+
+func TestApplyDeletionRescores(t *testing.T) {
+	db := openTestDB(t)
+
+	alice, bob := strings.Repeat("a", 64), strings.Repeat("b", 64)
+	mustExec := func(query string, args ...interface{}) {
+		t.Helper()
+		if _, err := db.Exec(query, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mustExec("INSERT INTO trust_scores (pubkey, npub, score) VALUES (?, 'npub-a', 80), (?, 'npub-b', 40)", alice, bob)
+	mustExec("INSERT INTO torrents (id, info_hash, name, magnet_uri, upload_count) VALUES (1, 'hash', 'Name', 'magnet:?xt=urn:btih:hash', 2)")
+	for _, upload := range []struct{ eventID, pubkey string }{
+		{strings.Repeat("1", 64), alice},
+		{strings.Repeat("2", 64), alice},
+		{strings.Repeat("3", 64), bob},
+	} {
+		mustExec("INSERT INTO torrent_uploads (torrent_id, uploader_npub, nostr_event_id, name) VALUES (1, ?, ?, 'Name')", upload.pubkey, upload.eventID)
+	}
+	mustExec("UPDATE torrents SET trust_score = " + torrentTrustScore)
+
+	check := func(step string, wantScore, wantUploaders int64) {
+		t.Helper()
+		var score, uploaders int64
+		if err := db.QueryRow("SELECT trust_score, upload_count FROM torrents WHERE id = 1").Scan(&score, &uploaders); err != nil {
+			t.Fatalf("%s: %v", step, err)
+		}
+		if score != wantScore || uploaders != wantUploaders {
+			t.Errorf("%s: trust_score = %d, upload_count = %d, want %d and %d", step, score, uploaders, wantScore, wantUploaders)
+		}
+		if _, computed, err := TrustScoreComponents(1); err != nil || computed != score {
+			t.Errorf("%s: stored score %d, computed %d (%v)", step, score, computed, err)
+		}
+	}
+	check("initial", 10+8+4, 2)
+
+	// Alice still uploads the torrent through her other event
+	if removed, purged := applyDeletion(db, alice, "del-1", []string{strings.Repeat("1", 64)}); removed != 1 || purged != 0 {
+		t.Fatalf("applyDeletion() = %d, %d, want 1, 0", removed, purged)
+	}
+	check("after a duplicate upload is deleted", 10+8+4, 2)
+
+	if removed, purged := applyDeletion(db, bob, "del-2", []string{strings.Repeat("3", 64)}); removed != 1 || purged != 0 {
+		t.Fatalf("applyDeletion() = %d, %d, want 1, 0", removed, purged)
+	}
+	check("after an uploader leaves", 10+8, 1)
+
+	// Only the author may delete an event
+	if removed, _ := applyDeletion(db, bob, "del-3", []string{strings.Repeat("2", 64)}); removed != 0 {
+		t.Errorf("applyDeletion() by another author removed %d uploads", removed)
+	}
+
+	if removed, purged := applyDeletion(db, alice, "del-4", []string{strings.Repeat("2", 64)}); removed != 1 || purged != 1 {
+		t.Fatalf("applyDeletion() = %d, %d, want 1, 1", removed, purged)
+	}
+	var n int
+	db.QueryRow("SELECT COUNT(*) FROM torrents").Scan(&n)
+	if n != 0 {
+		t.Error("a torrent without uploads should be deleted")
+	}
+}
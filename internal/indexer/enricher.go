@@ -1,6 +1,7 @@
 package indexer
 
 import (
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -22,18 +23,21 @@ type Enricher struct {
 
 // TMDBSearchResult represents TMDB search response
 type TMDBSearchResult struct {
-	Results []struct {
-		ID           int     `json:"id"`
-		Title        string  `json:"title"`
-		Name         string  `json:"name"` // For TV shows
-		Overview     string  `json:"overview"`
-		PosterPath   string  `json:"poster_path"`
-		BackdropPath string  `json:"backdrop_path"`
-		ReleaseDate  string  `json:"release_date"`
-		FirstAirDate string  `json:"first_air_date"` // For TV shows
-		VoteAverage  float64 `json:"vote_average"`
-		GenreIDs     []int   `json:"genre_ids"`
-	} `json:"results"`
+	Results []TMDBDetails `json:"results"`
+}
+
+// TMDBDetails represents a TMDB movie or TV show
+type TMDBDetails struct {
+	ID           int     `json:"id"`
+	Title        string  `json:"title"`
+	Name         string  `json:"name"` // For TV shows
+	Overview     string  `json:"overview"`
+	PosterPath   string  `json:"poster_path"`
+	BackdropPath string  `json:"backdrop_path"`
+	ReleaseDate  string  `json:"release_date"`
+	FirstAirDate string  `json:"first_air_date"` // For TV shows
+	VoteAverage  float64 `json:"vote_average"`
+	GenreIDs     []int   `json:"genre_ids"`
 }
 
 // OMDBResult represents OMDB API response
@@ -70,14 +74,30 @@ func (e *Enricher) EnrichTorrent(infoHash string) {
 	var id int64
 	var name string
 	var category int
+	var tmdbID sql.NullInt64
+	var imdbID sql.NullString
 	err := db.QueryRow(`
-		SELECT id, name, category FROM torrents WHERE info_hash = ?
-	`, infoHash).Scan(&id, &name, &category)
+		SELECT id, name, category, tmdb_id, imdb_id FROM torrents WHERE info_hash = ?
+	`, infoHash).Scan(&id, &name, &category, &tmdbID, &imdbID)
 
 	if err != nil {
 		return
 	}
 
+	// Uploader-supplied IDs allow exact lookups instead of guessing by name
+	if tmdbID.Valid || imdbID.String != "" {
+		if cfg.Enrichment.TMDBAPIKey != "" && tmdbID.Valid {
+			mediaType := tmdbMediaType(db, id, category)
+			if e.enrichFromTMDBByID(id, tmdbID.Int64, mediaType, cfg.Enrichment.TMDBAPIKey) {
+				return
+			}
+		}
+		if cfg.Enrichment.OMDBAPIKey != "" && imdbID.String != "" {
+			e.enrichFromOMDBByID(id, imdbID.String, cfg.Enrichment.OMDBAPIKey)
+		}
+		return
+	}
+
 	// Parse title and year from name
 	title, year := parseTitle(name)
 
@@ -94,6 +114,25 @@ func (e *Enricher) EnrichTorrent(infoHash string) {
 	}
 }
 
+// tmdbMediaType returns the TMDB media type for a torrent, preferring the
+// type given by the uploader's tmdb tag over the category
+func tmdbMediaType(db *sql.DB, torrentID int64, category int) string {
+	var mediaType sql.NullString
+	db.QueryRow(`
+		SELECT media_type FROM torrent_external_ids
+		WHERE torrent_id = ? AND id_type = 'tmdb' AND media_type IS NOT NULL
+		ORDER BY id LIMIT 1
+	`, torrentID).Scan(&mediaType)
+
+	if mediaType.String == "movie" || mediaType.String == "tv" {
+		return mediaType.String
+	}
+	if category >= 5000 && category < 6000 {
+		return "tv"
+	}
+	return "movie"
+}
+
 // enrichFromTMDB enriches from TMDB API
 func (e *Enricher) enrichFromTMDB(torrentID int64, title string, year int, category int, apiKey string) bool {
 	// Determine search type
@@ -138,7 +177,43 @@ func (e *Enricher) enrichFromTMDB(torrentID int64, title string, year int, categ
 
 	// Use first result
 	match := result.Results[0]
+	if !e.saveTMDBMatch(torrentID, &match) {
+		return false
+	}
+
+	// Name-based matches are checked against uploader-supplied IDs
+	recordEnrichedID(database.Get(), torrentID, "tmdb", searchType, strconv.Itoa(match.ID))
+	return true
+}
+
+// enrichFromTMDBByID enriches from TMDB API using a known TMDB ID
+func (e *Enricher) enrichFromTMDBByID(torrentID int64, tmdbID int64, mediaType string, apiKey string) bool {
+	detailsURL := fmt.Sprintf(
+		"https://api.themoviedb.org/3/%s/%d?api_key=%s",
+		mediaType, tmdbID, apiKey,
+	)
+
+	resp, err := e.httpClient.Get(detailsURL)
+	if err != nil {
+		log.Debug().Err(err).Msg("TMDB lookup failed")
+		return false
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return false
+	}
+
+	var match TMDBDetails
+	if err := json.NewDecoder(resp.Body).Decode(&match); err != nil {
+		return false
+	}
+
+	return e.saveTMDBMatch(torrentID, &match)
+}
 
+// saveTMDBMatch stores TMDB metadata on a torrent
+func (e *Enricher) saveTMDBMatch(torrentID int64, match *TMDBDetails) bool {
 	// Get title
 	matchTitle := match.Title
 	if matchTitle == "" {
@@ -165,13 +240,13 @@ func (e *Enricher) enrichFromTMDB(torrentID int64, title string, year int, categ
 		backdropURL = "https://image.tmdb.org/t/p/w1280" + match.BackdropPath
 	}
 
-	// Update database
+	// Update database, keeping any uploader-supplied TMDB ID
 	db := database.Get()
-	_, err = db.Exec(`
+	_, err := db.Exec(`
 		UPDATE torrents SET
 			title = ?,
 			year = ?,
-			tmdb_id = ?,
+			tmdb_id = COALESCE(tmdb_id, ?),
 			poster_url = ?,
 			backdrop_url = ?,
 			overview = ?,
@@ -205,26 +280,57 @@ func (e *Enricher) enrichFromOMDB(torrentID int64, title string, year int, apiKe
 		searchURL += fmt.Sprintf("&y=%d", year)
 	}
 
-	resp, err := e.httpClient.Get(searchURL)
+	result, ok := e.fetchOMDB(searchURL)
+	if !ok || !e.saveOMDBResult(torrentID, result) {
+		return false
+	}
+
+	// Name-based matches are checked against uploader-supplied IDs
+	recordEnrichedID(database.Get(), torrentID, "imdb", "", strings.ToLower(result.ImdbID))
+	return true
+}
+
+// enrichFromOMDBByID enriches from OMDB API using a known IMDb ID
+func (e *Enricher) enrichFromOMDBByID(torrentID int64, imdbID string, apiKey string) bool {
+	lookupURL := fmt.Sprintf(
+		"http://www.omdbapi.com/?apikey=%s&i=%s",
+		apiKey, url.QueryEscape(imdbID),
+	)
+
+	result, ok := e.fetchOMDB(lookupURL)
+	if !ok {
+		return false
+	}
+	return e.saveOMDBResult(torrentID, result)
+}
+
+// fetchOMDB performs an OMDB API request
+func (e *Enricher) fetchOMDB(requestURL string) (*OMDBResult, bool) {
+	resp, err := e.httpClient.Get(requestURL)
 	if err != nil {
 		log.Debug().Err(err).Msg("OMDB search failed")
-		return false
+		return nil, false
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return false
+		return nil, false
 	}
 
 	var result OMDBResult
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return false
+		return nil, false
 	}
 
 	if result.Response != "True" {
-		return false
+		return nil, false
 	}
 
+	return &result, true
+}
+
+// saveOMDBResult stores OMDB metadata on a torrent
+func (e *Enricher) saveOMDBResult(torrentID int64, result *OMDBResult) bool {
 	// Parse year
 	resultYear, _ := strconv.Atoi(result.Year)
 
@@ -237,20 +343,20 @@ func (e *Enricher) enrichFromOMDB(torrentID int64, title string, year int, apiKe
 		posterURL = result.Poster
 	}
 
-	// Update database
+	// Update database, keeping any uploader-supplied IMDb ID
 	db := database.Get()
-	_, err = db.Exec(`
+	_, err := db.Exec(`
 		UPDATE torrents SET
 			title = ?,
 			year = ?,
-			imdb_id = ?,
+			imdb_id = COALESCE(NULLIF(imdb_id, ''), ?),
 			poster_url = COALESCE(NULLIF(poster_url, ''), ?),
 			overview = ?,
 			genres = ?,
 			rating = ?,
 			updated_at = CURRENT_TIMESTAMP
 		WHERE id = ?
-	`, result.Title, resultYear, result.ImdbID, posterURL, result.Plot, result.Genre, rating, torrentID)
+	`, result.Title, resultYear, strings.ToLower(result.ImdbID), posterURL, result.Plot, result.Genre, rating, torrentID)
 
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to update torrent metadata")
@@ -0,0 +1,167 @@
+package indexer
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gmonarque/lighthouse/internal/database"
+	"github.com/rs/zerolog/log"
+)
+
+// EpisodeInfo contains season and episode information parsed from a release name
+type EpisodeInfo struct {
+	Season     int    // 0 if unknown
+	SeasonEnd  int    // Last season of a multi-season pack, 0 otherwise
+	Episode    int    // 0 for season packs
+	EpisodeEnd int    // Last episode of a multi-episode release, 0 otherwise
+	AirDate    string // YYYY-MM-DD for daily releases
+}
+
+// episodeBackfillSetting marks that existing torrents have been parsed
+const episodeBackfillSetting = "episode_info_backfilled"
+
+var (
+	// S01E02, S01E02E03, S01E02-E04, S01E02-04
+	sxxexxPattern = regexp.MustCompile(`\bs(\d{1,2})[ ._]?e(\d{1,3})((?:[-._ ]?e\d{1,3})*)(?:-(\d{1,3})\b)?`)
+	extraEpisode  = regexp.MustCompile(`e(\d{1,3})`)
+
+	// 1x02, 1x02-03, 1x02-1x03
+	nxnnPattern = regexp.MustCompile(`\b(\d{1,2})x(\d{2,3})(?:-(?:\d{1,2}x)?(\d{2,3}))?\b`)
+
+	// 2024.05.01, 2024-05-01, 2024 05 01
+	dailyPattern = regexp.MustCompile(`\b((?:19|20)\d{2})[-._ ](\d{2})[-._ ](\d{2})\b`)
+
+	// S01, S01-S03, S01-03
+	seasonPackPattern = regexp.MustCompile(`\bs(\d{1,2})(?:-s?(\d{1,2}))?\b`)
+
+	// Season 1, Season 1-3, Seasons 1 to 3
+	seasonWordPattern = regexp.MustCompile(`\bseasons?[ ._]?(\d{1,2})(?:[ ._]?(?:-|to)[ ._]?(\d{1,2}))?\b`)
+)
+
+// IsSeasonPack returns true if the release covers whole seasons
+func (e EpisodeInfo) IsSeasonPack() bool {
+	return e.Season > 0 && e.Episode == 0 && e.AirDate == ""
+}
+
+// ParseEpisodeInfo extracts season, episode and air date information from a release name
+func ParseEpisodeInfo(name string) EpisodeInfo {
+	// Underscores are word characters and would defeat the \b anchors
+	nameLower := strings.ToLower(strings.ReplaceAll(name, "_", "."))
+
+	if m := sxxexxPattern.FindStringSubmatch(nameLower); m != nil {
+		info := EpisodeInfo{Season: atoi(m[1]), Episode: atoi(m[2])}
+		last := info.Episode
+		for _, extra := range extraEpisode.FindAllStringSubmatch(m[3], -1) {
+			last = atoi(extra[1])
+		}
+		if m[4] != "" {
+			last = atoi(m[4])
+		}
+		if last > info.Episode {
+			info.EpisodeEnd = last
+		}
+		return info
+	}
+
+	if m := nxnnPattern.FindStringSubmatch(nameLower); m != nil {
+		info := EpisodeInfo{Season: atoi(m[1]), Episode: atoi(m[2])}
+		if end := atoi(m[3]); end > info.Episode {
+			info.EpisodeEnd = end
+		}
+		return info
+	}
+
+	if m := dailyPattern.FindStringSubmatch(nameLower); m != nil {
+		month, day := atoi(m[2]), atoi(m[3])
+		if month >= 1 && month <= 12 && day >= 1 && day <= 31 {
+			return EpisodeInfo{
+				Season:  atoi(m[1]),
+				AirDate: fmt.Sprintf("%s-%s-%s", m[1], m[2], m[3]),
+			}
+		}
+	}
+
+	m := seasonPackPattern.FindStringSubmatch(nameLower)
+	if m == nil {
+		m = seasonWordPattern.FindStringSubmatch(nameLower)
+	}
+	if m != nil {
+		info := EpisodeInfo{Season: atoi(m[1])}
+		if end := atoi(m[2]); end > info.Season {
+			info.SeasonEnd = end
+		}
+		return info
+	}
+
+	return EpisodeInfo{}
+}
+
+// backfillEpisodeInfo parses episode information for torrents indexed before
+// the columns existed. Runs once per database.
+func backfillEpisodeInfo() {
+	done, err := database.GetSetting(episodeBackfillSetting)
+	if err != nil || done == "true" {
+		return
+	}
+
+	db := database.Get()
+	rows, err := db.Query("SELECT id, name FROM torrents WHERE season IS NULL AND air_date IS NULL")
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to load torrents for episode backfill")
+		return
+	}
+
+	type pending struct {
+		id   int64
+		info EpisodeInfo
+	}
+	var updates []pending
+	for rows.Next() {
+		var id int64
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			continue
+		}
+		if info := ParseEpisodeInfo(name); info.Season > 0 {
+			updates = append(updates, pending{id: id, info: info})
+		}
+	}
+	rows.Close()
+
+	for _, u := range updates {
+		_, err := db.Exec(`
+			UPDATE torrents SET season = ?, season_end = ?, episode = ?, episode_end = ?, air_date = ?
+			WHERE id = ?
+		`, nullInt(u.info.Season), nullInt(u.info.SeasonEnd), nullInt(u.info.Episode),
+			nullInt(u.info.EpisodeEnd), nullString(u.info.AirDate), u.id)
+		if err != nil {
+			log.Error().Err(err).Int64("torrent_id", u.id).Msg("Failed to store episode info")
+			return
+		}
+	}
+
+	if err := database.SetSetting(episodeBackfillSetting, "true"); err != nil {
+		log.Warn().Err(err).Msg("Failed to mark episode backfill complete")
+	}
+
+	log.Info().Int("updated", len(updates)).Msg("Backfilled episode information")
+}
+
+// atoi converts a matched number, returning 0 for empty input
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+// nullInt maps a zero value to SQL NULL
+func nullInt(n int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(n), Valid: n != 0}
+}
+
+// nullString maps an empty string to SQL NULL
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
@@ -0,0 +1,47 @@
+package indexer
+
+import "testing"
+
+func TestParseEpisodeInfo(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected EpisodeInfo
+	}{
+		{"Show.Name.S01E02.1080p.WEB-DL.x264-GROUP", EpisodeInfo{Season: 1, Episode: 2}},
+		{"Show Name S12E105 720p HDTV", EpisodeInfo{Season: 12, Episode: 105}},
+		{"Show_Name_s03e04_HDTV", EpisodeInfo{Season: 3, Episode: 4}},
+		{"Show.Name.S01E01E02.720p", EpisodeInfo{Season: 1, Episode: 1, EpisodeEnd: 2}},
+		{"Show.Name.S01E01-E03.720p", EpisodeInfo{Season: 1, Episode: 1, EpisodeEnd: 3}},
+		{"Show.Name.S01E01-03.720p", EpisodeInfo{Season: 1, Episode: 1, EpisodeEnd: 3}},
+		{"Show Name 1x02 HDTV", EpisodeInfo{Season: 1, Episode: 2}},
+		{"Show Name 2x05-06 HDTV", EpisodeInfo{Season: 2, Episode: 5, EpisodeEnd: 6}},
+		{"Show.Name.S02.1080p.BluRay.x264-GROUP", EpisodeInfo{Season: 2}},
+		{"Show.Name.S01-S03.Complete.720p", EpisodeInfo{Season: 1, SeasonEnd: 3}},
+		{"Show Name Season 4 Complete", EpisodeInfo{Season: 4}},
+		{"Show Name Seasons 1-5 1080p", EpisodeInfo{Season: 1, SeasonEnd: 5}},
+		{"Daily.Show.2024.05.01.720p.WEB", EpisodeInfo{Season: 2024, AirDate: "2024-05-01"}},
+		{"Daily Show 2024-12-31 HDTV", EpisodeInfo{Season: 2024, AirDate: "2024-12-31"}},
+		{"Movie.Name.2019.1080p.BluRay.x264", EpisodeInfo{}},
+		{"Movie Name 1920x1080", EpisodeInfo{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseEpisodeInfo(tt.name); got != tt.expected {
+				t.Errorf("ParseEpisodeInfo(%q) = %+v, want %+v", tt.name, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestEpisodeInfoIsSeasonPack(t *testing.T) {
+	if !ParseEpisodeInfo("Show.S02.1080p").IsSeasonPack() {
+		t.Error("expected season pack")
+	}
+	if ParseEpisodeInfo("Show.S02E01.1080p").IsSeasonPack() {
+		t.Error("episode should not be a season pack")
+	}
+	if ParseEpisodeInfo("Show.2024.05.01.720p").IsSeasonPack() {
+		t.Error("daily episode should not be a season pack")
+	}
+}
@@ -0,0 +1,158 @@
+package indexer
+
+import (
+	"database/sql"
+	"strconv"
+
+	"github.com/gmonarque/lighthouse/internal/nostr"
+	"github.com/rs/zerolog/log"
+)
+
+// externalIDSourceEnricher marks IDs found by name-based enrichment
+const externalIDSourceEnricher = "enricher"
+
+// externalIDRow is a stored external ID with its source
+type externalIDRow struct {
+	Type   string
+	Value  string
+	Source string
+}
+
+// storeExternalIDs records the external IDs supplied by an uploader and
+// updates the torrent's ID columns
+func storeExternalIDs(db *sql.DB, torrentID int64, event *nostr.TorrentEvent) {
+	if len(event.ExternalIDs) == 0 {
+		return
+	}
+
+	for _, id := range event.ExternalIDs {
+		_, err := db.Exec(`
+			INSERT OR IGNORE INTO torrent_external_ids (torrent_id, id_type, media_type, value, source)
+			VALUES (?, ?, ?, ?, ?)
+		`, torrentID, id.Type, nullString(id.MediaType), id.Value, event.Pubkey)
+		if err != nil {
+			log.Error().Err(err).Int64("torrent_id", torrentID).Msg("Failed to store external ID")
+		}
+	}
+
+	applyExternalIDs(db, torrentID)
+}
+
+// recordEnrichedID records an ID found by name-based enrichment and
+// re-checks it against uploader-supplied IDs
+func recordEnrichedID(db *sql.DB, torrentID int64, idType, mediaType, value string) {
+	if value == "" || value == "0" {
+		return
+	}
+
+	_, err := db.Exec(`
+		INSERT OR IGNORE INTO torrent_external_ids (torrent_id, id_type, media_type, value, source)
+		VALUES (?, ?, ?, ?, ?)
+	`, torrentID, idType, nullString(mediaType), value, externalIDSourceEnricher)
+	if err != nil {
+		log.Error().Err(err).Int64("torrent_id", torrentID).Msg("Failed to store enriched ID")
+		return
+	}
+
+	applyExternalIDs(db, torrentID)
+}
+
+// applyExternalIDs sets the torrent's imdb_id, tmdb_id and tvdb_id from
+// uploader-supplied IDs and flags conflicts
+func applyExternalIDs(db *sql.DB, torrentID int64) {
+	rows, err := db.Query(`
+		SELECT id_type, value, source FROM torrent_external_ids
+		WHERE torrent_id = ? AND id_type IN ('imdb', 'tmdb', 'tvdb')
+		ORDER BY id
+	`, torrentID)
+	if err != nil {
+		log.Error().Err(err).Int64("torrent_id", torrentID).Msg("Failed to load external IDs")
+		return
+	}
+
+	var ids []externalIDRow
+	for rows.Next() {
+		var row externalIDRow
+		if err := rows.Scan(&row.Type, &row.Value, &row.Source); err == nil {
+			ids = append(ids, row)
+		}
+	}
+	rows.Close()
+
+	chosen, conflict := resolveExternalIDs(ids)
+
+	var tmdbID, tvdbID sql.NullInt64
+	if v, err := strconv.ParseInt(chosen["tmdb"], 10, 64); err == nil {
+		tmdbID = sql.NullInt64{Int64: v, Valid: true}
+	}
+	if v, err := strconv.ParseInt(chosen["tvdb"], 10, 64); err == nil {
+		tvdbID = sql.NullInt64{Int64: v, Valid: true}
+	}
+
+	// Uploader-supplied IDs take precedence over enrichment results
+	_, err = db.Exec(`
+		UPDATE torrents SET
+			imdb_id = COALESCE(?, imdb_id),
+			tmdb_id = COALESCE(?, tmdb_id),
+			tvdb_id = COALESCE(?, tvdb_id),
+			external_id_conflict = ?,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?
+	`, nullString(chosen["imdb"]), tmdbID, tvdbID, conflict, torrentID)
+	if err != nil {
+		log.Error().Err(err).Int64("torrent_id", torrentID).Msg("Failed to apply external IDs")
+		return
+	}
+
+	if conflict {
+		log.Debug().Int64("torrent_id", torrentID).Msg("Conflicting external IDs")
+	}
+}
+
+// resolveExternalIDs picks the uploader-supplied ID for each type by majority,
+// breaking ties by first appearance. Reports a conflict if uploaders disagree or
+// enrichment found a different ID than the uploaders supplied.
+func resolveExternalIDs(ids []externalIDRow) (map[string]string, bool) {
+	votes := make(map[string]map[string]int)
+	var order []externalIDRow
+	enriched := make(map[string]map[string]bool)
+
+	for _, id := range ids {
+		if id.Source == externalIDSourceEnricher {
+			if enriched[id.Type] == nil {
+				enriched[id.Type] = make(map[string]bool)
+			}
+			enriched[id.Type][id.Value] = true
+			continue
+		}
+		if votes[id.Type] == nil {
+			votes[id.Type] = make(map[string]int)
+		}
+		if votes[id.Type][id.Value] == 0 {
+			order = append(order, id)
+		}
+		votes[id.Type][id.Value]++
+	}
+
+	chosen := make(map[string]string)
+	for _, id := range order {
+		current, ok := chosen[id.Type]
+		if !ok || votes[id.Type][id.Value] > votes[id.Type][current] {
+			chosen[id.Type] = id.Value
+		}
+	}
+
+	conflict := false
+	for idType, values := range votes {
+		if len(values) > 1 {
+			conflict = true
+		}
+		for value := range enriched[idType] {
+			if value != chosen[idType] {
+				conflict = true
+			}
+		}
+	}
+
+	return chosen, conflict
+}
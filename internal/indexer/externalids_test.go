@@ -0,0 +1,63 @@
+package indexer
+
+import "testing"
+
+func TestResolveExternalIDs(t *testing.T) {
+	tests := []struct {
+		name     string
+		ids      []externalIDRow
+		expected map[string]string
+		conflict bool
+	}{
+		{
+			name:     "single uploader",
+			ids:      []externalIDRow{{"imdb", "tt0111161", "a"}, {"tmdb", "278", "a"}},
+			expected: map[string]string{"imdb": "tt0111161", "tmdb": "278"},
+		},
+		{
+			name:     "uploaders agree",
+			ids:      []externalIDRow{{"imdb", "tt0111161", "a"}, {"imdb", "tt0111161", "b"}},
+			expected: map[string]string{"imdb": "tt0111161"},
+		},
+		{
+			name:     "majority wins",
+			ids:      []externalIDRow{{"tmdb", "1", "a"}, {"tmdb", "2", "b"}, {"tmdb", "2", "c"}},
+			expected: map[string]string{"tmdb": "2"},
+			conflict: true,
+		},
+		{
+			name:     "tie keeps first",
+			ids:      []externalIDRow{{"tmdb", "1", "a"}, {"tmdb", "2", "b"}},
+			expected: map[string]string{"tmdb": "1"},
+			conflict: true,
+		},
+		{
+			name:     "enricher disagrees",
+			ids:      []externalIDRow{{"imdb", "tt0111161", "a"}, {"imdb", "tt0068646", externalIDSourceEnricher}},
+			expected: map[string]string{"imdb": "tt0111161"},
+			conflict: true,
+		},
+		{
+			name:     "enricher only",
+			ids:      []externalIDRow{{"tmdb", "278", externalIDSourceEnricher}},
+			expected: map[string]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chosen, conflict := resolveExternalIDs(tt.ids)
+			if conflict != tt.conflict {
+				t.Errorf("conflict = %v, want %v", conflict, tt.conflict)
+			}
+			if len(chosen) != len(tt.expected) {
+				t.Fatalf("chosen = %v, want %v", chosen, tt.expected)
+			}
+			for idType, value := range tt.expected {
+				if chosen[idType] != value {
+					t.Errorf("chosen[%q] = %q, want %q", idType, chosen[idType], value)
+				}
+			}
+		})
+	}
+}
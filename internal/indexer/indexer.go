@@ -2,12 +2,16 @@ package indexer
 
 import (
 	"context"
+	"errors"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/gmonarque/lighthouse/internal/comments"
 	"github.com/gmonarque/lighthouse/internal/config"
+	"github.com/gmonarque/lighthouse/internal/curator"
 	"github.com/gmonarque/lighthouse/internal/database"
+	"github.com/gmonarque/lighthouse/internal/decision"
 	"github.com/gmonarque/lighthouse/internal/nostr"
 	"github.com/gmonarque/lighthouse/internal/trust"
 	gonostr "github.com/nbd-wtf/go-nostr"
@@ -19,17 +23,55 @@ type Indexer struct {
 	relayManager *nostr.RelayManager
 	enricher     *Enricher
 	deduplicator *Deduplicator
+	curator      *curator.Curator
+	decisions    *decision.Storage
+	comments     *comments.Storage
 	running      bool
 	mu           sync.RWMutex
 	ctx          context.Context
 	cancel       context.CancelFunc
 	stats        IndexerStats
 
+	// Trusted authors of the live torrent and deletion subscriptions
+	authors       []string
+	authorsCancel context.CancelFunc
+	authorsMu     sync.Mutex
+
+	// Whether a historical fetch is running, and whether another was requested
+	// while it ran, guarded by syncMu
+	syncing     bool
+	syncPending bool
+	syncMu      sync.Mutex
+
+	// Generation of the trust scores torrents were last scored with
+	scoreGeneration uint64
+
+	// Held while the follow graph is being crawled
+	crawlMu sync.Mutex
+	// Delay before retrying a crawl that fetched no contact list, guarded by crawlMu
+	crawlRetry time.Duration
+
+	// Live subscription to comments from trusted authors and on recent torrents
+	commentsCancel context.CancelFunc
+	commentsMu     sync.Mutex
+
+	// Live subscription to NIP-51 lists synced into the whitelist and blacklist
+	listsCancel context.CancelFunc
+	listsMu     sync.Mutex
+
+	// Whitelist and blacklist as last published, by list kind
+	published   map[int]string
+	publishedAt gonostr.Timestamp
+	publishMu   sync.Mutex
+
+	// Progress of the current or last reindex job
+	reindex   ReindexProgress
+	reindexMu sync.RWMutex
+
 	// Cached trust data to avoid repeated DB queries per event
-	trustedSet   map[string]bool // hex pubkeys that are trusted
-	blacklistSet map[string]bool // hex pubkeys that are blacklisted
-	cacheMu      sync.RWMutex
-	cacheExpiry  time.Time
+	uploaderPolicy *trust.UploaderPolicy // trusted and blacklisted hex pubkeys with their time bounds
+	cacheMu        sync.RWMutex
+	cacheExpiry    time.Time
 }
 
 // IndexerStats tracks indexer statistics
@@ -37,6 +79,7 @@ type IndexerStats struct {
 	TorrentsProcessed int64
 	TorrentsAdded     int64
 	TorrentsDuplicate int64
+	TorrentsRejected  int64
 	EventsReceived    int64
 	LastEventAt       time.Time
 	StartedAt         time.Time
@@ -44,10 +87,15 @@ type IndexerStats struct {
 
 // New creates a new Indexer
 func New(relayManager *nostr.RelayManager) *Indexer {
+	// Stored events let relays supporting NIP-77 send only what we lack
+	relayManager.SetEventStore(torrentEventStore{})
+
 	return &Indexer{
 		relayManager: relayManager,
 		enricher:     NewEnricher(),
 		deduplicator: NewDeduplicator(),
+		decisions:    decision.NewStorage(),
+		comments:     comments.NewStorage(),
 	}
 }
 
@@ -71,63 +119,46 @@ func (idx *Indexer) Start(ctx context.Context) error {
 		return err
 	}
 
+	// Parse episode information for torrents indexed before it was stored
+	go backfillEpisodeInfo()
+
+	// Consume decisions from external curators in remote and hybrid modes
+	if remoteCurationEnabled() {
+		if err := idx.subscribeDecisions(); err != nil {
+			log.Warn().Err(err).Msg("Failed to subscribe to curator decisions")
+		}
+	}
+
 	// Get trusted uploaders and subscribe specifically for their events
 	// This is more efficient than fetching all events and filtering locally
-	wot := trust.NewWebOfTrust()
-	trustedUploaders, err := wot.GetTrustedUploaders()
+	idx.authorsMu.Lock()
+	idx.refreshTrustScores()
+	trustedPubkeys, err := trustedAuthors()
 	if err != nil {
+		idx.authorsMu.Unlock()
 		log.Error().Err(err).Msg("Failed to get trusted uploaders")
 		return err
 	}
 
-	if len(trustedUploaders) == 0 {
-		log.Warn().Msg("No trusted uploaders configured - indexer will not fetch any torrents")
-		return nil
-	}
-
-	// Convert npubs to hex pubkeys for the subscription filter
-	var trustedPubkeys []string
-	for _, npub := range trustedUploaders {
-		pubkey, err := nostr.NpubToHex(npub)
-		if err != nil {
-			log.Warn().Str("npub", npub).Err(err).Msg("Failed to convert npub to hex")
-			continue
-		}
-		trustedPubkeys = append(trustedPubkeys, pubkey)
-	}
-
-	log.Info().Int("trusted_uploaders", len(trustedPubkeys)).Msg("Subscribing to trusted uploaders")
-
-	// Fetch history via paginated queries, resuming from the latest event we already have
-	go func() {
-		sinceTimestamp, err := database.GetLatestEventTimestamp()
-		if err != nil {
-			log.Warn().Err(err).Msg("Failed to get latest event timestamp, fetching full history")
-			sinceTimestamp = 0
-		}
-
-		if sinceTimestamp > 0 {
-			log.Info().Int64("since_unix", sinceTimestamp).Msg("Resuming historical fetch from last known event")
-		} else {
-			log.Info().Msg("Starting full historical fetch (first run)")
-		}
-
-		if err := idx.relayManager.FetchAllHistoricalTorrents(idx.ctx, trustedPubkeys, sinceTimestamp, func(event *gonostr.Event, relayURL string) {
-			idx.processEvent(event, relayURL)
-		}); err != nil {
-			log.Error().Err(err).Msg("Historical fetch failed")
-		}
-	}()
-
-	// Subscribe to torrent events from trusted uploaders only (real-time + latest batch)
-	err = idx.relayManager.SubscribeTrustedTorrents(idx.ctx, trustedPubkeys, func(event *gonostr.Event, relayURL string) {
-		idx.processEvent(event, relayURL)
-	})
+	err = idx.subscribeAuthors(trustedPubkeys)
+	idx.authorsMu.Unlock()
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to subscribe to torrents")
 		return err
 	}
 
+	if len(trustedPubkeys) == 0 {
+		// Subscriptions start once uploaders are trusted, see RefreshTrustedAuthors
+		log.Warn().Msg("No trusted uploaders configured - indexer will not fetch any torrents")
+	} else {
+		// Fetch history via paginated queries, resuming from each relay's sync cursor
+		idx.syncHistory()
+	}
+
+	// Sync subscribed NIP-51 lists into the whitelist and blacklist
+	go idx.SyncTrustLists()
+	go idx.publishTrustLists()
+
 	// Start background tasks
 	go idx.runBackgroundTasks()
 
@@ -186,29 +217,55 @@ func (idx *Indexer) processEvent(event *gonostr.Event, relayURL string) {
 		log.Info().Int64("events_received", eventsReceived).Str("relay", relayURL).Msg("Processing events")
 	}
 
+	idx.ingestEvent(event, relayURL, false)
+}
+
+// ingestEvent runs a torrent event through filtering, curation and deduplication.
+// Replayed events update their existing upload instead of being skipped as seen.
+// Returns true if the event was indexed.
+func (idx *Indexer) ingestEvent(event *gonostr.Event, relayURL string, replay bool) bool {
+	// A replayed event that no longer passes the rules loses its upload
+	skip := func() bool {
+		if replay {
+			idx.unlinkReplayed(event)
+		}
+		return false
+	}
+
 	// Parse the event
 	torrentEvent, err := nostr.ParseTorrentEvent(event)
 	if err != nil || torrentEvent == nil {
-		return
+		return false
 	}
 
 	// Skip if no info hash
 	if torrentEvent.InfoHash == "" {
 		log.Debug().Str("event_id", event.ID).Msg("Skipping event without info hash")
-		return
+		return false
 	}
 
 	// Check if uploader is blacklisted
-	if idx.isBlacklisted(torrentEvent.Pubkey) {
+	if idx.isBlacklisted(torrentEvent.Pubkey, torrentEvent.CreatedAt) {
 		log.Debug().Str("pubkey", torrentEvent.Pubkey).Msg("Skipping blacklisted uploader")
-		return
+		return skip()
 	}
 
 	// Check if uploader is trusted (whitelist + follows based on trust depth)
-	if !idx.isTrusted(torrentEvent.Pubkey) {
+	if !idx.isTrusted(torrentEvent.Pubkey, torrentEvent.CreatedAt) {
 		// Only log occasionally to avoid spam
 		log.Debug().Str("pubkey", torrentEvent.Pubkey).Msg("Skipping untrusted uploader")
-		return
+		return skip()
+	}
+
+	// Never index an event its author has deleted
+	if isDeleted(event.ID, event.PubKey) {
+		log.Debug().Str("event_id", event.ID).Msg("Skipping deleted event")
+		return false
+	}
+
+	// Keep the verbatim event so it can be reindexed with future rules
+	if !replay {
+		storeRawEvent(event, relayURL)
 	}
 
 	// Check tag filter
@@ -217,39 +274,83 @@ func (idx *Indexer) processEvent(event *gonostr.Event, relayURL string) {
 			Str("info_hash", torrentEvent.InfoHash).
 			Strs("tags", torrentEvent.ContentTags).
 			Msg("Skipping torrent that doesn't match tag filter")
-		return
+		return skip()
+	}
+
+	// Run local curation; in local mode rejected torrents are never indexed,
+	// in hybrid mode they are indexed but hidden until remote decisions arrive
+	verdict := idx.curate(event)
+	if verdict != nil && verdict.Decision == decision.DecisionReject && config.Get().Curator.Mode == "local" {
+		// Hide any copy already indexed from another upload
+		idx.applyLocalCuration(torrentEvent.InfoHash, verdict)
+
+		if !replay {
+			idx.mu.Lock()
+			idx.stats.TorrentsRejected++
+			idx.mu.Unlock()
+		}
+
+		log.Debug().
+			Str("info_hash", torrentEvent.InfoHash).
+			Str("reason", string(verdict.GetPrimaryReason())).
+			Msg("Skipping torrent rejected by curator")
+		return skip()
 	}
 
-	// Log trusted events that pass all filters
-	log.Info().
-		Str("info_hash", torrentEvent.InfoHash).
-		Str("name", torrentEvent.Name).
-		Str("pubkey", torrentEvent.Pubkey[:16]+"...").
-		Msg("Indexing trusted torrent")
+	// Log trusted events that pass all filters (replays report progress instead)
+	if !replay {
+		log.Info().
+			Str("info_hash", torrentEvent.InfoHash).
+			Str("name", torrentEvent.Name).
+			Str("pubkey", torrentEvent.Pubkey[:16]+"...").
+			Msg("Indexing trusted torrent")
+	}
 
 	// Process with deduplicator
-	isNew, err := idx.deduplicator.Process(torrentEvent, relayURL)
+	var isNew bool
+	if replay {
+		isNew, err = idx.deduplicator.Reprocess(torrentEvent, relayURL)
+	} else {
+		isNew, err = idx.deduplicator.Process(torrentEvent, relayURL)
+	}
 	if err != nil {
 		log.Error().Err(err).Str("info_hash", torrentEvent.InfoHash).Msg("Failed to process torrent")
-		return
+		return false
 	}
 
-	idx.mu.Lock()
-	idx.stats.TorrentsProcessed++
-	if isNew {
-		idx.stats.TorrentsAdded++
-	} else {
-		idx.stats.TorrentsDuplicate++
+	if verdict != nil {
+		idx.applyLocalCuration(torrentEvent.InfoHash, verdict)
+	}
+
+	// Replays report their own progress instead of live stats
+	if !replay {
+		idx.mu.Lock()
+		idx.stats.TorrentsProcessed++
+		if verdict != nil && verdict.Decision == decision.DecisionReject {
+			idx.stats.TorrentsRejected++
+		}
+		if isNew {
+			idx.stats.TorrentsAdded++
+		} else {
+			idx.stats.TorrentsDuplicate++
+		}
+		idx.mu.Unlock()
 	}
-	idx.mu.Unlock()
 
 	// Enrich metadata for new torrents
 	if isNew {
 		go idx.enricher.EnrichTorrent(torrentEvent.InfoHash)
+
+		// Apply remote decisions received before the torrent itself
+		if remoteCurationEnabled() {
+			idx.requeueAggregation(torrentEvent.InfoHash)
+		}
 	}
+
+	return true
 }
 
-// refreshTrustCache rebuilds the in-memory trust and blacklist sets from the DB.
+// refreshTrustCache rebuilds the in-memory uploader policy from the DB.
 // The cache is refreshed at most once per minute.
 func (idx *Indexer) refreshTrustCache() {
 	idx.cacheMu.RLock()
@@ -267,66 +368,35 @@ func (idx *Indexer) refreshTrustCache() {
 		return
 	}
 
-	db := database.Get()
-
-	// Build trusted set (store both hex and npub forms for fast lookup)
 	wot := trust.NewWebOfTrust()
-	trustedUploaders, err := wot.GetTrustedUploaders()
+	policy, err := wot.GetUploaderPolicy()
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to refresh trusted uploaders cache")
 		return
 	}
 
-	newTrusted := make(map[string]bool, len(trustedUploaders)*2)
-	for _, npubOrHex := range trustedUploaders {
-		newTrusted[npubOrHex] = true
-		if hexPk, err := nostr.NpubToHex(npubOrHex); err == nil {
-			newTrusted[hexPk] = true
-		}
-	}
-
-	// Build blacklist set
-	newBlacklist := make(map[string]bool)
-	rows, err := db.Query("SELECT npub FROM trust_blacklist")
-	if err == nil {
-		defer rows.Close()
-		for rows.Next() {
-			var npub string
-			if rows.Scan(&npub) == nil {
-				newBlacklist[npub] = true
-				if hexPk, err := nostr.NpubToHex(npub); err == nil {
-					newBlacklist[hexPk] = true
-				}
-			}
-		}
-	}
-
-	idx.trustedSet = newTrusted
-	idx.blacklistSet = newBlacklist
+	idx.uploaderPolicy = policy
 	idx.cacheExpiry = time.Now().Add(60 * time.Second)
 
-	log.Debug().Int("trusted", len(trustedUploaders)).Int("blacklisted", len(newBlacklist)/2).Msg("Trust cache refreshed")
+	log.Debug().Int("trusted", len(policy.Trusted)).Int("blacklisted", len(policy.Blacklisted)).Msg("Trust cache refreshed")
 }
 
-// isBlacklisted checks if a pubkey is blacklisted (uses cache)
-func (idx *Indexer) isBlacklisted(pubkey string) bool {
+// isBlacklisted checks if a blacklist entry covers events of a pubkey created
+// at createdAt (uses cache)
+func (idx *Indexer) isBlacklisted(pubkey string, createdAt int64) bool {
 	idx.refreshTrustCache()
 	idx.cacheMu.RLock()
 	defer idx.cacheMu.RUnlock()
-	return idx.blacklistSet[pubkey]
+	return idx.uploaderPolicy != nil && idx.uploaderPolicy.Blocks(pubkey, createdAt)
 }
 
-// isTrusted checks if a pubkey is trusted based on whitelist and trust depth (uses cache)
-func (idx *Indexer) isTrusted(pubkey string) bool {
+// isTrusted checks if events of a pubkey created at createdAt are trusted based
+// on whitelist and trust depth (uses cache)
+func (idx *Indexer) isTrusted(pubkey string, createdAt int64) bool {
 	idx.refreshTrustCache()
 	idx.cacheMu.RLock()
 	defer idx.cacheMu.RUnlock()
-
-	if len(idx.trustedSet) == 0 {
-		return false
-	}
-
-	return idx.trustedSet[pubkey]
+	return idx.uploaderPolicy != nil && idx.uploaderPolicy.Allows(pubkey, createdAt)
 }
 
 // matchesTagFilter checks if a torrent matches the configured tag filter
@@ -372,6 +442,27 @@ func (idx *Indexer) runBackgroundTasks() {
 	enrichTicker := time.NewTicker(10 * time.Minute)
 	defer enrichTicker.Stop()
 
+	// Decision aggregation ticker
+	aggregateTicker := time.NewTicker(1 * time.Minute)
+	defer aggregateTicker.Stop()
+
+	// Trusted uploader set ticker
+	authorsTicker := time.NewTicker(1 * time.Minute)
+	defer authorsTicker.Stop()
+
+	// Follow graph crawl ticker
+	crawlTicker := time.NewTicker(crawlInterval())
+	defer crawlTicker.Stop()
+	go idx.crawlTrustGraph()
+
+	// Comment subscription ticker, adding newly indexed torrents
+	commentsTicker := time.NewTicker(30 * time.Minute)
+	defer commentsTicker.Stop()
+
+	// Subscribed list resync ticker, catching updates missed by the live subscription
+	listsTicker := time.NewTicker(1 * time.Hour)
+	defer listsTicker.Stop()
+
 	for {
 		select {
 		case <-idx.ctx.Done():
@@ -388,12 +479,34 @@ func (idx *Indexer) runBackgroundTasks() {
 				Int64("processed", stats.TorrentsProcessed).
 				Int64("added", stats.TorrentsAdded).
 				Int64("duplicate", stats.TorrentsDuplicate).
+				Int64("rejected", stats.TorrentsRejected).
 				Int("relays", idx.relayManager.ConnectedCount()).
 				Msg("Indexer stats")
 
 		case <-enrichTicker.C:
 			// Enrich pending torrents
 			go idx.enrichPendingTorrents()
+
+		case <-aggregateTicker.C:
+			// Aggregate curator decisions received since the last run
+			if remoteCurationEnabled() {
+				idx.aggregatePendingDecisions()
+			}
+
+		case <-authorsTicker.C:
+			// Pick up follows synced since the last check
+			idx.RefreshTrustedAuthors()
+			go idx.publishTrustLists()
+
+		case <-crawlTicker.C:
+			// Refresh the follow graph from current contact lists
+			go idx.crawlTrustGraph()
+
+		case <-commentsTicker.C:
+			go idx.refreshComments()
+
+		case <-listsTicker.C:
+			go idx.SyncTrustLists()
 		}
 	}
 }
@@ -428,35 +541,26 @@ func (idx *Indexer) FetchHistorical(days int) error {
 	}
 
 	// Get trusted uploaders
-	wot := trust.NewWebOfTrust()
-	trustedUploaders, err := wot.GetTrustedUploaders()
+	trustedPubkeys, err := trustedAuthors()
 	if err != nil {
 		return err
 	}
 
-	if len(trustedUploaders) == 0 {
+	if len(trustedPubkeys) == 0 {
 		log.Warn().Msg("No trusted uploaders configured")
 		return nil
 	}
 
-	// Convert npubs to hex pubkeys
-	var trustedPubkeys []string
-	for _, npub := range trustedUploaders {
-		pubkey, err := nostr.NpubToHex(npub)
-		if err != nil {
-			continue
-		}
-		trustedPubkeys = append(trustedPubkeys, pubkey)
-	}
-
+	var since int64
 	if days == 0 {
 		log.Info().Int("uploaders", len(trustedPubkeys)).Msg("Fetching all historical torrents from trusted uploaders")
 	} else {
+		since = time.Now().AddDate(0, 0, -days).Unix()
 		log.Info().Int("days", days).Int("uploaders", len(trustedPubkeys)).Msg("Fetching historical torrents from trusted uploaders")
 	}
 
-	// Re-subscribe to get fresh data from trusted uploaders
-	return idx.relayManager.SubscribeTrustedTorrents(idx.ctx, trustedPubkeys, func(event *gonostr.Event, relayURL string) {
+	// Refetch the requested window from every relay, regardless of sync cursors
+	return idx.relayManager.FetchTorrentsSince(idx.ctx, trustedPubkeys, since, func(event *gonostr.Event, relayURL string) {
 		idx.processEvent(event, relayURL)
 	})
 }
@@ -475,32 +579,24 @@ func (idx *Indexer) ImportContactList(npub string) error {
 		return err
 	}
 
-	// Parse contacts
-	contacts := nostr.ParseContactList(event)
-	if len(contacts) == 0 {
-		return nil
+	// Store follows as a trust root's contact list, kept when crawls no longer reach it
+	list := contactList{
+		Follower:  pubkey,
+		Root:      pubkey,
+		Depth:     1,
+		Source:    followSourceImport,
+		EventID:   event.ID,
+		CreatedAt: int64(event.CreatedAt),
+		Pubkeys:   parseListPubkeys(event, config.Get().Trust.MaxFollowsPerUser),
 	}
-
-	// Store follows in database
-	db := database.Get()
-	for _, contact := range contacts {
-		contactNpub, err := nostr.HexToNpub(contact)
-		if err != nil {
-			continue
-		}
-
-		_, err = db.Exec(`
-			INSERT INTO trust_follows (follower_npub, followed_npub, depth)
-			VALUES (?, ?, 1)
-			ON CONFLICT(follower_npub, followed_npub) DO NOTHING
-		`, npub, contactNpub)
-		if err != nil {
-			log.Error().Err(err).Msg("Failed to store follow")
-		}
+	if !storeContactList(database.Get(), list) {
+		return errors.New("failed to store contact list")
 	}
 
-	log.Info().Int("contacts", len(contacts)).Str("npub", npub).Msg("Imported contact list")
+	log.Info().Int("contacts", len(list.Pubkeys)).Str("npub", npub).Msg("Imported contact list")
 	database.LogActivity("contacts_imported", npub)
 
+	idx.RefreshTrustedAuthors()
+
 	return nil
 }
@@ -0,0 +1,256 @@
+package indexer
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/gmonarque/lighthouse/internal/config"
+	"github.com/gmonarque/lighthouse/internal/database"
+	"github.com/gmonarque/lighthouse/internal/nostr"
+	"github.com/gmonarque/lighthouse/internal/trust"
+	gonostr "github.com/nbd-wtf/go-nostr"
+	"github.com/rs/zerolog/log"
+)
+
+// SyncTrustLists fetches the newest version of every subscribed NIP-51 list,
+// syncs it into the whitelist or blacklist, and renews the live subscription
+// that keeps the lists up to date
+func (idx *Indexer) SyncTrustLists() {
+	if !idx.IsRunning() {
+		return
+	}
+
+	idx.listsMu.Lock()
+	changed := idx.syncTrustLists()
+	idx.listsMu.Unlock()
+
+	if changed {
+		idx.RefreshTrustedAuthors()
+	}
+}
+
+// syncTrustLists does the work of SyncTrustLists with listsMu held. Returns
+// true if the whitelist or blacklist changed.
+func (idx *Indexer) syncTrustLists() bool {
+	if idx.listsCancel != nil {
+		idx.listsCancel()
+		idx.listsCancel = nil
+	}
+
+	lists, err := trust.NewListSubscriptions().GetAll()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to load trust list subscriptions")
+		return false
+	}
+	filters := trustListFilters(lists)
+	if len(filters) == 0 {
+		return false
+	}
+
+	events, err := idx.relayManager.FetchEvents(idx.ctx, filters)
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to fetch trust lists")
+	}
+
+	// Apply only the newest version of each list
+	newest := make(map[string]*gonostr.Event)
+	for _, event := range events {
+		key := listKey(event)
+		if current, ok := newest[key]; !ok || event.CreatedAt > current.CreatedAt {
+			newest[key] = event
+		}
+	}
+
+	changed := false
+	for _, event := range newest {
+		if idx.applyListEvent(event) {
+			changed = true
+		}
+	}
+
+	// Follow updates from now on
+	ctx, cancel := context.WithCancel(idx.ctx)
+	now := gonostr.Now()
+	for i := range filters {
+		filters[i].Since = &now
+	}
+	if err := idx.relayManager.SubscribeAll(ctx, filters, idx.processListEvent); err != nil {
+		cancel()
+		log.Warn().Err(err).Msg("Failed to subscribe to trust lists")
+		return changed
+	}
+	idx.listsCancel = cancel
+
+	return changed
+}
+
+// processListEvent applies a live update of a subscribed list
+func (idx *Indexer) processListEvent(event *gonostr.Event, relayURL string) {
+	idx.listsMu.Lock()
+	changed := idx.applyListEvent(event)
+	idx.listsMu.Unlock()
+
+	if changed {
+		idx.RefreshTrustedAuthors()
+	}
+}
+
+// applyListEvent syncs a list event into the subscribed list it belongs to.
+// Returns true if the whitelist or blacklist changed. listsMu must be held.
+func (idx *Indexer) applyListEvent(event *gonostr.Event) bool {
+	if _, ok := trust.ListTarget(event.Kind); !ok {
+		return false
+	}
+
+	owner, err := nostr.HexToNpub(event.PubKey)
+	if err != nil {
+		return false
+	}
+
+	lists := trust.NewListSubscriptions()
+	list, err := lists.Find(owner, event.Kind, listDTag(event))
+	if err != nil || list == nil {
+		return false
+	}
+	if list.EventID != "" && int64(event.CreatedAt) <= list.EventCreatedAt {
+		return false
+	}
+
+	if ok, err := event.CheckSignature(); err != nil || !ok {
+		log.Debug().Str("event_id", event.ID).Msg("Skipping list with invalid signature")
+		return false
+	}
+
+	var npubs []string
+	for _, pk := range parseListPubkeys(event, len(event.Tags)) {
+		if npub, err := nostr.HexToNpub(pk); err == nil {
+			npubs = append(npubs, npub)
+		}
+	}
+
+	result, err := lists.Apply(list, event.ID, int64(event.CreatedAt), npubs)
+	if err != nil {
+		log.Error().Err(err).Int64("list_id", list.ID).Msg("Failed to sync trust list")
+		return false
+	}
+
+	log.Info().
+		Str("owner", owner).
+		Str("target", list.Target).
+		Int("entries", len(npubs)).
+		Int("added", len(result.Added)).
+		Int("removed", len(result.Removed)).
+		Msg("Trust list synced")
+
+	return len(result.Added) > 0 || len(result.Removed) > 0
+}
+
+// trustListFilters returns filters for the events of the subscribed lists
+func trustListFilters(lists []trust.ListSubscription) []gonostr.Filter {
+	var kinds []int
+	owners := make(map[int][]string)
+	dTags := make(map[int][]string)
+	for _, list := range lists {
+		pubkey, err := nostr.NpubToHex(list.OwnerNpub)
+		if err != nil {
+			continue
+		}
+		if _, ok := owners[list.Kind]; !ok {
+			kinds = append(kinds, list.Kind)
+		}
+		owners[list.Kind] = append(owners[list.Kind], pubkey)
+		if list.Kind != nostr.KindMuteList {
+			dTags[list.Kind] = append(dTags[list.Kind], list.DTag)
+		}
+	}
+
+	slices.Sort(kinds)
+	var filters []gonostr.Filter
+	for _, kind := range kinds {
+		filter := gonostr.Filter{
+			Kinds:   []int{kind},
+			Authors: owners[kind],
+		}
+		if len(dTags[kind]) > 0 {
+			filter.Tags = gonostr.TagMap{"d": dTags[kind]}
+		}
+		filters = append(filters, filter)
+	}
+	return filters
+}
+
+// listDTag returns the d tag identifying a list; mute lists have none
+func listDTag(event *gonostr.Event) string {
+	if event.Kind == nostr.KindMuteList {
+		return ""
+	}
+	return event.Tags.GetD()
+}
+
+// listKey identifies the list an event is a version of
+func listKey(event *gonostr.Event) string {
+	return fmt.Sprintf("%d:%s:%s", event.Kind, event.PubKey, listDTag(event))
+}
+
+// publishTrustLists publishes the whitelist and blacklist as NIP-51 lists
+// signed with the node identity, if enabled and they changed since they were
+// last published
+func (idx *Indexer) publishTrustLists() {
+	cfg := config.Get()
+	if !cfg.Trust.PublishLists || cfg.Nostr.Identity.Nsec == "" {
+		return
+	}
+
+	idx.publishMu.Lock()
+	defer idx.publishMu.Unlock()
+
+	events, err := trust.PublishedLists()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to build trust lists for publishing")
+		return
+	}
+
+	if idx.published == nil {
+		idx.published = make(map[int]string)
+	}
+
+	for _, event := range events {
+		digest := listDigest(cfg.Nostr.Identity.Npub, event)
+		if idx.published[event.Kind] == digest {
+			continue
+		}
+
+		// A replaceable event only replaces versions with an older timestamp
+		if event.CreatedAt <= idx.publishedAt {
+			event.CreatedAt = idx.publishedAt + 1
+		}
+		if err := nostr.SignEvent(event, cfg.Nostr.Identity.Nsec); err != nil {
+			log.Error().Err(err).Msg("Failed to sign trust list")
+			return
+		}
+
+		if err := idx.relayManager.PublishToAll(idx.ctx, event); err != nil {
+			// Retried on the next check
+			log.Warn().Err(err).Int("kind", event.Kind).Msg("Failed to publish trust list")
+			continue
+		}
+
+		idx.published[event.Kind] = digest
+		idx.publishedAt = event.CreatedAt
+
+		entries := len(event.Tags.GetAll([]string{"p"}))
+		log.Info().Str("event_id", event.ID).Int("kind", event.Kind).Int("entries", entries).Msg("Trust list published")
+		database.LogActivity("trust_list_published", fmt.Sprintf("kind %d, %d entries", event.Kind, entries))
+	}
+}
+
+// listDigest identifies the content of a list as published by an identity
+func listDigest(npub string, event *gonostr.Event) string {
+	tags, _ := json.Marshal(event.Tags)
+	sum := sha256.Sum256(append([]byte(npub), tags...))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,36 @@
+package indexer
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/gmonarque/lighthouse/internal/nostr"
+	"github.com/gmonarque/lighthouse/internal/trust"
+)
+
+func TestTrustListFilters(t *testing.T) {
+	owner, _ := nostr.HexToNpub(testPubkey(1))
+	other, _ := nostr.HexToNpub(testPubkey(2))
+
+	filters := trustListFilters([]trust.ListSubscription{
+		{OwnerNpub: owner, Kind: nostr.KindMuteList},
+		{OwnerNpub: owner, Kind: nostr.KindFollowSet, DTag: "friends"},
+		{OwnerNpub: other, Kind: nostr.KindFollowSet, DTag: "uploaders"},
+		{OwnerNpub: "invalid", Kind: nostr.KindMuteList},
+	})
+
+	if len(filters) != 2 {
+		t.Fatalf("got %d filters, want 2", len(filters))
+	}
+	if !slices.Equal(filters[0].Kinds, []int{nostr.KindMuteList}) || !slices.Equal(filters[0].Authors, []string{testPubkey(1)}) {
+		t.Errorf("mute list filter = %v", filters[0])
+	}
+	if !slices.Equal(filters[1].Authors, []string{testPubkey(1), testPubkey(2)}) ||
+		!slices.Equal(filters[1].Tags["d"], []string{"friends", "uploaders"}) {
+		t.Errorf("follow set filter = %v", filters[1])
+	}
+
+	if filters := trustListFilters(nil); len(filters) != 0 {
+		t.Errorf("got %d filters for no lists, want 0", len(filters))
+	}
+}
@@ -0,0 +1,307 @@
+package indexer
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gmonarque/lighthouse/internal/nostr"
+	"github.com/gmonarque/lighthouse/internal/trust"
+	"github.com/rs/zerolog/log"
+)
+
+// uploadMetadata is the metadata published by one upload of a torrent
+type uploadMetadata struct {
+	Pubkey      string
+	Weight      int
+	Name        string
+	Size        int64
+	Category    int
+	Description string
+	Trackers    []string
+	Files       []nostr.TorrentFile
+}
+
+// mergedMetadata is the canonical torrent metadata built from all uploads
+type mergedMetadata struct {
+	Name        string
+	Size        int64
+	Category    int
+	Description string
+	Trackers    []string
+	Files       []nostr.TorrentFile
+	Conflict    bool
+}
+
+// mergeUploads rebuilds the canonical torrent row from the metadata of all its uploads
+func mergeUploads(db *sql.DB, torrentID int64) {
+	var infoHash, magnetURI string
+	if err := db.QueryRow("SELECT info_hash, magnet_uri FROM torrents WHERE id = ?", torrentID).Scan(&infoHash, &magnetURI); err != nil {
+		return
+	}
+
+	uploads, err := loadUploadMetadata(db, torrentID)
+	if err != nil {
+		log.Error().Err(err).Int64("torrent_id", torrentID).Msg("Failed to load upload metadata")
+		return
+	}
+
+	// Uploads recorded before metadata was stored have nothing to merge
+	if len(uploads) == 0 {
+		return
+	}
+
+	merged := mergeMetadata(uploads)
+
+	if len(merged.Trackers) > 0 {
+		magnetURI = nostr.BuildMagnetURI(infoHash, merged.Name, merged.Size, merged.Trackers)
+	}
+
+	ep := ParseEpisodeInfo(merged.Name)
+
+	_, err = db.Exec(`
+		UPDATE torrents SET
+			name = COALESCE(NULLIF(?, ''), name),
+			size = CASE WHEN ? > 0 THEN ? ELSE size END,
+			category = CASE WHEN ? > 0 THEN ? ELSE category END,
+			files = COALESCE(?, files),
+			description = ?,
+			trackers = ?,
+			magnet_uri = ?,
+			metadata_conflict = ?,
+			season = ?, season_end = ?, episode = ?, episode_end = ?, air_date = ?,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?
+	`, merged.Name, merged.Size, merged.Size, merged.Category, merged.Category, marshalJSON(merged.Files),
+		nullString(merged.Description), marshalJSON(merged.Trackers), magnetURI, merged.Conflict,
+		nullInt(ep.Season), nullInt(ep.SeasonEnd), nullInt(ep.Episode), nullInt(ep.EpisodeEnd), nullString(ep.AirDate),
+		torrentID)
+	if err != nil {
+		log.Error().Err(err).Int64("torrent_id", torrentID).Msg("Failed to merge upload metadata")
+		return
+	}
+
+	if merged.Conflict {
+		log.Debug().Int64("torrent_id", torrentID).Msg("Uploaders disagree on torrent metadata")
+	}
+}
+
+// loadUploadMetadata loads the stored metadata of a torrent's uploads,
+// weighting each by the uploader's trust score
+func loadUploadMetadata(db *sql.DB, torrentID int64) ([]uploadMetadata, error) {
+	rows, err := db.Query(`
+		SELECT uploader_npub, name, size, category, description, trackers, files
+		FROM torrent_uploads
+		WHERE torrent_id = ? AND name IS NOT NULL
+		ORDER BY id ASC
+	`, torrentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var uploads []uploadMetadata
+	for rows.Next() {
+		var u uploadMetadata
+		var size, category sql.NullInt64
+		var description, trackers, files sql.NullString
+		if err := rows.Scan(&u.Pubkey, &u.Name, &size, &category, &description, &trackers, &files); err != nil {
+			continue
+		}
+		u.Size = size.Int64
+		u.Category = int(category.Int64)
+		u.Description = description.String
+		if trackers.Valid {
+			json.Unmarshal([]byte(trackers.String), &u.Trackers)
+		}
+		if files.Valid {
+			json.Unmarshal([]byte(files.String), &u.Files)
+		}
+		uploads = append(uploads, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	wot := trust.NewWebOfTrust()
+	for i := range uploads {
+		uploads[i].Weight = uploaderWeight(wot, uploads[i].Pubkey)
+	}
+
+	return uploads, nil
+}
+
+// uploaderWeight returns the vote weight of an uploader. Every uploader gets
+// at least one vote, blacklisted uploaders get none.
+func uploaderWeight(wot *trust.WebOfTrust, pubkey string) int {
+	npub, err := nostr.HexToNpub(pubkey)
+	if err != nil {
+		return 1
+	}
+
+	score := wot.GetTrustScore(npub)
+	if score < 0 {
+		return 0
+	}
+	return score + 1
+}
+
+// mergeMetadata builds canonical metadata from all uploads of a torrent.
+// Name, size and category are chosen by trust-weighted vote, trackers are merged and the
+// most complete file list wins. Conflict is set if uploaders disagree.
+func mergeMetadata(uploads []uploadMetadata) mergedMetadata {
+	var merged mergedMetadata
+
+	// Name: weighted vote over normalized names, represented by the heaviest upload
+	nameVotes := make(map[string]int)
+	nameRepr := make(map[string]uploadMetadata)
+	var nameOrder []string
+	for _, u := range uploads {
+		if u.Name == "" {
+			continue
+		}
+		key := normalizeReleaseName(u.Name)
+		if _, ok := nameRepr[key]; !ok {
+			nameOrder = append(nameOrder, key)
+			nameRepr[key] = u
+		} else if u.Weight > nameRepr[key].Weight {
+			nameRepr[key] = u
+		}
+		nameVotes[key] += u.Weight
+	}
+	if key, ok := heaviest(nameOrder, nameVotes); ok {
+		merged.Name = nameRepr[key].Name
+	}
+
+	// Size: weighted vote over stated sizes
+	sizeVotes := make(map[int64]int)
+	var sizeOrder []int64
+	for _, u := range uploads {
+		if u.Size <= 0 {
+			continue
+		}
+		if _, ok := sizeVotes[u.Size]; !ok {
+			sizeOrder = append(sizeOrder, u.Size)
+		}
+		sizeVotes[u.Size] += u.Weight
+	}
+	if size, ok := heaviest(sizeOrder, sizeVotes); ok {
+		merged.Size = size
+	}
+
+	// Category: weighted vote, disagreement alone is not a conflict
+	categoryVotes := make(map[int]int)
+	var categoryOrder []int
+	for _, u := range uploads {
+		if u.Category <= 0 {
+			continue
+		}
+		if _, ok := categoryVotes[u.Category]; !ok {
+			categoryOrder = append(categoryOrder, u.Category)
+		}
+		categoryVotes[u.Category] += u.Weight
+	}
+	if category, ok := heaviest(categoryOrder, categoryVotes); ok {
+		merged.Category = category
+	}
+
+	// Description: from the most trusted uploader that provided one
+	descWeight := -1
+	for _, u := range uploads {
+		if u.Description != "" && u.Weight > descWeight {
+			merged.Description = u.Description
+			descWeight = u.Weight
+		}
+	}
+
+	// Trackers: union in order of appearance
+	seenTrackers := make(map[string]bool)
+	for _, u := range uploads {
+		for _, tr := range u.Trackers {
+			if tr != "" && !seenTrackers[tr] {
+				seenTrackers[tr] = true
+				merged.Trackers = append(merged.Trackers, tr)
+			}
+		}
+	}
+
+	// Files: the most complete list, ties broken by total size then trust
+	fileLists := make(map[string]bool)
+	var best *uploadMetadata
+	for i := range uploads {
+		u := &uploads[i]
+		if len(u.Files) == 0 {
+			continue
+		}
+		fileLists[fileListSignature(u.Files)] = true
+		if best == nil || betterFileList(u, best) {
+			best = u
+		}
+	}
+	if best != nil {
+		merged.Files = best.Files
+	}
+
+	merged.Conflict = len(nameVotes) > 1 || len(sizeVotes) > 1 || len(fileLists) > 1
+
+	return merged
+}
+
+// heaviest returns the key with the most votes, ties going to the first seen
+func heaviest[K comparable](order []K, votes map[K]int) (K, bool) {
+	var best K
+	for i, key := range order {
+		if i == 0 || votes[key] > votes[best] {
+			best = key
+		}
+	}
+	return best, len(order) > 0
+}
+
+// betterFileList reports whether a's file list is more complete than b's
+func betterFileList(a, b *uploadMetadata) bool {
+	if len(a.Files) != len(b.Files) {
+		return len(a.Files) > len(b.Files)
+	}
+	aSize, bSize := totalFileSize(a.Files), totalFileSize(b.Files)
+	if aSize != bSize {
+		return aSize > bSize
+	}
+	return a.Weight > b.Weight
+}
+
+// totalFileSize sums the sizes of a file list
+func totalFileSize(files []nostr.TorrentFile) int64 {
+	var total int64
+	for _, f := range files {
+		total += f.Size
+	}
+	return total
+}
+
+// fileListSignature returns an order-independent key for a file list
+func fileListSignature(files []nostr.TorrentFile) string {
+	entries := make([]string, len(files))
+	for i, f := range files {
+		entries[i] = fmt.Sprintf("%s:%d", f.Name, f.Size)
+	}
+	sort.Strings(entries)
+	return strings.Join(entries, "\n")
+}
+
+// normalizeReleaseName folds case and separators so cosmetic differences don't count as disagreement
+func normalizeReleaseName(name string) string {
+	replacer := strings.NewReplacer(".", " ", "_", " ", "-", " ")
+	return strings.Join(strings.Fields(strings.ToLower(replacer.Replace(name))), " ")
+}
+
+// marshalJSON encodes a value for a JSON column, using NULL for empty values
+func marshalJSON(v interface{}) sql.NullString {
+	data, err := json.Marshal(v)
+	if err != nil || string(data) == "null" || string(data) == "[]" {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: string(data), Valid: true}
+}
@@ -0,0 +1,61 @@
+package indexer
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gmonarque/lighthouse/internal/nostr"
+)
+
+func TestMergeMetadata(t *testing.T) {
+	files := []nostr.TorrentFile{{Name: "movie.mkv", Size: 1000}}
+	moreFiles := []nostr.TorrentFile{{Name: "movie.mkv", Size: 1000}, {Name: "movie.srt", Size: 10}}
+
+	uploads := []uploadMetadata{
+		{Pubkey: "a", Weight: 1, Name: "Movie.2020.1080p", Size: 1000, Category: 2000, Trackers: []string{"udp://a"}, Files: files},
+		{Pubkey: "b", Weight: 101, Name: "Movie 2020 1080p PROPER", Size: 1010, Category: 2040, Description: "trusted", Trackers: []string{"udp://b", "udp://a"}, Files: moreFiles},
+		{Pubkey: "c", Weight: 1, Name: "movie 2020 1080p", Size: 1000, Description: "untrusted"},
+	}
+
+	merged := mergeMetadata(uploads)
+
+	if merged.Name != "Movie 2020 1080p PROPER" {
+		t.Errorf("Name = %q, want trust-weighted winner", merged.Name)
+	}
+	if merged.Size != 1010 {
+		t.Errorf("Size = %d, want 1010", merged.Size)
+	}
+	if merged.Category != 2040 {
+		t.Errorf("Category = %d, want 2040", merged.Category)
+	}
+	if merged.Description != "trusted" {
+		t.Errorf("Description = %q, want %q", merged.Description, "trusted")
+	}
+	if !reflect.DeepEqual(merged.Trackers, []string{"udp://a", "udp://b"}) {
+		t.Errorf("Trackers = %v, want union", merged.Trackers)
+	}
+	if !reflect.DeepEqual(merged.Files, moreFiles) {
+		t.Errorf("Files = %v, want most complete list", merged.Files)
+	}
+	if !merged.Conflict {
+		t.Error("expected conflict")
+	}
+}
+
+func TestMergeMetadataAgreement(t *testing.T) {
+	files := []nostr.TorrentFile{{Name: "a.iso", Size: 500}}
+	uploads := []uploadMetadata{
+		{Weight: 1, Name: "Distro.24.04", Size: 500, Files: files},
+		{Weight: 1, Name: "distro 24 04", Size: 500, Files: files},
+		{Weight: 1, Name: "Distro_24_04"},
+	}
+
+	merged := mergeMetadata(uploads)
+
+	if merged.Conflict {
+		t.Error("cosmetic name differences should not conflict")
+	}
+	if merged.Name != "Distro.24.04" {
+		t.Errorf("Name = %q, want first seen name", merged.Name)
+	}
+}
@@ -0,0 +1,263 @@
+package indexer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gmonarque/lighthouse/internal/database"
+	"github.com/gmonarque/lighthouse/internal/nostr"
+	gonostr "github.com/nbd-wtf/go-nostr"
+	"github.com/rs/zerolog/log"
+)
+
+// reindexBatchSize is the number of stored events replayed per query
+const reindexBatchSize = 500
+
+// ErrReindexRunning is returned when a reindex is requested while one is in progress
+var ErrReindexRunning = errors.New("reindex already running")
+
+// ReindexProgress reports the state of a reindex job
+type ReindexProgress struct {
+	Running    bool       `json:"running"`
+	Total      int64      `json:"total"`
+	Processed  int64      `json:"processed"`
+	Indexed    int64      `json:"indexed"`
+	Skipped    int64      `json:"skipped"`
+	Removed    int64      `json:"removed"`
+	Failed     int64      `json:"failed"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	Error      string     `json:"error,omitempty"`
+}
+
+// storeRawEvent keeps the signed event of an upload so it can be replayed later
+func storeRawEvent(event *gonostr.Event, relayURL string) {
+	raw, err := json.Marshal(event)
+	if err != nil {
+		log.Error().Err(err).Str("event_id", event.ID).Msg("Failed to encode raw event")
+		return
+	}
+
+	_, err = database.Get().Exec(`
+		INSERT OR IGNORE INTO torrent_events (event_id, pubkey, created_at, raw_json, relay_url)
+		VALUES (?, ?, ?, ?, ?)
+	`, event.ID, event.PubKey, int64(event.CreatedAt), string(raw), relayURL)
+	if err != nil {
+		log.Error().Err(err).Str("event_id", event.ID).Msg("Failed to store raw event")
+	}
+}
+
+// torrentEventStore lists stored torrent events for relay set reconciliation.
+// Events that were never stored, such as unparseable ones, are fetched again
+// on every sync.
+type torrentEventStore struct{}
+
+// EventRefs returns the stored torrent events of the filter's authors
+func (torrentEventStore) EventRefs(filter gonostr.Filter) ([]nostr.EventRef, error) {
+	if len(filter.Kinds) > 0 && !slices.Contains(filter.Kinds, nostr.KindTorrent) {
+		return nil, nil
+	}
+
+	// Deleted events count as held so they are not downloaded again
+	query := `
+		SELECT event_id, created_at FROM torrent_events WHERE %[1]s
+		UNION ALL
+		SELECT event_id, created_at FROM deleted_events WHERE created_at IS NOT NULL AND %[1]s
+	`
+	where := "1 = 1"
+	var args []interface{}
+	if len(filter.Authors) > 0 {
+		where = "pubkey IN (?" + strings.Repeat(",?", len(filter.Authors)-1) + ")"
+		for _, author := range filter.Authors {
+			args = append(args, author)
+		}
+		args = append(args, args...)
+	}
+	query = fmt.Sprintf(query, where)
+
+	rows, err := database.Get().Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var refs []nostr.EventRef
+	for rows.Next() {
+		var ref nostr.EventRef
+		if err := rows.Scan(&ref.ID, &ref.CreatedAt); err != nil {
+			return nil, err
+		}
+		refs = append(refs, ref)
+	}
+	return refs, rows.Err()
+}
+
+// Reindex starts replaying all stored events through the current parsing,
+// trust and curation rules in the background
+func (idx *Indexer) Reindex() error {
+	idx.reindexMu.Lock()
+	defer idx.reindexMu.Unlock()
+
+	if idx.reindex.Running {
+		return ErrReindexRunning
+	}
+
+	now := time.Now()
+	idx.reindex = ReindexProgress{Running: true, StartedAt: &now}
+
+	go idx.runReindex()
+
+	return nil
+}
+
+// GetReindexProgress returns the progress of the current or last reindex
+func (idx *Indexer) GetReindexProgress() ReindexProgress {
+	idx.reindexMu.RLock()
+	defer idx.reindexMu.RUnlock()
+	return idx.reindex
+}
+
+// runReindex replays stored events in batches and records progress
+func (idx *Indexer) runReindex() {
+	db := database.Get()
+
+	ctx := idx.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	// Apply the current trust lists rather than a cached copy
+	idx.cacheMu.Lock()
+	idx.cacheExpiry = time.Time{}
+	idx.cacheMu.Unlock()
+
+	var total int64
+	if err := db.QueryRow("SELECT COUNT(*) FROM torrent_events").Scan(&total); err != nil {
+		idx.finishReindex(err)
+		return
+	}
+
+	idx.reindexMu.Lock()
+	idx.reindex.Total = total
+	idx.reindexMu.Unlock()
+
+	log.Info().Int64("events", total).Msg("Starting reindex")
+
+	var lastID int64
+	for {
+		// Stop with the indexer
+		if err := ctx.Err(); err != nil {
+			idx.finishReindex(err)
+			return
+		}
+
+		rows, err := db.Query(`
+			SELECT id, raw_json, COALESCE(relay_url, '') FROM torrent_events
+			WHERE id > ? ORDER BY id LIMIT ?
+		`, lastID, reindexBatchSize)
+		if err != nil {
+			idx.finishReindex(err)
+			return
+		}
+
+		type storedEvent struct {
+			raw      string
+			relayURL string
+		}
+		var batch []storedEvent
+		for rows.Next() {
+			var e storedEvent
+			if err := rows.Scan(&lastID, &e.raw, &e.relayURL); err != nil {
+				continue
+			}
+			batch = append(batch, e)
+		}
+		rows.Close()
+
+		if len(batch) == 0 {
+			break
+		}
+
+		var indexed, skipped, failed int64
+		for _, e := range batch {
+			var event gonostr.Event
+			if err := json.Unmarshal([]byte(e.raw), &event); err != nil {
+				failed++
+				continue
+			}
+			if idx.ingestEvent(&event, e.relayURL, true) {
+				indexed++
+			} else {
+				skipped++
+			}
+		}
+
+		idx.reindexMu.Lock()
+		idx.reindex.Processed += int64(len(batch))
+		idx.reindex.Indexed += indexed
+		idx.reindex.Skipped += skipped
+		idx.reindex.Failed += failed
+		idx.reindexMu.Unlock()
+	}
+
+	idx.finishReindex(nil)
+}
+
+// unlinkReplayed removes the upload of a replayed event that no longer passes
+// the trust, tag filter or curation rules. Its torrent is deleted if no other
+// upload is left, otherwise it is rescored and re-merged. The stored event is
+// kept so a later reindex can restore the upload.
+func (idx *Indexer) unlinkReplayed(event *gonostr.Event) {
+	db := database.Get()
+
+	var torrentID int64
+	err := db.QueryRow("SELECT torrent_id FROM torrent_uploads WHERE nostr_event_id = ?", event.ID).Scan(&torrentID)
+	if err != nil {
+		return
+	}
+
+	if _, err := db.Exec("DELETE FROM torrent_uploads WHERE nostr_event_id = ?", event.ID); err != nil {
+		log.Error().Err(err).Str("event_id", event.ID).Msg("Failed to remove upload")
+		return
+	}
+	refreshAfterDeletion(db, torrentID, event.PubKey)
+
+	idx.reindexMu.Lock()
+	idx.reindex.Removed++
+	idx.reindexMu.Unlock()
+}
+
+// finishReindex marks the reindex job as finished
+func (idx *Indexer) finishReindex(err error) {
+	now := time.Now()
+
+	idx.reindexMu.Lock()
+	idx.reindex.Running = false
+	idx.reindex.FinishedAt = &now
+	if err != nil {
+		idx.reindex.Error = err.Error()
+	}
+	progress := idx.reindex
+	idx.reindexMu.Unlock()
+
+	if err != nil {
+		log.Error().Err(err).Msg("Reindex failed")
+		return
+	}
+
+	database.LogActivity("reindex_completed", fmt.Sprintf("%d events, %d indexed, %d skipped, %d removed, %d failed",
+		progress.Processed, progress.Indexed, progress.Skipped, progress.Removed, progress.Failed))
+
+	log.Info().
+		Int64("processed", progress.Processed).
+		Int64("indexed", progress.Indexed).
+		Int64("skipped", progress.Skipped).
+		Int64("removed", progress.Removed).
+		Int64("failed", progress.Failed).
+		Msg("Reindex completed")
+}
@@ -0,0 +1,70 @@
+package indexer
+
+import (
+	"database/sql"
+	"encoding/json"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// maxTagLength is the longest content tag stored
+const maxTagLength = 64
+
+// storeTags merges an upload's content tags into the torrent's tag set
+func storeTags(db *sql.DB, torrentID int64, tags []string) {
+	for _, tag := range normalizeTags(tags) {
+		_, err := db.Exec(`
+			INSERT INTO torrent_tags (torrent_id, tag) VALUES (?, ?)
+			ON CONFLICT(torrent_id, tag) DO UPDATE SET upload_count = upload_count + 1
+		`, torrentID, tag)
+		if err != nil {
+			log.Error().Err(err).Int64("torrent_id", torrentID).Str("tag", tag).Msg("Failed to store tag")
+		}
+	}
+}
+
+// rebuildTags recomputes a torrent's tag set from the tags stored with its uploads
+func rebuildTags(db *sql.DB, torrentID int64) {
+	if _, err := db.Exec("DELETE FROM torrent_tags WHERE torrent_id = ?", torrentID); err != nil {
+		log.Error().Err(err).Int64("torrent_id", torrentID).Msg("Failed to clear tags")
+		return
+	}
+
+	rows, err := db.Query("SELECT tags FROM torrent_uploads WHERE torrent_id = ? AND tags IS NOT NULL", torrentID)
+	if err != nil {
+		log.Error().Err(err).Int64("torrent_id", torrentID).Msg("Failed to load upload tags")
+		return
+	}
+
+	var uploads [][]string
+	for rows.Next() {
+		var tagsJSON string
+		var tags []string
+		if err := rows.Scan(&tagsJSON); err == nil && json.Unmarshal([]byte(tagsJSON), &tags) == nil {
+			uploads = append(uploads, tags)
+		}
+	}
+	rows.Close()
+
+	for _, tags := range uploads {
+		storeTags(db, torrentID, tags)
+	}
+}
+
+// normalizeTags lowercases and trims tags, dropping empty, oversized and duplicate values
+func normalizeTags(tags []string) []string {
+	seen := make(map[string]bool, len(tags))
+	result := make([]string, 0, len(tags))
+
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || len(tag) > maxTagLength || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		result = append(result, tag)
+	}
+
+	return result
+}
@@ -0,0 +1,28 @@
+package indexer
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    []string
+		expected []string
+	}{
+		{"empty", nil, []string{}},
+		{"lowercase and trim", []string{" 4K ", "HDR"}, []string{"4k", "hdr"}},
+		{"duplicates", []string{"x265", "X265", "movie"}, []string{"x265", "movie"}},
+		{"drops empty and oversized", []string{"", "  ", strings.Repeat("a", maxTagLength+1), "en"}, []string{"en"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normalizeTags(tt.input); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("normalizeTags(%v) = %v, want %v", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
@@ -19,6 +19,10 @@ type TrustWhitelistEntry struct {
 	Alias   string    `json:"alias,omitempty"`
 	Notes   string    `json:"notes,omitempty"`
 	AddedAt time.Time `json:"added_at"`
+
+	// Time bounds on the created_at of events the entry applies to (unix seconds)
+	EffectiveFrom  int64 `json:"effective_from,omitempty"`
+	EffectiveUntil int64 `json:"effective_until,omitempty"`
 }
 
 // TrustBlacklistEntry represents a blacklisted user
@@ -27,6 +31,10 @@ type TrustBlacklistEntry struct {
 	Npub    string    `json:"npub"`
 	Reason  string    `json:"reason,omitempty"`
 	AddedAt time.Time `json:"added_at"`
+
+	// Time bounds on the created_at of events the entry applies to (unix seconds)
+	EffectiveFrom  int64 `json:"effective_from,omitempty"`
+	EffectiveUntil int64 `json:"effective_until,omitempty"`
 }
 
 // TrustFollow represents a follow relationship
@@ -15,12 +15,23 @@ type Client struct {
 	url       string
 	connected bool
 	mu        sync.RWMutex
+
+	// Teardown of the relay connection, see Disconnect
+	conn     *nostr.Connection
+	cancel   context.CancelFunc
+	watchSub *nostr.Subscription
+
+	// NIP-77 reconciliation sessions by subscription ID
+	negSessions    map[string]chan negentropyMessage
+	negUnsupported bool
+	negMu          sync.Mutex
 }
 
 // NewClient creates a new Nostr client
 func NewClient(url string) *Client {
 	return &Client{
-		url: url,
+		url:         url,
+		negSessions: make(map[string]chan negentropyMessage),
 	}
 }
 
@@ -33,12 +44,18 @@ func (c *Client) Connect(ctx context.Context) error {
 		return nil
 	}
 
-	relay, err := nostr.RelayConnect(ctx, c.url)
-	if err != nil {
+	relayCtx, cancel := context.WithCancel(context.Background())
+	relay := nostr.NewRelay(relayCtx, c.url, nostr.WithCustomHandler(c.handleNegentropyMessage))
+	if err := relay.Connect(ctx); err != nil {
+		cancel()
 		return err
 	}
 
 	c.relay = relay
+	c.conn = relay.Connection
+	c.cancel = cancel
+	// Never fired; go-nostr unsubscribes it once it has dropped the connection
+	c.watchSub = relay.PrepareSubscription(context.Background(), nil)
 	c.connected = true
 
 	log.Info().Str("url", c.url).Msg("Connected to relay")
@@ -51,7 +68,14 @@ func (c *Client) Disconnect() {
 	defer c.mu.Unlock()
 
 	if c.relay != nil {
+		// go-nostr clears the relay's connection from a goroutine when its
+		// context ends, while Close reads it. End the context and wait for
+		// that goroutine to unsubscribe watchSub before closing, so the two
+		// never race.
+		c.cancel()
+		<-c.watchSub.Context.Done()
 		c.relay.Close()
+		c.conn.Close()
 		c.connected = false
 		log.Info().Str("url", c.url).Msg("Disconnected from relay")
 	}
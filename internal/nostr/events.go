@@ -1,6 +1,7 @@
 package nostr
 
 import (
+	"net/url"
 	"regexp"
 	"strconv"
 	"strings"
@@ -21,8 +22,16 @@ type TorrentEvent struct {
 	Files       []TorrentFile
 	Trackers    []string          // Tracker URLs from the event
 	Tags        map[string]string
-	ContentTags []string // t tags for content classification (movie, tv, 4k, hd, etc.)
-	Description string   // Event content if not a magnet URI, or from summary tag
+	ContentTags []string     // t tags for content classification (movie, tv, 4k, hd, etc.)
+	Description string       // Event content if not a magnet URI, or from summary tag
+	ExternalIDs []ExternalID // NIP-35 i tags (imdb, tmdb, tvdb, ...)
+}
+
+// ExternalID is a reference to an external database from a NIP-35 "i" tag
+type ExternalID struct {
+	Type      string // imdb, tmdb, tvdb, mal, anilist, ...
+	MediaType string // movie or tv, for TMDB IDs
+	Value     string
 }
 
 // TorrentFile represents a file in a torrent
@@ -45,6 +54,7 @@ func ParseTorrentEvent(event *nostr.Event) (*TorrentEvent, error) {
 		ContentTags: make([]string, 0),
 		Files:       make([]TorrentFile, 0),
 		Trackers:    make([]string, 0),
+		ExternalIDs: make([]ExternalID, 0),
 	}
 
 	// Check if content is a magnet URI or description
@@ -92,6 +102,11 @@ func ParseTorrentEvent(event *nostr.Event) (*TorrentEvent, error) {
 			if value != "" {
 				te.Trackers = append(te.Trackers, value)
 			}
+		case "i":
+			// NIP-35 external ID tag: ["i", "imdb:tt1234567"]
+			if id, ok := ParseExternalID(value); ok {
+				te.ExternalIDs = append(te.ExternalIDs, id)
+			}
 		case "summary":
 			// Some events use summary tag for description
 			if te.Description == "" {
@@ -123,12 +138,46 @@ func ParseTorrentEvent(event *nostr.Event) (*TorrentEvent, error) {
 	// Generate magnet URI if we have info hash but no magnet URI
 	// Per https://en.wikipedia.org/wiki/Magnet_URI_scheme
 	if te.MagnetURI == "" && te.InfoHash != "" {
-		te.MagnetURI = buildMagnetURI(te.InfoHash, te.Name, te.Size, te.Trackers)
+		te.MagnetURI = BuildMagnetURI(te.InfoHash, te.Name, te.Size, te.Trackers)
 	}
 
 	return te, nil
 }
 
+// ParseExternalID parses a NIP-35 "i" tag value such as "imdb:tt1234567",
+// "tmdb:movie:693134" or "tvdb:290434"
+func ParseExternalID(value string) (ExternalID, bool) {
+	parts := strings.SplitN(strings.TrimSpace(value), ":", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return ExternalID{}, false
+	}
+
+	id := ExternalID{Type: strings.ToLower(parts[0]), Value: parts[1]}
+
+	switch id.Type {
+	case "imdb":
+		id.Value = strings.ToLower(id.Value)
+		if !strings.HasPrefix(id.Value, "tt") {
+			id.Value = "tt" + id.Value
+		}
+	case "tmdb":
+		// TMDB IDs are only unique per media type: tmdb:movie:123, tmdb:tv:456
+		if mediaType, rest, found := strings.Cut(id.Value, ":"); found {
+			id.MediaType = strings.ToLower(mediaType)
+			id.Value = rest
+		}
+		if _, err := strconv.Atoi(id.Value); err != nil {
+			return ExternalID{}, false
+		}
+	case "tvdb":
+		if _, err := strconv.Atoi(id.Value); err != nil {
+			return ExternalID{}, false
+		}
+	}
+
+	return id, true
+}
+
 // ParseContactList parses a Kind 3 Nostr event (contact list)
 func ParseContactList(event *nostr.Event) []string {
 	if event.Kind != KindContactList {
@@ -145,6 +194,89 @@ func ParseContactList(event *nostr.Event) []string {
 	return contacts
 }
 
+// ParseFollowSet parses the public entries of a Kind 30000 Nostr event (NIP-51 follow set)
+func ParseFollowSet(event *nostr.Event) []string {
+	if event.Kind != KindFollowSet {
+		return nil
+	}
+
+	var follows []string
+	for _, tag := range event.Tags {
+		if len(tag) >= 2 && tag[0] == "p" {
+			follows = append(follows, tag[1])
+		}
+	}
+
+	return follows
+}
+
+// ParseMuteList parses the public entries of a Kind 10000 Nostr event (NIP-51 mute list).
+// Private entries are encrypted in the content and are not read.
+func ParseMuteList(event *nostr.Event) []string {
+	if event.Kind != KindMuteList {
+		return nil
+	}
+
+	var muted []string
+	for _, tag := range event.Tags {
+		if len(tag) >= 2 && tag[0] == "p" {
+			muted = append(muted, tag[1])
+		}
+	}
+
+	return muted
+}
+
+// ParseMuteSet parses the public entries of a Kind 30007 Nostr event (NIP-51 kind mute set)
+func ParseMuteSet(event *nostr.Event) []string {
+	if event.Kind != KindMuteSet {
+		return nil
+	}
+
+	var muted []string
+	for _, tag := range event.Tags {
+		if len(tag) >= 2 && tag[0] == "p" {
+			muted = append(muted, tag[1])
+		}
+	}
+
+	return muted
+}
+
+// ListEntry is a pubkey in a NIP-51 list, with an optional petname and note
+type ListEntry struct {
+	Pubkey  string
+	Petname string
+	Note    string
+}
+
+// CreateListEvent creates a NIP-51 set identified by a d tag. Entries become
+// p tags of the form ["p", pubkey, relay, petname, note], with trailing empty
+// fields left out.
+func CreateListEvent(kind int, dTag, title, description string, entries []ListEntry) *nostr.Event {
+	tags := nostr.Tags{{"d", dTag}}
+	if title != "" {
+		tags = append(tags, nostr.Tag{"title", title})
+	}
+	if description != "" {
+		tags = append(tags, nostr.Tag{"description", description})
+	}
+
+	for _, e := range entries {
+		tag := nostr.Tag{"p", e.Pubkey, "", e.Petname, e.Note}
+		for len(tag) > 2 && tag[len(tag)-1] == "" {
+			tag = tag[:len(tag)-1]
+		}
+		tags = append(tags, tag)
+	}
+
+	return &nostr.Event{
+		Kind:      kind,
+		Tags:      tags,
+		CreatedAt: nostr.Now(),
+	}
+}
+
 // extractInfoHash extracts the info hash from a magnet URI
 func extractInfoHash(magnetURI string) string {
 	// Match btih (BitTorrent Info Hash) in magnet URI
@@ -178,9 +310,9 @@ func extractNameFromMagnet(magnetURI string) string {
 	return ""
 }
 
-// buildMagnetURI constructs a magnet URI from info hash, name, size, and trackers
+// BuildMagnetURI constructs a magnet URI from info hash, name, size, and trackers
 // Per https://en.wikipedia.org/wiki/Magnet_URI_scheme
-func buildMagnetURI(infoHash, name string, size int64, eventTrackers []string) string {
+func BuildMagnetURI(infoHash, name string, size int64, eventTrackers []string) string {
 	// Start with the required xt (exact topic) parameter
 	magnet := "magnet:?xt=urn:btih:" + strings.ToLower(infoHash)
 
@@ -225,6 +357,27 @@ func buildMagnetURI(infoHash, name string, size int64, eventTrackers []string) s
 	return magnet
 }
 
+// MagnetTrackers returns the tracker URLs (tr parameters) of a magnet URI
+func MagnetTrackers(magnetURI string) []string {
+	_, rawQuery, found := strings.Cut(magnetURI, "?")
+	if !found {
+		return nil
+	}
+
+	values, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return nil
+	}
+
+	var trackers []string
+	for _, tr := range values["tr"] {
+		if tr != "" {
+			trackers = append(trackers, tr)
+		}
+	}
+	return trackers
+}
+
 // CreateTorrentEvent creates a new Kind 2003 torrent event
 func CreateTorrentEvent(magnetURI, name, category string, size int64, infoHash string) *nostr.Event {
 	tags := nostr.Tags{
@@ -1,6 +1,7 @@
 package nostr
 
 import (
+	"slices"
 	"testing"
 
 	"github.com/nbd-wtf/go-nostr"
@@ -185,3 +186,94 @@ func TestParseContactList(t *testing.T) {
 		}
 	}
 }
+
+func TestParseTorrentEvent_ExternalIDs(t *testing.T) {
+	event := &nostr.Event{
+		Kind: KindTorrent,
+		Tags: nostr.Tags{
+			{"x", "abc123def456"},
+			{"i", "imdb:tt0111161"},
+			{"i", "tmdb:movie:278"},
+			{"i", "tvdb:not-a-number"},
+			{"i", "mal:12345"},
+		},
+	}
+
+	result, err := ParseTorrentEvent(event)
+	if err != nil {
+		t.Fatalf("ParseTorrentEvent failed: %v", err)
+	}
+
+	expected := []ExternalID{
+		{Type: "imdb", Value: "tt0111161"},
+		{Type: "tmdb", MediaType: "movie", Value: "278"},
+		{Type: "mal", Value: "12345"},
+	}
+
+	if len(result.ExternalIDs) != len(expected) {
+		t.Fatalf("ExternalIDs = %+v, want %+v", result.ExternalIDs, expected)
+	}
+	for i, id := range expected {
+		if result.ExternalIDs[i] != id {
+			t.Errorf("ExternalIDs[%d] = %+v, want %+v", i, result.ExternalIDs[i], id)
+		}
+	}
+}
+
+func TestParseExternalID(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected ExternalID
+		ok       bool
+	}{
+		{"imdb:tt1234567", ExternalID{Type: "imdb", Value: "tt1234567"}, true},
+		{"IMDB:1234567", ExternalID{Type: "imdb", Value: "tt1234567"}, true},
+		{"tmdb:tv:1399", ExternalID{Type: "tmdb", MediaType: "tv", Value: "1399"}, true},
+		{"tmdb:1399", ExternalID{Type: "tmdb", Value: "1399"}, true},
+		{"tvdb:121361", ExternalID{Type: "tvdb", Value: "121361"}, true},
+		{"tmdb:movie:abc", ExternalID{}, false},
+		{"imdb:", ExternalID{}, false},
+		{"nocolon", ExternalID{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			result, ok := ParseExternalID(tt.input)
+			if result != tt.expected || ok != tt.ok {
+				t.Errorf("ParseExternalID(%q) = %+v, %v, want %+v, %v", tt.input, result, ok, tt.expected, tt.ok)
+			}
+		})
+	}
+}
+
+func TestCreateListEvent(t *testing.T) {
+	event := CreateListEvent(KindFollowSet, "uploaders", "Uploaders", "", []ListEntry{
+		{Pubkey: "aa"},
+		{Pubkey: "bb", Petname: "bob"},
+		{Pubkey: "cc", Note: "mirrors releases"},
+	})
+
+	if event.Kind != KindFollowSet || event.Tags.GetD() != "uploaders" {
+		t.Fatalf("kind = %d, d = %q", event.Kind, event.Tags.GetD())
+	}
+
+	want := []nostr.Tag{
+		{"d", "uploaders"},
+		{"title", "Uploaders"},
+		{"p", "aa"},
+		{"p", "bb", "", "bob"},
+		{"p", "cc", "", "", "mirrors releases"},
+	}
+	if len(event.Tags) != len(want) {
+		t.Fatalf("got %d tags, want %d: %v", len(event.Tags), len(want), event.Tags)
+	}
+	for i, tag := range want {
+		if !slices.Equal(event.Tags[i], tag) {
+			t.Errorf("tag %d = %v, want %v", i, event.Tags[i], tag)
+		}
+	}
+
+	if follows := ParseFollowSet(event); !slices.Equal(follows, []string{"aa", "bb", "cc"}) {
+		t.Errorf("ParseFollowSet() = %v", follows)
+	}
+}
@@ -0,0 +1,290 @@
+package nostr
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip77/negentropy"
+	"github.com/nbd-wtf/go-nostr/nip77/negentropy/storage/vector"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// negentropyFrameLimit bounds the size of each NEG-MSG we send
+	negentropyFrameLimit = 60000
+
+	// negentropyTimeout is how long to wait for each reconciliation round
+	negentropyTimeout = 15 * time.Second
+
+	// negentropyAuthorBatch is the number of authors reconciled per session
+	negentropyAuthorBatch = 200
+
+	// negentropyFetchBatch is the number of missing event IDs requested per query
+	negentropyFetchBatch = 100
+)
+
+// ErrNegentropyUnsupported is returned for relays that did not answer NEG-OPEN
+var ErrNegentropyUnsupported = errors.New("relay does not support negentropy")
+
+// EventRef identifies a locally held event for set reconciliation
+type EventRef struct {
+	ID        string
+	CreatedAt int64
+}
+
+// EventStore lists the events held locally that match a filter
+type EventStore interface {
+	EventRefs(filter nostr.Filter) ([]EventRef, error)
+}
+
+// negentropyMessage is a NEG-MSG or NEG-ERR received for a session
+type negentropyMessage struct {
+	Message string
+	Err     string
+}
+
+// SupportsNegentropy returns false once the relay failed to answer a NEG-OPEN
+func (c *Client) SupportsNegentropy() bool {
+	c.negMu.Lock()
+	defer c.negMu.Unlock()
+	return !c.negUnsupported
+}
+
+// handleNegentropyMessage routes NIP-77 replies to their session
+func (c *Client) handleNegentropyMessage(data []byte) {
+	var msg []json.RawMessage
+	if err := json.Unmarshal(data, &msg); err != nil || len(msg) < 3 {
+		return
+	}
+
+	var label, subID, payload string
+	json.Unmarshal(msg[0], &label)
+	json.Unmarshal(msg[1], &subID)
+	json.Unmarshal(msg[2], &payload)
+
+	var m negentropyMessage
+	switch label {
+	case "NEG-MSG":
+		m.Message = payload
+	case "NEG-ERR":
+		m.Err = payload
+	default:
+		return
+	}
+
+	c.negMu.Lock()
+	ch := c.negSessions[subID]
+	c.negMu.Unlock()
+
+	if ch != nil {
+		select {
+		case ch <- m:
+		default:
+		}
+	}
+}
+
+// Reconcile runs NIP-77 set reconciliation for a filter and returns the IDs
+// of events the relay has that are missing from local
+func (c *Client) Reconcile(ctx context.Context, filter nostr.Filter, local []EventRef) ([]string, error) {
+	c.mu.RLock()
+	relay := c.relay
+	c.mu.RUnlock()
+
+	if relay == nil {
+		return nil, ErrNotConnected
+	}
+	if !c.SupportsNegentropy() {
+		return nil, ErrNegentropyUnsupported
+	}
+
+	vec := vector.New()
+	for _, ref := range local {
+		vec.Insert(nostr.Timestamp(ref.CreatedAt), ref.ID)
+	}
+	vec.Seal()
+	neg := negentropy.New(vec, negentropyFrameLimit)
+
+	subID := newNegentropySubID()
+	responses := make(chan negentropyMessage, 1)
+
+	c.negMu.Lock()
+	c.negSessions[subID] = responses
+	c.negMu.Unlock()
+
+	defer func() {
+		c.negMu.Lock()
+		delete(c.negSessions, subID)
+		c.negMu.Unlock()
+	}()
+
+	// Collect the IDs we lack while reconciling; we only download, so haves are discarded
+	var need []string
+	done := make(chan struct{})
+	collected := make(chan struct{})
+	defer close(done)
+	go func() {
+		defer close(collected)
+		haves, haveNots := neg.Haves, neg.HaveNots
+		for haves != nil || haveNots != nil {
+			select {
+			case _, ok := <-haves:
+				if !ok {
+					haves = nil
+				}
+			case id, ok := <-haveNots:
+				if !ok {
+					haveNots = nil
+					continue
+				}
+				need = append(need, id)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	open, err := json.Marshal([]interface{}{"NEG-OPEN", subID, filter, neg.Start()})
+	if err != nil {
+		return nil, err
+	}
+	if err := <-relay.Write(open); err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		closeMsg, _ := json.Marshal([]interface{}{"NEG-CLOSE", subID})
+		<-relay.Write(closeMsg)
+	}()
+
+	for round := 0; ; round++ {
+		var m negentropyMessage
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(negentropyTimeout):
+			if round == 0 {
+				// No answer to NEG-OPEN at all, don't try this relay again
+				c.negMu.Lock()
+				c.negUnsupported = true
+				c.negMu.Unlock()
+				return nil, ErrNegentropyUnsupported
+			}
+			return nil, errors.New("negentropy reconciliation timed out")
+		case m = <-responses:
+		}
+
+		if m.Err != "" {
+			return nil, fmt.Errorf("relay rejected negentropy: %s", m.Err)
+		}
+
+		next, err := neg.Reconcile(m.Message)
+		if err != nil {
+			return nil, fmt.Errorf("failed to reconcile: %w", err)
+		}
+
+		// An empty reply means reconciliation is complete
+		if next == "" {
+			break
+		}
+
+		msg, _ := json.Marshal([]interface{}{"NEG-MSG", subID, next})
+		if err := <-relay.Write(msg); err != nil {
+			return nil, err
+		}
+	}
+
+	<-collected
+	return need, nil
+}
+
+// reconcileTorrents syncs a relay's torrent events from trusted authors using
+// NIP-77, downloading only events missing locally. Returns false if the relay
+// must be synced with paginated queries instead.
+func (rm *RelayManager) reconcileTorrents(ctx context.Context, client *Client, pubkeys []string, handler func(*nostr.Event, string)) bool {
+	rm.mu.RLock()
+	store := rm.eventStore
+	rm.mu.RUnlock()
+
+	if store == nil || !client.SupportsNegentropy() {
+		return false
+	}
+
+	url := client.URL()
+	kinds := []int{KindTorrent}
+	key := syncFilterKey(kinds)
+	state := &SyncState{
+		RelayURL:         url,
+		FilterKey:        key,
+		Kinds:            kinds,
+		AuthorCount:      len(pubkeys),
+		BackfillComplete: true,
+	}
+	track := func(createdAt int64) {
+		if createdAt > state.NewestCreatedAt {
+			state.NewestCreatedAt = createdAt
+		}
+		if state.OldestCreatedAt == 0 || createdAt < state.OldestCreatedAt {
+			state.OldestCreatedAt = createdAt
+		}
+	}
+
+	fetched := 0
+	for start := 0; start < len(pubkeys); start += negentropyAuthorBatch {
+		end := min(start+negentropyAuthorBatch, len(pubkeys))
+		filter := nostr.Filter{Kinds: kinds, Authors: pubkeys[start:end]}
+
+		local, err := store.EventRefs(filter)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to list local events for negentropy")
+			return false
+		}
+
+		need, err := client.Reconcile(ctx, filter, local)
+		if err != nil {
+			log.Info().Err(err).Str("relay", url).Msg("Negentropy sync unavailable, using paginated fetch")
+			return false
+		}
+
+		for _, ref := range local {
+			track(ref.CreatedAt)
+		}
+
+		for i := 0; i < len(need); i += negentropyFetchBatch {
+			ids := need[i:min(i+negentropyFetchBatch, len(need))]
+			events, err := client.QueryEvents(ctx, []nostr.Filter{{IDs: ids}})
+			if err != nil {
+				log.Error().Err(err).Str("relay", url).Msg("Failed to fetch missing events")
+				return false
+			}
+			for _, event := range events {
+				handler(event, url)
+				track(int64(event.CreatedAt))
+			}
+			fetched += len(events)
+		}
+	}
+
+	// Reconciliation covers every author, so the cursor does too
+	if synced, err := loadSyncAuthors(url, key); err == nil {
+		deleteSyncAuthors(url, key, newAuthors(pubkeys, synced))
+	}
+	saveSyncAuthors(url, key, pubkeys)
+	saveSyncState(state)
+
+	log.Info().Str("relay", url).Int("fetched", fetched).Msg("Negentropy sync complete")
+	return true
+}
+
+// newNegentropySubID returns a random subscription ID for a reconciliation session
+func newNegentropySubID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return "neg-" + hex.EncodeToString(b)
+}
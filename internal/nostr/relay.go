@@ -3,6 +3,7 @@ package nostr
 import (
 	"context"
 	"errors"
+	"fmt"
 	"sync"
 	"time"
 
@@ -17,21 +18,31 @@ var (
 	ErrRelayExists  = errors.New("relay already exists")
 )
 
+// contactListBatch is the number of authors per contact or mute list query
+const contactListBatch = 100
+
 // Nostr event kinds
 const (
 	KindMetadata    = 0
 	KindTextNote    = 1
 	KindContactList = 3
+	KindDeletion    = 5     // NIP-09 deletion request
+	KindMuteList    = 10000 // NIP-51 mute list
 	KindRelayList   = 10002 // NIP-65 relay list
 	KindTorrent     = 2003
+	KindComment     = 2004  // Torrent comment
+	KindFollowSet   = 30000 // NIP-51 follow set
+	KindMuteSet     = 30007 // NIP-51 kind mute set
+	KindDecision    = 30175 // Curator verification decision
 )
 
 // RelayManager manages connections to multiple Nostr relays
 type RelayManager struct {
-	clients map[string]*Client
-	mu      sync.RWMutex
-	ctx     context.Context
-	cancel  context.CancelFunc
+	clients    map[string]*Client
+	eventStore EventStore
+	mu         sync.RWMutex
+	ctx        context.Context
+	cancel     context.CancelFunc
 }
 
 // NewRelayManager creates a new relay manager
@@ -50,6 +61,13 @@ func NewRelayManager(relays []config.RelayConfig) *RelayManager {
 	return rm
 }
 
+// SetEventStore sets the local event store used for negentropy reconciliation
+func (rm *RelayManager) SetEventStore(store EventStore) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+	rm.eventStore = store
+}
+
 // Start connects to all configured relays
 func (rm *RelayManager) Start(ctx context.Context) error {
 	rm.mu.Lock()
@@ -180,6 +198,11 @@ func (rm *RelayManager) ConnectedCount() int {
 
 // SubscribeAll subscribes to events on all connected relays
 func (rm *RelayManager) SubscribeAll(ctx context.Context, filters []nostr.Filter, handler func(*nostr.Event, string)) error {
+	return rm.subscribeEach(ctx, func(string) []nostr.Filter { return filters }, handler)
+}
+
+// subscribeEach subscribes on all connected relays with filters built per relay
+func (rm *RelayManager) subscribeEach(ctx context.Context, filtersFor func(url string) []nostr.Filter, handler func(*nostr.Event, string)) error {
 	clients := rm.GetConnectedClients()
 	if len(clients) == 0 {
 		return errors.New("no connected relays")
@@ -188,7 +211,7 @@ func (rm *RelayManager) SubscribeAll(ctx context.Context, filters []nostr.Filter
 	successCount := 0
 	for _, client := range clients {
 		url := client.URL()
-		err := client.Subscribe(ctx, filters, func(event *nostr.Event) {
+		err := client.Subscribe(ctx, filtersFor(url), func(event *nostr.Event) {
 			handler(event, url)
 		})
 		if err != nil {
@@ -216,31 +239,114 @@ func (rm *RelayManager) SubscribeTorrents(ctx context.Context, since time.Time,
 	return rm.SubscribeAll(ctx, filters, handler)
 }
 
-// SubscribeTrustedTorrents subscribes to torrent events from specific authors (trusted uploaders)
-// This is more efficient than fetching all events because relays return ALL events from these authors
+// SubscribeTrustedTorrents subscribes to torrent events from specific authors (trusted uploaders).
+// Each relay is subscribed from its sync cursor; relays without one start from now
+// and are left to FetchAllHistoricalTorrents for their backfill, as are authors
+// the cursor does not cover yet. Live events never move the cursor: a relay may
+// cap the events it replays, so only FetchAllHistoricalTorrents knows what was
+// fetched in full.
 func (rm *RelayManager) SubscribeTrustedTorrents(ctx context.Context, pubkeys []string, handler func(*nostr.Event, string)) error {
 	if len(pubkeys) == 0 {
 		return errors.New("no pubkeys provided")
 	}
 
-	// Query for all historical events from trusted authors (no time limit)
+	kinds := []int{KindTorrent}
+	key := syncFilterKey(kinds)
+	now := nostr.Now()
+
+	log.Info().Int("authors", len(pubkeys)).Msg("Subscribing to torrents from trusted authors")
+
+	return rm.subscribeEach(ctx, func(url string) []nostr.Filter {
+		since := now
+		state, err := loadSyncState(url, key)
+		if err != nil {
+			log.Warn().Err(err).Str("relay", url).Msg("Failed to load sync state")
+		} else if state != nil {
+			since = nostr.Timestamp(state.ResumeSince())
+		}
+		return []nostr.Filter{
+			{
+				Kinds:   kinds,
+				Authors: pubkeys,
+				Since:   &since,
+			},
+		}
+	}, handler)
+}
+
+// commentInfohashBatch is the number of infohashes per #x filter of a comment subscription
+const commentInfohashBatch = 500
+
+// SubscribeComments subscribes to torrent comments written by the given authors
+// or referencing the given infohashes on all connected relays
+func (rm *RelayManager) SubscribeComments(ctx context.Context, pubkeys, infohashes []string, handler func(*nostr.Event, string)) error {
+	if len(pubkeys) == 0 && len(infohashes) == 0 {
+		return errors.New("no pubkeys or infohashes provided")
+	}
+
+	var filters []nostr.Filter
+	if len(pubkeys) > 0 {
+		filters = append(filters, nostr.Filter{
+			Kinds:   []int{KindComment},
+			Authors: pubkeys,
+		})
+	}
+	for start := 0; start < len(infohashes); start += commentInfohashBatch {
+		end := min(start+commentInfohashBatch, len(infohashes))
+		filters = append(filters, nostr.Filter{
+			Kinds: []int{KindComment},
+			Tags:  nostr.TagMap{"x": infohashes[start:end]},
+		})
+	}
+
+	log.Info().Int("authors", len(pubkeys)).Int("infohashes", len(infohashes)).Msg("Subscribing to torrent comments")
+
+	return rm.SubscribeAll(ctx, filters, handler)
+}
+
+// SubscribeDeletions subscribes to deletion requests published by the given authors
+func (rm *RelayManager) SubscribeDeletions(ctx context.Context, pubkeys []string, handler func(*nostr.Event, string)) error {
+	if len(pubkeys) == 0 {
+		return errors.New("no pubkeys provided")
+	}
+
 	filters := []nostr.Filter{
 		{
-			Kinds:   []int{KindTorrent},
+			Kinds:   []int{KindDeletion},
 			Authors: pubkeys,
 		},
 	}
 
-	log.Info().Int("authors", len(pubkeys)).Msg("Subscribing to torrents from trusted authors")
+	log.Info().Int("authors", len(pubkeys)).Msg("Subscribing to deletion requests")
 
 	return rm.SubscribeAll(ctx, filters, handler)
 }
 
-// FetchAllHistoricalTorrents fetches torrent events from trusted authors by paginating
-// through pages using the Until filter (newest-first, decreasing Until per page).
-// If sinceTimestamp > 0, only fetches events newer than that unix timestamp,
-// allowing resumption from where the last fetch left off.
-func (rm *RelayManager) FetchAllHistoricalTorrents(ctx context.Context, pubkeys []string, sinceTimestamp int64, handler func(*nostr.Event, string)) error {
+// SubscribeCuratorDecisions subscribes to verification decisions published by the given curators
+func (rm *RelayManager) SubscribeCuratorDecisions(ctx context.Context, curators []string, handler func(*nostr.Event, string)) error {
+	if len(curators) == 0 {
+		return errors.New("no curators provided")
+	}
+
+	filters := []nostr.Filter{
+		{
+			Kinds:   []int{KindDecision},
+			Authors: curators,
+		},
+	}
+
+	log.Info().Int("curators", len(curators)).Msg("Subscribing to curator decisions")
+
+	return rm.SubscribeAll(ctx, filters, handler)
+}
+
+// FetchAllHistoricalTorrents fetches torrent events from trusted authors on every
+// connected relay. Relays supporting NIP-77 are reconciled against the local
+// event store. Others resume from their sync cursor: new events are fetched
+// down to the newest cursor, and an unfinished backfill continues below the oldest.
+// Relays never synced get a full backfill, and authors the cursor does not
+// cover yet are backfilled on their own.
+func (rm *RelayManager) FetchAllHistoricalTorrents(ctx context.Context, pubkeys []string, handler func(*nostr.Event, string)) error {
 	if len(pubkeys) == 0 {
 		return errors.New("no pubkeys provided")
 	}
@@ -250,67 +356,235 @@ func (rm *RelayManager) FetchAllHistoricalTorrents(ctx context.Context, pubkeys
 		return errors.New("no connected relays")
 	}
 
-	const pageSize = 500
+	for _, client := range clients {
+		if err := rm.syncRelayHistory(ctx, client, pubkeys, handler); err != nil {
+			log.Error().Err(err).Str("relay", client.URL()).Msg("Historical fetch failed")
+		}
+	}
+
+	return nil
+}
+
+// syncRelayHistory brings the torrent history of an author set on one relay up to date
+func (rm *RelayManager) syncRelayHistory(ctx context.Context, client *Client, pubkeys []string, handler func(*nostr.Event, string)) error {
+	kinds := []int{KindTorrent}
+	pruneSyncState(client.URL(), kinds, syncFilterKey(kinds))
+
+	if rm.reconcileTorrents(ctx, client, pubkeys, handler) {
+		return nil
+	}
+
+	url := client.URL()
+	key := syncFilterKey(kinds)
+
+	state, err := loadSyncState(url, key)
+	if err != nil {
+		return fmt.Errorf("failed to load sync state: %w", err)
+	}
+
+	// Split the authors into those the cursor covers and those it does not,
+	// forgetting authors no longer followed
+	synced, err := loadSyncAuthors(url, key)
+	if err != nil {
+		return fmt.Errorf("failed to load sync authors: %w", err)
+	}
+	deleteSyncAuthors(url, key, newAuthors(pubkeys, synced))
+
+	covered := pubkeys
+	var added []string
+	if state != nil {
+		added = newAuthors(synced, pubkeys)
+		covered = newAuthors(added, pubkeys)
+	} else {
+		state = &SyncState{RelayURL: url, FilterKey: key}
+		saveSyncAuthors(url, key, pubkeys)
+		log.Info().Str("relay", url).Int("authors", len(pubkeys)).Msg("Starting full backfill for relay")
+	}
+	state.Kinds = kinds
+	state.AuthorCount = len(covered)
+
+	if len(covered) > 0 {
+		if err := syncCursorRange(ctx, client, state, covered, handler); err != nil {
+			return err
+		}
+	}
+
+	if len(added) > 0 {
+		// Fetch the whole history of new authors before the cursor covers them.
+		// An interrupted backfill starts over, since they are not recorded yet.
+		log.Info().Str("relay", url).Int("authors", len(added)).Msg("Backfilling new authors")
+		fetched, err := fetchRange(ctx, client, nostr.Filter{Kinds: kinds, Authors: added}, 0, 0, handler, nil)
+		if err != nil {
+			return fmt.Errorf("backfill of new authors interrupted after %d events: %w", fetched, err)
+		}
+		saveSyncAuthors(url, key, added)
+		state.AuthorCount = len(pubkeys)
+		saveSyncState(state)
+
+		log.Info().Str("relay", url).Int("total", fetched).Msg("New authors backfilled")
+	}
+
+	return nil
+}
+
+// syncCursorRange fetches the events of the authors covered by a sync state
+// newer than its cursor, then continues an unfinished backfill below it
+func syncCursorRange(ctx context.Context, client *Client, state *SyncState, pubkeys []string, handler func(*nostr.Event, string)) error {
+	url := client.URL()
+	filter := nostr.Filter{Kinds: state.Kinds, Authors: pubkeys}
+
+	if state.NewestCreatedAt > 0 {
+		// Catch up on events newer than the cursor
+		since := state.ResumeSince()
+		log.Info().Str("relay", url).Int64("since", since).Msg("Resuming torrent sync from cursor")
+
+		newest := state.NewestCreatedAt
+		fetched, err := fetchRange(ctx, client, filter, since, 0, handler, func(_, pageNewest int64) {
+			if pageNewest > newest {
+				newest = pageNewest
+			}
+		})
+		if err != nil {
+			return fmt.Errorf("failed to fetch new events: %w", err)
+		}
+		state.NewestCreatedAt = newest
+		saveSyncState(state)
+
+		log.Info().Str("relay", url).Int("total", fetched).Msg("Caught up with relay")
+
+		if state.BackfillComplete {
+			return nil
+		}
+	}
+
+	// Backfill older history, saving the cursor after every page so an
+	// interrupted backfill resumes where it stopped
+	fetched, err := fetchRange(ctx, client, filter, 0, state.OldestCreatedAt, handler, func(pageOldest, pageNewest int64) {
+		if pageNewest > state.NewestCreatedAt {
+			state.NewestCreatedAt = pageNewest
+		}
+		if state.OldestCreatedAt == 0 || pageOldest < state.OldestCreatedAt {
+			state.OldestCreatedAt = pageOldest
+		}
+		saveSyncState(state)
+	})
+	if err != nil {
+		return fmt.Errorf("backfill interrupted after %d events: %w", fetched, err)
+	}
+
+	state.BackfillComplete = true
+	saveSyncState(state)
+
+	log.Info().Str("relay", url).Int("total", fetched).Msg("Historical fetch complete")
+	return nil
+}
+
+// newAuthors returns the pubkeys that are not in previous
+func newAuthors(previous, pubkeys []string) []string {
+	known := make(map[string]bool, len(previous))
+	for _, pk := range previous {
+		known[pk] = true
+	}
+
+	var added []string
+	for _, pk := range pubkeys {
+		if !known[pk] {
+			added = append(added, pk)
+		}
+	}
+	return added
+}
+
+// FetchTorrentsSince fetches torrent events from trusted authors newer than
+// sinceTimestamp (0 for all history) from every connected relay, regardless of sync cursors
+func (rm *RelayManager) FetchTorrentsSince(ctx context.Context, pubkeys []string, sinceTimestamp int64, handler func(*nostr.Event, string)) error {
+	if len(pubkeys) == 0 {
+		return errors.New("no pubkeys provided")
+	}
+
+	clients := rm.GetConnectedClients()
+	if len(clients) == 0 {
+		return errors.New("no connected relays")
+	}
+
+	filter := nostr.Filter{Kinds: []int{KindTorrent}, Authors: pubkeys}
 
 	for _, client := range clients {
 		url := client.URL()
 		log.Info().Str("relay", url).Int64("since", sinceTimestamp).Msg("Fetching historical torrents (paginated)")
 
-		var until *nostr.Timestamp
-		var since *nostr.Timestamp
-		if sinceTimestamp > 0 {
-			s := nostr.Timestamp(sinceTimestamp)
-			since = &s
+		fetched, err := fetchRange(ctx, client, filter, sinceTimestamp, 0, handler, nil)
+		if err != nil {
+			log.Error().Err(err).Str("relay", url).Msg("Failed to query historical events")
 		}
-		totalFetched := 0
-		page := 0
 
-		for {
-			filter := nostr.Filter{
-				Kinds:   []int{KindTorrent},
-				Authors: pubkeys,
-				Limit:   pageSize,
-			}
-			if until != nil {
-				filter.Until = until
-			}
-			if since != nil {
-				filter.Since = since
-			}
+		log.Info().Str("relay", url).Int("total", fetched).Msg("Historical fetch complete")
+	}
 
-			events, err := client.QueryEvents(ctx, []nostr.Filter{filter})
-			if err != nil {
-				log.Error().Err(err).Str("relay", url).Int("page", page).Msg("Failed to query historical events")
-				break
-			}
+	return nil
+}
 
-			if len(events) == 0 {
-				break
-			}
+// fetchRange pages newest-first through events matching filter with
+// since <= created_at <= until (0 for unbounded). onPage, if set, is called
+// after each page has been handled with the page's oldest and newest created_at.
+// Returns the number of events fetched; a nil error means the range was exhausted.
+func fetchRange(ctx context.Context, client *Client, filter nostr.Filter, since, until int64, handler func(*nostr.Event, string), onPage func(oldest, newest int64)) (int, error) {
+	const pageSize = 500
 
-			for _, event := range events {
-				handler(event, url)
-			}
+	url := client.URL()
+	filter.Limit = pageSize
+	filter.Since = nil
+	filter.Until = nil
+	if since > 0 {
+		s := nostr.Timestamp(since)
+		filter.Since = &s
+	}
+	if until > 0 {
+		u := nostr.Timestamp(until)
+		filter.Until = &u
+	}
+
+	totalFetched := 0
+	page := 0
+
+	for {
+		events, err := client.QueryEvents(ctx, []nostr.Filter{filter})
+		if err != nil {
+			return totalFetched, err
+		}
 
-			totalFetched += len(events)
-			page++
-			log.Info().Str("relay", url).Int("page", page).Int("batch", len(events)).Int("total", totalFetched).Msg("Historical page fetched")
-
-			// Advance Until to the oldest event's timestamp in this batch.
-			// Using the exact timestamp (not -1) avoids skipping events that
-			// share the same second. The deduplicator handles any overlap.
-			oldest := events[len(events)-1].CreatedAt
-			if until != nil && oldest == *until {
-				// Same timestamp as last page — we've exhausted this second
-				oldest--
+		if len(events) == 0 {
+			return totalFetched, nil
+		}
+
+		newest, oldest := events[0].CreatedAt, events[0].CreatedAt
+		for _, event := range events {
+			handler(event, url)
+			if event.CreatedAt > newest {
+				newest = event.CreatedAt
+			}
+			if event.CreatedAt < oldest {
+				oldest = event.CreatedAt
 			}
-			until = &oldest
 		}
 
-		log.Info().Str("relay", url).Int("total", totalFetched).Msg("Historical fetch complete")
-	}
+		if onPage != nil {
+			onPage(int64(oldest), int64(newest))
+		}
 
-	return nil
+		totalFetched += len(events)
+		page++
+		log.Info().Str("relay", url).Int("page", page).Int("batch", len(events)).Int("total", totalFetched).Msg("Historical page fetched")
+
+		// Advance Until to the oldest event's timestamp in this batch.
+		// Using the exact timestamp (not -1) avoids skipping events that
+		// share the same second. The deduplicator handles any overlap.
+		if filter.Until != nil && oldest == *filter.Until {
+			// Same timestamp as last page — we've exhausted this second
+			oldest--
+		}
+		filter.Until = &oldest
+	}
 }
 
 // FetchContactList fetches contact list from any connected relay
@@ -331,6 +605,79 @@ func (rm *RelayManager) FetchContactList(ctx context.Context, pubkey string) (*n
 	return nil, errors.New("contact list not found")
 }
 
+// FetchContactLists fetches the newest contact list of each pubkey across all
+// connected relays. Pubkeys without a contact list are missing from the result.
+func (rm *RelayManager) FetchContactLists(ctx context.Context, pubkeys []string) (map[string]*nostr.Event, error) {
+	return rm.fetchLatestLists(ctx, KindContactList, pubkeys)
+}
+
+// FetchMuteLists fetches the newest NIP-51 mute list of each pubkey across all
+// connected relays. Pubkeys without a mute list are missing from the result.
+func (rm *RelayManager) FetchMuteLists(ctx context.Context, pubkeys []string) (map[string]*nostr.Event, error) {
+	return rm.fetchLatestLists(ctx, KindMuteList, pubkeys)
+}
+
+// fetchLatestLists fetches the newest replaceable event of a kind for each pubkey
+func (rm *RelayManager) fetchLatestLists(ctx context.Context, kind int, pubkeys []string) (map[string]*nostr.Event, error) {
+	clients := rm.GetConnectedClients()
+	if len(clients) == 0 {
+		return nil, errors.New("no connected relays")
+	}
+
+	lists := make(map[string]*nostr.Event)
+	for start := 0; start < len(pubkeys); start += contactListBatch {
+		filter := nostr.Filter{
+			Kinds:   []int{kind},
+			Authors: pubkeys[start:min(start+contactListBatch, len(pubkeys))],
+		}
+
+		for _, client := range clients {
+			events, err := client.QueryEvents(ctx, []nostr.Filter{filter})
+			if err != nil {
+				log.Debug().Err(err).Str("relay", client.URL()).Int("kind", kind).Msg("Failed to fetch lists")
+				continue
+			}
+			for _, event := range events {
+				if current, ok := lists[event.PubKey]; !ok || event.CreatedAt > current.CreatedAt {
+					lists[event.PubKey] = event
+				}
+			}
+		}
+
+		if ctx.Err() != nil {
+			return lists, ctx.Err()
+		}
+	}
+
+	return lists, nil
+}
+
+// FetchEvents queries all connected relays and returns the distinct events matching the filters
+func (rm *RelayManager) FetchEvents(ctx context.Context, filters []nostr.Filter) ([]*nostr.Event, error) {
+	clients := rm.GetConnectedClients()
+	if len(clients) == 0 {
+		return nil, errors.New("no connected relays")
+	}
+
+	seen := make(map[string]bool)
+	var events []*nostr.Event
+	for _, client := range clients {
+		found, err := client.QueryEvents(ctx, filters)
+		if err != nil {
+			log.Debug().Err(err).Str("relay", client.URL()).Msg("Failed to query events")
+			continue
+		}
+		for _, event := range found {
+			if !seen[event.ID] {
+				seen[event.ID] = true
+				events = append(events, event)
+			}
+		}
+	}
+
+	return events, ctx.Err()
+}
+
 // PublishToAll publishes an event to all connected relays
 func (rm *RelayManager) PublishToAll(ctx context.Context, event *nostr.Event) error {
 	clients := rm.GetConnectedClients()
@@ -0,0 +1,168 @@
+//go:build fts5
+
+package nostr
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gmonarque/lighthouse/internal/config"
+	"github.com/gmonarque/lighthouse/internal/database"
+	"github.com/gorilla/websocket"
+	"github.com/nbd-wtf/go-nostr"
+)
+
+// openTestDB initializes a database in a temporary directory
+func openTestDB(t *testing.T) {
+	t.Helper()
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	if _, err := config.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.Init(filepath.Join(dir, "lighthouse.db")); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { database.Close() })
+}
+
+// startCappedRelay serves events like a relay that sends at most replayCap
+// events for filters without a limit
+func startCappedRelay(t *testing.T, events []*nostr.Event, replayCap int) string {
+	t.Helper()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		for {
+			var msg []json.RawMessage
+			if err := conn.ReadJSON(&msg); err != nil {
+				return
+			}
+			var typ, subID string
+			if len(msg) < 3 || json.Unmarshal(msg[0], &typ) != nil || typ != "REQ" || json.Unmarshal(msg[1], &subID) != nil {
+				continue
+			}
+
+			var matched []*nostr.Event
+			limit := replayCap
+			for _, raw := range msg[2:] {
+				var filter nostr.Filter
+				if json.Unmarshal(raw, &filter) != nil {
+					continue
+				}
+				if filter.Limit > 0 {
+					limit = filter.Limit
+				}
+				for _, event := range events {
+					if filter.Matches(event) {
+						matched = append(matched, event)
+					}
+				}
+			}
+			sort.Slice(matched, func(i, j int) bool { return matched[i].CreatedAt > matched[j].CreatedAt })
+			if len(matched) > limit {
+				matched = matched[:limit]
+			}
+
+			for _, event := range matched {
+				conn.WriteJSON([]interface{}{"EVENT", subID, event})
+			}
+			conn.WriteJSON([]interface{}{"EOSE", subID})
+		}
+	}))
+	t.Cleanup(ts.Close)
+
+	return "ws" + strings.TrimPrefix(ts.URL, "http")
+}
+
+func TestCappedReplayBeforeHistorySync(t *testing.T) {
+	openTestDB(t)
+
+	// The relay was last synced two hours before the events it now holds
+	sk := nostr.GeneratePrivateKey()
+	pk, _ := nostr.GetPublicKey(sk)
+	cursor := time.Now().Add(-4 * time.Hour).Unix()
+
+	var events []*nostr.Event
+	for i := 0; i < 120; i++ {
+		event := &nostr.Event{
+			Kind:      KindTorrent,
+			CreatedAt: nostr.Timestamp(cursor + 2*syncCursorOverlap + int64(i)*60),
+			Tags:      nostr.Tags{},
+		}
+		if err := event.Sign(sk); err != nil {
+			t.Fatal(err)
+		}
+		events = append(events, event)
+	}
+	url := startCappedRelay(t, events, 50)
+
+	key := syncFilterKey([]int{KindTorrent})
+	saveSyncState(&SyncState{RelayURL: url, FilterKey: key, Kinds: []int{KindTorrent}, AuthorCount: 1,
+		NewestCreatedAt: cursor, OldestCreatedAt: cursor - 86400, BackfillComplete: true})
+	saveSyncAuthors(url, key, []string{pk})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	rm := NewRelayManager([]config.RelayConfig{{URL: url, Enabled: true}})
+	if err := rm.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer rm.Stop()
+
+	var mu sync.Mutex
+	received := make(map[string]bool)
+	handler := func(event *nostr.Event, _ string) {
+		mu.Lock()
+		received[event.ID] = true
+		mu.Unlock()
+	}
+	count := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(received)
+	}
+
+	// The live subscription replays only the newest events
+	if err := rm.SubscribeTrustedTorrents(ctx, []string{pk}, handler); err != nil {
+		t.Fatal(err)
+	}
+	for count() < 50 {
+		if ctx.Err() != nil {
+			t.Fatalf("received %d replayed events, want 50", count())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// The history sync must still fetch everything since the cursor
+	if err := rm.FetchAllHistoricalTorrents(ctx, []string{pk}, handler); err != nil {
+		t.Fatal(err)
+	}
+	if got := count(); got != len(events) {
+		t.Errorf("received %d events, want %d", got, len(events))
+	}
+}
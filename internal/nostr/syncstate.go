@@ -0,0 +1,187 @@
+package nostr
+
+import (
+	"database/sql"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gmonarque/lighthouse/internal/database"
+	"github.com/rs/zerolog/log"
+)
+
+// syncCursorOverlap is the number of seconds re-fetched below a relay's newest
+// cursor, to catch events that arrive late or carry skewed timestamps
+const syncCursorOverlap = 3600
+
+// SyncState is the range of events fully fetched from one relay for one filter.
+// Timestamps are event created_at values, never local time. The range covers
+// the authors recorded for the filter with saveSyncAuthors.
+type SyncState struct {
+	RelayURL         string
+	FilterKey        string
+	Kinds            []int
+	AuthorCount      int
+	NewestCreatedAt  int64
+	OldestCreatedAt  int64
+	BackfillComplete bool
+}
+
+// ResumeSince returns the timestamp to fetch new events from
+func (s *SyncState) ResumeSince() int64 {
+	if s.NewestCreatedAt <= syncCursorOverlap {
+		return 0
+	}
+	return s.NewestCreatedAt - syncCursorOverlap
+}
+
+// syncFilterKey identifies a filter by its kinds, independent of order. Authors
+// are not part of the key, so the cursor survives changes to the author set.
+func syncFilterKey(kinds []int) string {
+	sortedKinds := append([]int(nil), kinds...)
+	sort.Ints(sortedKinds)
+	return "kinds:" + joinKinds(sortedKinds)
+}
+
+// joinKinds formats kinds as a comma-separated list
+func joinKinds(kinds []int) string {
+	parts := make([]string, len(kinds))
+	for i, k := range kinds {
+		parts[i] = strconv.Itoa(k)
+	}
+	return strings.Join(parts, ",")
+}
+
+// loadSyncState returns the sync state of a relay and filter, or nil if the
+// relay has never been synced with this filter
+func loadSyncState(relayURL, filterKey string) (*SyncState, error) {
+	db := database.Get()
+	if db == nil {
+		return nil, nil
+	}
+
+	state := &SyncState{RelayURL: relayURL, FilterKey: filterKey}
+	err := db.QueryRow(`
+		SELECT newest_created_at, oldest_created_at, backfill_complete
+		FROM relay_sync_state WHERE relay_url = ? AND filter_key = ?
+	`, relayURL, filterKey).Scan(&state.NewestCreatedAt, &state.OldestCreatedAt, &state.BackfillComplete)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// saveSyncState stores a sync state. The newest cursor never moves backwards.
+func saveSyncState(state *SyncState) {
+	db := database.Get()
+	if db == nil {
+		return
+	}
+
+	_, err := db.Exec(`
+		INSERT INTO relay_sync_state (relay_url, filter_key, kinds, author_count,
+			newest_created_at, oldest_created_at, backfill_complete, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(relay_url, filter_key) DO UPDATE SET
+			newest_created_at = MAX(newest_created_at, excluded.newest_created_at),
+			oldest_created_at = excluded.oldest_created_at,
+			backfill_complete = excluded.backfill_complete,
+			updated_at = CURRENT_TIMESTAMP
+	`, state.RelayURL, state.FilterKey, joinKinds(state.Kinds), state.AuthorCount,
+		state.NewestCreatedAt, state.OldestCreatedAt, state.BackfillComplete)
+	if err != nil {
+		log.Error().Err(err).Str("relay", state.RelayURL).Msg("Failed to save sync state")
+	}
+}
+
+// pruneSyncState removes the sync states of a relay for the same kinds under
+// another filter key, such as cursors of author sets kept before they were
+// keyed by kinds alone
+func pruneSyncState(relayURL string, kinds []int, filterKey string) {
+	db := database.Get()
+	if db == nil {
+		return
+	}
+
+	sortedKinds := append([]int(nil), kinds...)
+	sort.Ints(sortedKinds)
+	if _, err := db.Exec(`
+		DELETE FROM relay_sync_state WHERE relay_url = ? AND kinds = ? AND filter_key != ?
+	`, relayURL, joinKinds(sortedKinds), filterKey); err != nil {
+		log.Error().Err(err).Str("relay", relayURL).Msg("Failed to prune sync state")
+	}
+}
+
+// loadSyncAuthors returns the authors covered by the sync state of a relay and filter
+func loadSyncAuthors(relayURL, filterKey string) ([]string, error) {
+	db := database.Get()
+	if db == nil {
+		return nil, nil
+	}
+
+	rows, err := db.Query(`
+		SELECT pubkey FROM relay_sync_authors WHERE relay_url = ? AND filter_key = ?
+	`, relayURL, filterKey)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pubkeys []string
+	for rows.Next() {
+		var pubkey string
+		if err := rows.Scan(&pubkey); err != nil {
+			return nil, err
+		}
+		pubkeys = append(pubkeys, pubkey)
+	}
+	return pubkeys, rows.Err()
+}
+
+// saveSyncAuthors records authors as covered by the sync state of a relay and filter
+func saveSyncAuthors(relayURL, filterKey string, pubkeys []string) {
+	db := database.Get()
+	if db == nil {
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		log.Error().Err(err).Str("relay", relayURL).Msg("Failed to save sync authors")
+		return
+	}
+	defer tx.Rollback()
+
+	for _, pubkey := range pubkeys {
+		if _, err := tx.Exec(`
+			INSERT OR IGNORE INTO relay_sync_authors (relay_url, filter_key, pubkey) VALUES (?, ?, ?)
+		`, relayURL, filterKey, pubkey); err != nil {
+			log.Error().Err(err).Str("relay", relayURL).Msg("Failed to save sync authors")
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		log.Error().Err(err).Str("relay", relayURL).Msg("Failed to save sync authors")
+	}
+}
+
+// deleteSyncAuthors removes authors from the sync state of a relay and filter.
+// Their events are no longer followed, so the cursor stops covering them.
+func deleteSyncAuthors(relayURL, filterKey string, pubkeys []string) {
+	db := database.Get()
+	if db == nil {
+		return
+	}
+
+	for _, pubkey := range pubkeys {
+		if _, err := db.Exec(`
+			DELETE FROM relay_sync_authors WHERE relay_url = ? AND filter_key = ? AND pubkey = ?
+		`, relayURL, filterKey, pubkey); err != nil {
+			log.Error().Err(err).Str("relay", relayURL).Msg("Failed to delete sync authors")
+			return
+		}
+	}
+}
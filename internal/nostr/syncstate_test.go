@@ -0,0 +1,34 @@
+package nostr
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSyncFilterKey(t *testing.T) {
+	if syncFilterKey([]int{KindTorrent, KindComment}) != syncFilterKey([]int{KindComment, KindTorrent}) {
+		t.Error("key should not depend on kind order")
+	}
+	if syncFilterKey([]int{KindTorrent}) == syncFilterKey([]int{KindComment}) {
+		t.Error("key should change with the kinds")
+	}
+}
+
+func TestSyncStateResumeSince(t *testing.T) {
+	if got := (&SyncState{NewestCreatedAt: 100}).ResumeSince(); got != 0 {
+		t.Errorf("ResumeSince() = %d, want 0", got)
+	}
+	if got := (&SyncState{NewestCreatedAt: 1700000000}).ResumeSince(); got != 1700000000-syncCursorOverlap {
+		t.Errorf("ResumeSince() = %d, want cursor minus overlap", got)
+	}
+}
+
+func TestNewAuthors(t *testing.T) {
+	got := newAuthors([]string{"aa", "bb"}, []string{"aa", "cc", "dd"})
+	if !slices.Equal(got, []string{"cc", "dd"}) {
+		t.Errorf("newAuthors() = %v, want [cc dd]", got)
+	}
+	if got := newAuthors([]string{"aa", "bb"}, []string{"aa"}); len(got) != 0 {
+		t.Errorf("newAuthors() = %v, want none", got)
+	}
+}
@@ -0,0 +1,145 @@
+package relay
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gmonarque/lighthouse/internal/nostr"
+	"github.com/gmonarque/lighthouse/internal/trust"
+)
+
+// Access levels for reading from and writing to a community relay
+const (
+	AccessOpen          = "open"          // anyone, without authenticating
+	AccessAuthenticated = "authenticated" // any pubkey authenticated with NIP-42
+	AccessTrusted       = "trusted"       // authenticated whitelisted or WoT-trusted pubkeys
+)
+
+// kindAuth is the kind of NIP-42 authentication events
+const kindAuth = 22242
+
+// authMaxSkew bounds how far the created_at of an AUTH event may be from now
+const authMaxSkew = 10 * time.Minute
+
+// AccessRules decide who may read from and write to a community relay.
+// An empty level is open.
+type AccessRules struct {
+	Read  string
+	Write string
+	// Kinds overrides Write for specific event kinds
+	Kinds map[int]string
+}
+
+// Validate checks that every rule is a known access level
+func (a AccessRules) Validate() error {
+	levels := map[string]string{"read": a.Read, "write": a.Write}
+	for kind, level := range a.Kinds {
+		levels[fmt.Sprintf("kind %d", kind)] = level
+	}
+	for name, level := range levels {
+		switch level {
+		case "", AccessOpen, AccessAuthenticated, AccessTrusted:
+		default:
+			return fmt.Errorf("invalid %s access level %q", name, level)
+		}
+	}
+	return nil
+}
+
+// writeLevel returns the access level required to publish an event of kind
+func (a AccessRules) writeLevel(kind int) string {
+	if level, ok := a.Kinds[kind]; ok {
+		return level
+	}
+	return a.Write
+}
+
+// requiresAuth reports whether an access level requires authentication.
+// Access rules only apply in community mode.
+func (s *Server) requiresAuth(level string) bool {
+	return s.mode != "public" && level != "" && level != AccessOpen
+}
+
+// checkAccess checks a client against an access level. Returns the reason it
+// is refused, with a NIP-01 machine-readable prefix, or "" if it is allowed.
+func (s *Server) checkAccess(client *Client, level, action string) string {
+	if !s.requiresAuth(level) {
+		return ""
+	}
+	if !client.authenticated {
+		return "auth-required: " + action + " requires authentication"
+	}
+	if level == AccessTrusted && !s.isTrusted(client.pubkey) {
+		return "restricted: " + action + " is limited to trusted pubkeys"
+	}
+	return ""
+}
+
+// trustedPubkey reports whether a hex pubkey is whitelisted or in the web of trust
+func trustedPubkey(pubkey string) bool {
+	npub, err := nostr.HexToNpub(pubkey)
+	if err != nil {
+		return false
+	}
+	return trust.NewWebOfTrust().IsTrusted(npub)
+}
+
+// newChallenge returns a random NIP-42 challenge
+func newChallenge() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// sendAuth sends the client its NIP-42 challenge
+func (s *Server) sendAuth(client *Client) {
+	msg, _ := json.Marshal([]interface{}{"AUTH", client.challenge})
+	client.send(msg)
+}
+
+// handleAuth processes AUTH messages, authenticating the client as the pubkey
+// that signed the challenge
+func (s *Server) handleAuth(client *Client, eventData json.RawMessage) {
+	var event Event
+	if err := json.Unmarshal(eventData, &event); err != nil {
+		s.sendOK(client, "", false, "invalid: malformed AUTH event")
+		return
+	}
+
+	if reason := checkAuthEvent(&event, client.challenge, client.host, time.Now()); reason != "" {
+		s.sendOK(client, event.ID, false, reason)
+		return
+	}
+
+	client.pubkey = event.PubKey
+	client.authenticated = true
+
+	s.sendOK(client, event.ID, true, "")
+}
+
+// checkAuthEvent checks a NIP-42 authentication event against the challenge
+// sent to the client and the host it connected to. Returns the reason it is
+// refused, or "" if it is valid.
+func checkAuthEvent(event *Event, challenge, host string, now time.Time) string {
+	if event.Kind != kindAuth {
+		return fmt.Sprintf("invalid: AUTH event must be kind %d", kindAuth)
+	}
+	if event.GetTagValue("challenge") != challenge {
+		return "invalid: challenge mismatch"
+	}
+	if d := now.Sub(time.Unix(event.CreatedAt, 0)); d > authMaxSkew || d < -authMaxSkew {
+		return "invalid: AUTH event created_at is too far from now"
+	}
+	if u, err := url.Parse(event.GetTagValue("relay")); err != nil || !strings.EqualFold(u.Host, host) {
+		return "invalid: AUTH event is for another relay"
+	}
+	if !event.VerifySignature() {
+		return "invalid: bad signature"
+	}
+	return ""
+}
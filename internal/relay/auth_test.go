@@ -0,0 +1,96 @@
+package relay
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	gonostr "github.com/nbd-wtf/go-nostr"
+)
+
+func signedAuthEvent(t *testing.T, sk, relayURL, challenge string, createdAt time.Time) *Event {
+	t.Helper()
+
+	event := &gonostr.Event{
+		Kind:      kindAuth,
+		CreatedAt: gonostr.Timestamp(createdAt.Unix()),
+		Tags:      gonostr.Tags{{"relay", relayURL}, {"challenge", challenge}},
+	}
+	if err := event.Sign(sk); err != nil {
+		t.Fatal(err)
+	}
+	return FromNostrEvent(event)
+}
+
+func TestCheckAuthEvent(t *testing.T) {
+	sk := gonostr.GeneratePrivateKey()
+	now := time.Now()
+
+	tests := []struct {
+		name  string
+		event *Event
+		want  string
+	}{
+		{"valid", signedAuthEvent(t, sk, "wss://example.com/relay", "abc", now), ""},
+		{"host is case-insensitive", signedAuthEvent(t, sk, "wss://EXAMPLE.com", "abc", now), ""},
+		{"wrong challenge", signedAuthEvent(t, sk, "wss://example.com", "xyz", now), "invalid: challenge"},
+		{"other relay", signedAuthEvent(t, sk, "wss://other.com", "abc", now), "invalid: AUTH event is for another relay"},
+		{"stale", signedAuthEvent(t, sk, "wss://example.com", "abc", now.Add(-time.Hour)), "invalid: AUTH event created_at"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := checkAuthEvent(tt.event, "abc", "example.com", now)
+			if (tt.want == "") != (got == "") || !strings.HasPrefix(got, tt.want) {
+				t.Errorf("checkAuthEvent() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	tampered := signedAuthEvent(t, sk, "wss://example.com", "abc", now)
+	tampered.PubKey, _ = gonostr.GetPublicKey(gonostr.GeneratePrivateKey())
+	if got := checkAuthEvent(tampered, "abc", "example.com", now); got != "invalid: bad signature" {
+		t.Errorf("checkAuthEvent() with another pubkey = %q, want a bad signature", got)
+	}
+}
+
+func TestCheckAccess(t *testing.T) {
+	s, err := NewServer(Config{
+		Mode:   "community",
+		Access: AccessRules{Read: AccessOpen, Write: AccessTrusted, Kinds: map[int]string{2004: AccessAuthenticated}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.isTrusted = func(pubkey string) bool { return pubkey == "member" }
+
+	anonymous := &Client{}
+	member := &Client{pubkey: "member", authenticated: true}
+	stranger := &Client{pubkey: "stranger", authenticated: true}
+
+	tests := []struct {
+		name   string
+		client *Client
+		level  string
+		want   string
+	}{
+		{"anyone reads", anonymous, s.access.Read, ""},
+		{"anonymous write", anonymous, s.access.writeLevel(2003), "auth-required:"},
+		{"member write", member, s.access.writeLevel(2003), ""},
+		{"stranger write", stranger, s.access.writeLevel(2003), "restricted:"},
+		{"stranger comment", stranger, s.access.writeLevel(2004), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := s.checkAccess(tt.client, tt.level, "publishing")
+			if (tt.want == "") != (got == "") || !strings.HasPrefix(got, tt.want) {
+				t.Errorf("checkAccess() = %q, want prefix %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := NewServer(Config{Access: AccessRules{Write: "members"}}); err == nil {
+		t.Error("NewServer() should reject an unknown access level")
+	}
+}
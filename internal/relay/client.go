@@ -0,0 +1,175 @@
+package relay
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/nbd-wtf/go-nostr/nip77/negentropy"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// clientQueueSize bounds the replies, and separately the live events,
+	// waiting to be written to a client
+	clientQueueSize = 256
+	// writeWait is the time allowed to write a message to a client
+	writeWait = 10 * time.Second
+	// pongWait is how long a client may go without answering a ping
+	pongWait = 60 * time.Second
+	// pingPeriod is how often clients are pinged; shorter than pongWait
+	pingPeriod = pongWait * 9 / 10
+)
+
+// Client represents a connected WebSocket client. Its fields are only used by
+// the goroutine reading its messages; everything written to it goes through
+// its queue and its writer goroutine.
+type Client struct {
+	conn          *websocket.Conn
+	subscriptions map[string]*Subscription
+	negSessions   map[string]*negentropy.Negentropy
+	pubkey        string // set once authenticated with NIP-42
+	authenticated bool
+	challenge     string       // NIP-42 challenge sent on connect
+	host          string       // host the client connected to, checked against AUTH events
+	lastPing      atomic.Int64 // unix nanoseconds of the last pong, or of connecting
+
+	// queue holds messages waiting to be written, in order. Replies and live
+	// events are counted separately so a long replay never counts against
+	// the live events the client keeps up with.
+	mu      sync.Mutex
+	written *sync.Cond // signalled when queued replies are taken or the client closes
+	queue   [][]byte
+	replies int
+	live    int
+	closed  bool
+
+	wake      chan struct{} // wakes the writer goroutine
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// newClient creates a client for a WebSocket connection
+func newClient(conn *websocket.Conn, host string) *Client {
+	c := &Client{
+		conn:          conn,
+		subscriptions: make(map[string]*Subscription),
+		negSessions:   make(map[string]*negentropy.Negentropy),
+		challenge:     newChallenge(),
+		host:          host,
+		wake:          make(chan struct{}, 1),
+		done:          make(chan struct{}),
+	}
+	c.written = sync.NewCond(&c.mu)
+	c.lastPing.Store(time.Now().UnixNano())
+	return c
+}
+
+// send queues a reply, waiting while clientQueueSize replies are queued. Used
+// from the client's own reader goroutine, so a slow client only slows itself down.
+func (c *Client) send(msg []byte) {
+	c.mu.Lock()
+	for c.replies >= clientQueueSize && !c.closed {
+		c.written.Wait()
+	}
+	if !c.closed {
+		c.queue = append(c.queue, msg)
+		c.replies++
+	}
+	c.mu.Unlock()
+	c.notify()
+}
+
+// trySend queues a live event without waiting. A client with clientQueueSize
+// live events still queued cannot keep up and is disconnected; queued replies
+// do not count. Reports whether the message was queued.
+func (c *Client) trySend(msg []byte) bool {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return false
+	}
+	if c.live >= clientQueueSize {
+		c.mu.Unlock()
+		log.Warn().Str("remote", c.conn.RemoteAddr().String()).Msg("Relay client too slow, disconnecting")
+		c.close()
+		return false
+	}
+	c.queue = append(c.queue, msg)
+	c.live++
+	c.mu.Unlock()
+	c.notify()
+	return true
+}
+
+// notify wakes the writer goroutine
+func (c *Client) notify() {
+	select {
+	case c.wake <- struct{}{}:
+	default:
+	}
+}
+
+// take removes and returns the queued messages
+func (c *Client) take() [][]byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	msgs := c.queue
+	c.queue = nil
+	c.replies = 0
+	c.live = 0
+	c.written.Broadcast()
+	return msgs
+}
+
+// close disconnects the client; its writer goroutine closes the connection
+func (c *Client) close() {
+	c.closeOnce.Do(func() {
+		c.mu.Lock()
+		c.closed = true
+		c.written.Broadcast()
+		c.mu.Unlock()
+		close(c.done)
+	})
+}
+
+// writeLoop writes queued messages to the client and pings it, until the
+// client is closed, a write fails or it stops answering pings
+func (c *Client) writeLoop() {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+	}()
+
+	for {
+		select {
+		case <-c.wake:
+			for _, msg := range c.take() {
+				c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+				if err := c.conn.WriteMessage(websocket.TextMessage, msg); err != nil {
+					c.close()
+					return
+				}
+			}
+
+		case <-ticker.C:
+			if time.Since(time.Unix(0, c.lastPing.Load())) > pongWait {
+				log.Debug().Str("remote", c.conn.RemoteAddr().String()).Msg("Relay client stopped answering pings")
+				c.close()
+				return
+			}
+			if err := c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeWait)); err != nil {
+				c.close()
+				return
+			}
+
+		case <-c.done:
+			c.conn.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseGoingAway, ""), time.Now().Add(writeWait))
+			return
+		}
+	}
+}
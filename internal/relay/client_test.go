@@ -0,0 +1,135 @@
+package relay
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// testConn returns both sides of a WebSocket connection
+func testConn(t *testing.T) (conn, peer *websocket.Conn) {
+	t.Helper()
+
+	conns := make(chan *websocket.Conn, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		conns <- conn
+	}))
+	t.Cleanup(ts.Close)
+
+	peer, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { peer.Close() })
+
+	conn = <-conns
+	t.Cleanup(func() { conn.Close() })
+	return conn, peer
+}
+
+func TestSlowClientDisconnected(t *testing.T) {
+	// Without a writer goroutine nothing drains the queue
+	conn, _ := testConn(t)
+	client := newClient(conn, "")
+
+	for i := 0; i < clientQueueSize; i++ {
+		if !client.trySend([]byte(`["NOTICE","x"]`)) {
+			t.Fatalf("trySend() failed after %d messages, want room for %d", i, clientQueueSize)
+		}
+	}
+
+	if client.trySend([]byte(`["NOTICE","x"]`)) {
+		t.Error("trySend() on a full queue should fail")
+	}
+	select {
+	case <-client.done:
+	default:
+		t.Error("a client whose queue overflows should be closed")
+	}
+
+	// Replies to a closed client are dropped instead of blocking
+	client.send([]byte(`["NOTICE","x"]`))
+}
+
+func TestReplayDoesNotDisconnect(t *testing.T) {
+	s, err := NewServer(Config{Mode: "public"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored := make([]*Event, clientQueueSize+44)
+	for i := range stored {
+		stored[i] = &Event{ID: fmt.Sprintf("%064x", i), Kind: 1, CreatedAt: int64(len(stored) - i)}
+	}
+	s.query = func([]Filter) []*Event { return stored }
+
+	conn, peer := testConn(t)
+	client := newClient(conn, "")
+
+	// Without a writer the replay fills the queue and waits for it to drain
+	replayed := make(chan struct{})
+	go func() {
+		s.handleReq(client, []json.RawMessage{json.RawMessage(`"sub"`), json.RawMessage(`{"kinds":[1]}`)})
+		close(replayed)
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		client.mu.Lock()
+		full := client.replies == clientQueueSize
+		client.mu.Unlock()
+		if full {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the replay did not fill the queue")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// A live event during the replay must not disconnect the client
+	live := &Event{ID: fmt.Sprintf("%064x", len(stored)), Kind: 1, CreatedAt: int64(len(stored) + 1)}
+	s.broadcastEvent(live)
+	select {
+	case <-client.done:
+		t.Fatal("a client receiving a replay should not be disconnected")
+	default:
+	}
+
+	go client.writeLoop()
+	<-replayed
+
+	var events, eose int
+	peer.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for events < len(stored)+1 || eose == 0 {
+		var msg []json.RawMessage
+		if err := peer.ReadJSON(&msg); err != nil {
+			t.Fatalf("read after %d events: %v", events, err)
+		}
+		var typ string
+		json.Unmarshal(msg[0], &typ)
+		switch typ {
+		case "EVENT":
+			var event Event
+			json.Unmarshal(msg[2], &event)
+			if (event.ID == live.ID) != (eose > 0) {
+				t.Fatalf("event %s sent on the wrong side of EOSE", event.ID)
+			}
+			events++
+		case "EOSE":
+			eose++
+		default:
+			t.Fatalf("unexpected %s message", typ)
+		}
+	}
+	client.close()
+}
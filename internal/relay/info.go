@@ -0,0 +1,88 @@
+package relay
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// Limits enforced by the relay and advertised in its information document
+const (
+	// maxMessageLength is the largest WebSocket message accepted from a client
+	maxMessageLength = 512 * 1024
+	// defaultLimit applies to filters without a limit
+	defaultLimit = 500
+	// maxLimit caps the limit of a filter
+	maxLimit = 5000
+)
+
+// supportedNIPs lists the NIPs the relay implements
+var supportedNIPs = []int{1, 9, 11, 42, 77}
+
+// torrentKinds are the torrent-related kinds accepted in every mode
+var torrentKinds = []int{
+	5,     // Deletion request
+	2003,  // Torrent
+	2004,  // Torrent comment
+	30173, // Trust policy
+	30175, // Verification decision
+}
+
+// software identifies the relay implementation in its information document
+const software = "https://github.com/gmonarque/lighthouse"
+
+// Info returns the NIP-11 relay information document
+func (s *Server) Info() RelayInfo {
+	// Kinds are listed like NIP-11 retention kinds: single kinds or [from, to] ranges
+	var kinds []interface{}
+	if s.mode == "public" {
+		kinds = append(kinds, [2]int{0, 9999})
+	}
+	for _, kind := range torrentKinds {
+		if s.mode != "public" || kind >= 10000 {
+			kinds = append(kinds, kind)
+		}
+	}
+
+	return RelayInfo{
+		Name:          s.name,
+		Description:   s.description,
+		Pubkey:        s.pubkey,
+		Contact:       s.contact,
+		SupportedNIPs: supportedNIPs,
+		Software:      software,
+		Limitation: &Limits{
+			MaxMessageLength: maxMessageLength,
+			MaxSubscriptions: maxSubscriptions,
+			MaxFilters:       maxFilters,
+			MaxLimit:         maxLimit,
+			DefaultLimit:     defaultLimit,
+			AuthRequired:     s.requiresAuth(s.access.Read),
+			RestrictedWrites: s.mode != "public" || s.requireCuration,
+		},
+		Mode:            s.mode,
+		AcceptedKinds:   kinds,
+		RequireCuration: s.requireCuration,
+	}
+}
+
+// wantsInfo reports whether a request asks for the NIP-11 document
+func wantsInfo(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "application/nostr+json")
+}
+
+// handleInfo serves the NIP-11 relay information document
+func (s *Server) handleInfo(w http.ResponseWriter, r *http.Request) {
+	// NIP-11 requires CORS so web clients can read the document
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Headers", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/nostr+json")
+	json.NewEncoder(w).Encode(s.Info())
+}
@@ -0,0 +1,59 @@
+package relay
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestInfoDocument(t *testing.T) {
+	s, err := NewServer(Config{Mode: "community", Name: "Test Relay", RequireCuration: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, MountPath, nil)
+	req.Header.Set("Accept", "application/nostr+json")
+	rec := httptest.NewRecorder()
+	s.Handler(MountPath).ServeHTTP(rec, req)
+
+	if ct := rec.Header().Get("Content-Type"); ct != "application/nostr+json" {
+		t.Fatalf("Content-Type = %q, want application/nostr+json", ct)
+	}
+	if rec.Header().Get("Access-Control-Allow-Origin") != "*" {
+		t.Error("NIP-11 document should allow any origin")
+	}
+
+	var info RelayInfo
+	if err := json.NewDecoder(rec.Body).Decode(&info); err != nil {
+		t.Fatal(err)
+	}
+	if info.Name != "Test Relay" || !info.RequireCuration {
+		t.Errorf("info = %+v, want the configured name and curation requirement", info)
+	}
+	if len(info.AcceptedKinds) != len(torrentKinds) {
+		t.Errorf("accepted_kinds = %v, want the torrent kinds only", info.AcceptedKinds)
+	}
+	if info.Limitation == nil || !info.Limitation.RestrictedWrites || info.Limitation.MaxLimit != maxLimit {
+		t.Errorf("limitation = %+v, want restricted writes and the enforced limits", info.Limitation)
+	}
+}
+
+func TestPublicModeKinds(t *testing.T) {
+	s, err := NewServer(Config{Mode: "public"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for kind, want := range map[int]bool{1: true, 2003: true, 10002: false, 30175: true} {
+		if got := s.isEventAllowed(&Event{Kind: kind}); got != want {
+			t.Errorf("isEventAllowed(kind %d) = %t, want %t", kind, got, want)
+		}
+	}
+
+	// Kinds below 10000 are covered by the range
+	if got := len(s.Info().AcceptedKinds); got != 3 {
+		t.Errorf("accepted_kinds has %d entries, want the range and the two addressable torrent kinds", got)
+	}
+}
@@ -0,0 +1,124 @@
+package relay
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip77/negentropy"
+	"github.com/nbd-wtf/go-nostr/nip77/negentropy/storage/vector"
+	"github.com/rs/zerolog/log"
+)
+
+// Limits on the NIP-77 reconciliation sessions of a connection
+const (
+	// negentropyMaxRecords caps the number of events a single session may cover
+	negentropyMaxRecords = 500000
+	// maxNegSessions caps the open sessions of a connection, each of which
+	// holds its events in memory
+	maxNegSessions = 4
+	// negentropyFrameLimit bounds each NEG-MSG reply in bytes. Replies are hex
+	// encoded, so they stay well within maxMessageLength.
+	negentropyFrameLimit = 60000
+)
+
+// errTooManyRecords is returned when a reconciliation filter matches too many events
+var errTooManyRecords = errors.New("too many records")
+
+// SyncItem identifies a stored event for set reconciliation
+type SyncItem struct {
+	ID        string
+	CreatedAt int64
+}
+
+// handleNegOpen starts a NIP-77 reconciliation session: ["NEG-OPEN", subID, filter, message]
+func (s *Server) handleNegOpen(client *Client, msg []json.RawMessage) {
+	var subID, initial string
+	var filter Filter
+	if len(msg) < 3 || json.Unmarshal(msg[0], &subID) != nil ||
+		json.Unmarshal(msg[1], &filter) != nil || json.Unmarshal(msg[2], &initial) != nil {
+		s.sendNotice(client, "Invalid NEG-OPEN")
+		return
+	}
+
+	// Opening an existing session ID replaces it
+	if _, ok := client.negSessions[subID]; ok {
+		delete(client.negSessions, subID)
+	} else if len(client.negSessions) >= maxNegSessions {
+		s.sendNegErr(client, subID, fmt.Sprintf("blocked: at most %d reconciliation sessions per connection", maxNegSessions))
+		return
+	}
+
+	if reason := s.checkAccess(client, s.access.Read, "reading"); reason != "" {
+		s.sendNegErr(client, subID, reason)
+		return
+	}
+
+	items, err := s.syncItems(filter, negentropyMaxRecords)
+	if errors.Is(err, errTooManyRecords) {
+		s.sendNegErr(client, subID, "blocked: too many records, narrow the filter")
+		return
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to load negentropy items")
+		s.sendNegErr(client, subID, "error: could not load events")
+		return
+	}
+
+	vec := vector.New()
+	for _, item := range items {
+		vec.Insert(nostr.Timestamp(item.CreatedAt), item.ID)
+	}
+	vec.Seal()
+
+	neg := negentropy.New(vec, negentropyFrameLimit)
+	client.negSessions[subID] = neg
+
+	s.reconcile(client, subID, neg, initial)
+}
+
+// handleNegMsg continues a reconciliation session: ["NEG-MSG", subID, message]
+func (s *Server) handleNegMsg(client *Client, msg []json.RawMessage) {
+	var subID, message string
+	if len(msg) < 2 || json.Unmarshal(msg[0], &subID) != nil || json.Unmarshal(msg[1], &message) != nil {
+		s.sendNotice(client, "Invalid NEG-MSG")
+		return
+	}
+
+	neg, ok := client.negSessions[subID]
+	if !ok {
+		s.sendNegErr(client, subID, "closed: unknown session")
+		return
+	}
+
+	s.reconcile(client, subID, neg, message)
+}
+
+// handleNegClose ends a reconciliation session: ["NEG-CLOSE", subID]
+func (s *Server) handleNegClose(client *Client, subIDData json.RawMessage) {
+	var subID string
+	if err := json.Unmarshal(subIDData, &subID); err != nil {
+		return
+	}
+	delete(client.negSessions, subID)
+}
+
+// reconcile answers one round of a session, closing it on failure
+func (s *Server) reconcile(client *Client, subID string, neg *negentropy.Negentropy, message string) {
+	reply, err := neg.Reconcile(message)
+	if err != nil {
+		delete(client.negSessions, subID)
+		s.sendNegErr(client, subID, "error: "+err.Error())
+		return
+	}
+
+	msg, _ := json.Marshal([]interface{}{"NEG-MSG", subID, reply})
+	client.send(msg)
+}
+
+// sendNegErr sends a NEG-ERR message to a client
+func (s *Server) sendNegErr(client *Client, subID, reason string) {
+	msg, _ := json.Marshal([]interface{}{"NEG-ERR", subID, reason})
+	client.send(msg)
+}
@@ -0,0 +1,224 @@
+package relay
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gmonarque/lighthouse/internal/nostr"
+	"github.com/gorilla/websocket"
+	gonostr "github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip77/negentropy"
+	"github.com/nbd-wtf/go-nostr/nip77/negentropy/storage/vector"
+)
+
+// startTestRelay serves a relay whose stored events are the given items
+func startTestRelay(t *testing.T, items []SyncItem) string {
+	t.Helper()
+
+	s, err := NewServer(Config{Mode: "public"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.syncItems = func(filter Filter, maxRecords int) ([]SyncItem, error) {
+		if len(items) > maxRecords {
+			return nil, errTooManyRecords
+		}
+		return items, nil
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(s.handleWebSocket))
+	t.Cleanup(ts.Close)
+
+	return "ws" + strings.TrimPrefix(ts.URL, "http")
+}
+
+func testSyncItem(n int) SyncItem {
+	id := sha256.Sum256([]byte(fmt.Sprintf("event-%d", n)))
+	return SyncItem{ID: hex.EncodeToString(id[:]), CreatedAt: 1700000000 + int64(n)}
+}
+
+func TestNegentropyReconcile(t *testing.T) {
+	var relayItems []SyncItem
+	for i := 0; i < 5000; i++ {
+		relayItems = append(relayItems, testSyncItem(i))
+	}
+
+	// Local copy has most of the relay's events plus some of its own
+	var local []nostr.EventRef
+	for i := 0; i < 5000; i++ {
+		if i%7 != 0 {
+			item := testSyncItem(i)
+			local = append(local, nostr.EventRef{ID: item.ID, CreatedAt: item.CreatedAt})
+		}
+	}
+	for i := 10000; i < 10010; i++ {
+		item := testSyncItem(i)
+		local = append(local, nostr.EventRef{ID: item.ID, CreatedAt: item.CreatedAt})
+	}
+
+	var want []string
+	for i := 0; i < 5000; i += 7 {
+		want = append(want, testSyncItem(i).ID)
+	}
+	sort.Strings(want)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	client := nostr.NewClient(startTestRelay(t, relayItems))
+	if err := client.Connect(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer client.Disconnect()
+
+	need, err := client.Reconcile(ctx, gonostr.Filter{Kinds: []int{nostr.KindTorrent}}, local)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(need)
+
+	if strings.Join(need, ",") != strings.Join(want, ",") {
+		t.Errorf("Reconcile() returned %d missing IDs, want %d", len(need), len(want))
+	}
+}
+
+func TestNegentropyTooManyRecords(t *testing.T) {
+	items := make([]SyncItem, negentropyMaxRecords+1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	client := nostr.NewClient(startTestRelay(t, items))
+	if err := client.Connect(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer client.Disconnect()
+
+	if _, err := client.Reconcile(ctx, gonostr.Filter{}, nil); err == nil {
+		t.Error("expected the relay to reject an oversized reconciliation")
+	}
+	if !client.SupportsNegentropy() {
+		t.Error("a rejection should not mark the relay as unsupported")
+	}
+}
+
+// negOpen sends a NEG-OPEN with the initial message of an empty set and returns the reply
+func negOpen(t *testing.T, conn *websocket.Conn, subID string) []string {
+	t.Helper()
+
+	vec := vector.New()
+	vec.Seal()
+	initial := negentropy.New(vec, 0).Start()
+
+	if err := conn.WriteJSON([]interface{}{"NEG-OPEN", subID, gonostr.Filter{}, initial}); err != nil {
+		t.Fatal(err)
+	}
+	var reply []string
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if err := conn.ReadJSON(&reply); err != nil {
+		t.Fatal(err)
+	}
+	return reply
+}
+
+func TestNegentropySessionLimit(t *testing.T) {
+	conn, _, err := websocket.DefaultDialer.Dial(startTestRelay(t, []SyncItem{testSyncItem(0)}), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// Skip the AUTH challenge sent on connect
+	var challenge []string
+	if err := conn.ReadJSON(&challenge); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < maxNegSessions; i++ {
+		if reply := negOpen(t, conn, fmt.Sprintf("neg-%d", i)); reply[0] != "NEG-MSG" {
+			t.Fatalf("session %d: got %v, want NEG-MSG", i, reply)
+		}
+	}
+
+	reply := negOpen(t, conn, "neg-extra")
+	if reply[0] != "NEG-ERR" || !strings.HasPrefix(reply[2], "blocked:") {
+		t.Errorf("got %v, want NEG-ERR blocked", reply)
+	}
+
+	// Reopening an open session replaces it
+	if reply := negOpen(t, conn, "neg-0"); reply[0] != "NEG-MSG" {
+		t.Errorf("reopening a session: got %v, want NEG-MSG", reply)
+	}
+}
+
+func TestNegentropyFrameLimit(t *testing.T) {
+	// The relay and the client hold disjoint sets, so every ID is exchanged
+	var relayItems []SyncItem
+	local := vector.New()
+	for i := 0; i < 20000; i++ {
+		relayItems = append(relayItems, testSyncItem(2*i))
+		item := testSyncItem(2*i + 1)
+		local.Insert(gonostr.Timestamp(item.CreatedAt), item.ID)
+	}
+	local.Seal()
+
+	conn, _, err := websocket.DefaultDialer.Dial(startTestRelay(t, relayItems), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	var challenge []string
+	if err := conn.ReadJSON(&challenge); err != nil {
+		t.Fatal(err)
+	}
+
+	// The client sends frames of any size
+	neg := negentropy.New(local, 0)
+	go func() {
+		for range neg.Haves {
+		}
+	}()
+	go func() {
+		for range neg.HaveNots {
+		}
+	}()
+
+	msg := []interface{}{"NEG-OPEN", "neg", gonostr.Filter{}, neg.Start()}
+	for rounds := 0; ; rounds++ {
+		if rounds > 100 {
+			t.Fatal("reconciliation did not finish")
+		}
+		if err := conn.WriteJSON(msg); err != nil {
+			t.Fatal(err)
+		}
+		var reply []string
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		if err := conn.ReadJSON(&reply); err != nil {
+			t.Fatal(err)
+		}
+		if reply[0] != "NEG-MSG" {
+			t.Fatalf("got %v, want NEG-MSG", reply)
+		}
+		if len(reply[2]) > 2*negentropyFrameLimit {
+			t.Fatalf("reply of %d hex characters exceeds the %d byte frame limit", len(reply[2]), negentropyFrameLimit)
+		}
+
+		next, err := neg.Reconcile(reply[2])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if next == "" {
+			return
+		}
+		msg = []interface{}{"NEG-MSG", "neg", next}
+	}
+}
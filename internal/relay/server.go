@@ -6,71 +6,93 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"slices"
+	"strconv"
 	"sync"
 	"time"
 
 	"github.com/gmonarque/lighthouse/internal/config"
+	"github.com/gmonarque/lighthouse/internal/nostr"
 	"github.com/gorilla/websocket"
 	"github.com/rs/zerolog/log"
 )
 
+// MountPath is where the relay is served on the main HTTP server when it has
+// no listen address of its own
+const MountPath = "/relay"
+
 // Server is a Nostr relay server for torrent events
 type Server struct {
 	mu sync.RWMutex
 
 	// Configuration
-	listen          string
+	listen          string // empty when mounted on the main HTTP server
 	mode            string // "public" or "community"
 	requireCuration bool
+	access          AccessRules
+
+	// Information document (NIP-11)
+	name        string
+	description string
+	pubkey      string
+	contact     string
 
 	// Components
 	policy        *TorrentPolicy
 	storage       *EventStorage
-	subscriptions map[string]*Subscription
+	subscriptions *subscriptionIndex
+
+	// query returns the stored events matching filters
+	query func(filters []Filter) []*Event
+
+	// syncItems lists stored events for NIP-77 reconciliation
+	syncItems func(filter Filter, maxRecords int) ([]SyncItem, error)
+
+	// isTrusted reports whether a pubkey meets the trusted access level
+	isTrusted func(pubkey string) bool
 
 	// WebSocket upgrader
 	upgrader websocket.Upgrader
 
 	// State
 	running bool
+	stopped bool // refuses new clients once stopped
 	server  *http.Server
 	clients map[*websocket.Conn]*Client
 }
 
-// Client represents a connected WebSocket client
-type Client struct {
-	conn          *websocket.Conn
-	subscriptions map[string]*Subscription
-	pubkey        string
-	authenticated bool
-	lastPing      time.Time
-}
-
-// Subscription represents a client subscription
-type Subscription struct {
-	ID      string
-	Filters []Filter
-	Client  *Client
-}
-
 // Config holds relay configuration
 type Config struct {
 	Listen          string
 	Mode            string
 	RequireCuration bool
+	Access          AccessRules
 	SyncWith        []string
 	EnableDiscovery bool
+	Name            string
+	Description     string
+	Pubkey          string // hex pubkey of the operator
+	Contact         string
 }
 
 // NewServer creates a new relay server
 func NewServer(cfg Config) (*Server, error) {
+	if err := cfg.Access.Validate(); err != nil {
+		return nil, err
+	}
+
 	s := &Server{
 		listen:          cfg.Listen,
 		mode:            cfg.Mode,
 		requireCuration: cfg.RequireCuration,
+		access:          cfg.Access,
+		name:            cfg.Name,
+		description:     cfg.Description,
+		pubkey:          cfg.Pubkey,
+		contact:         cfg.Contact,
 		policy:          NewTorrentPolicy(),
 		storage:         NewEventStorage(),
-		subscriptions:   make(map[string]*Subscription),
+		subscriptions:   newSubscriptionIndex(),
 		clients:         make(map[*websocket.Conn]*Client),
 		upgrader: websocket.Upgrader{
 			ReadBufferSize:  1024,
@@ -81,6 +103,11 @@ func NewServer(cfg Config) (*Server, error) {
 		},
 	}
 
+	s.query = s.storage.Query
+	s.syncItems = s.storage.SyncItems
+	s.isTrusted = trustedPubkey
+	s.policy.SetRequireCuration(cfg.RequireCuration)
+
 	return s, nil
 }
 
@@ -92,15 +119,20 @@ func (s *Server) Start() error {
 		return fmt.Errorf("relay already running")
 	}
 	s.running = true
+	s.stopped = false
 	s.mu.Unlock()
 
-	mux := http.NewServeMux()
-	mux.HandleFunc("/", s.handleWebSocket)
-	mux.HandleFunc("/health", s.handleHealth)
+	if s.listen == "" {
+		log.Info().
+			Str("path", MountPath).
+			Str("mode", s.mode).
+			Msg("Nostr relay mounted on the HTTP server")
+		return nil
+	}
 
 	s.server = &http.Server{
 		Addr:         s.listen,
-		Handler:      mux,
+		Handler:      s.Handler(""),
 		ReadTimeout:  10 * time.Second,
 		WriteTimeout: 10 * time.Second,
 	}
@@ -119,6 +151,34 @@ func (s *Server) Start() error {
 	return nil
 }
 
+// Mounted reports whether the relay is served by the main HTTP server
+// rather than on its own listen address
+func (s *Server) Mounted() bool {
+	return s.listen == ""
+}
+
+// Handler returns the HTTP handler of the relay, serving WebSocket clients and
+// the NIP-11 document at base and a health check at base + "/health"
+func (s *Server) Handler(base string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(base+"/", s.handleRoot)
+	mux.HandleFunc(base+"/health", s.handleHealth)
+	if base != "" {
+		mux.HandleFunc(base, s.handleRoot)
+	}
+	return mux
+}
+
+// handleRoot serves the NIP-11 document to clients asking for it and upgrades
+// everyone else to a WebSocket
+func (s *Server) handleRoot(w http.ResponseWriter, r *http.Request) {
+	if wantsInfo(r) {
+		s.handleInfo(w, r)
+		return
+	}
+	s.handleWebSocket(w, r)
+}
+
 // Stop stops the relay server
 func (s *Server) Stop() error {
 	s.mu.Lock()
@@ -129,10 +189,11 @@ func (s *Server) Stop() error {
 	}
 
 	s.running = false
+	s.stopped = true
 
 	// Close all client connections
-	for conn := range s.clients {
-		conn.Close()
+	for _, client := range s.clients {
+		client.close()
 	}
 
 	if s.server != nil {
@@ -146,29 +207,50 @@ func (s *Server) Stop() error {
 
 // handleWebSocket handles WebSocket connections
 func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	stopped := s.stopped
+	s.mu.RUnlock()
+	if stopped {
+		http.Error(w, "relay stopped", http.StatusServiceUnavailable)
+		return
+	}
+
 	conn, err := s.upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		log.Error().Err(err).Msg("WebSocket upgrade failed")
 		return
 	}
+	conn.SetReadLimit(maxMessageLength)
 
-	client := &Client{
-		conn:          conn,
-		subscriptions: make(map[string]*Subscription),
-		lastPing:      time.Now(),
-	}
+	client := newClient(conn, r.Host)
+	conn.SetPongHandler(func(string) error {
+		client.lastPing.Store(time.Now().UnixNano())
+		return nil
+	})
 
 	s.mu.Lock()
+	if s.stopped {
+		// Stopped while upgrading
+		s.mu.Unlock()
+		conn.Close()
+		return
+	}
 	s.clients[conn] = client
 	s.mu.Unlock()
 
 	log.Debug().Str("remote", conn.RemoteAddr().String()).Msg("Client connected")
 
+	go client.writeLoop()
+	s.sendAuth(client)
+
 	defer func() {
 		s.mu.Lock()
 		delete(s.clients, conn)
 		s.mu.Unlock()
-		conn.Close()
+		for _, sub := range client.subscriptions {
+			s.subscriptions.remove(sub)
+		}
+		client.close()
 		log.Debug().Str("remote", conn.RemoteAddr().String()).Msg("Client disconnected")
 	}()
 
@@ -227,6 +309,26 @@ func (s *Server) handleMessage(client *Client, message []byte) {
 		}
 		s.handleClose(client, msg[1])
 
+	case "AUTH":
+		if len(msg) < 2 {
+			s.sendNotice(client, "Missing auth event")
+			return
+		}
+		s.handleAuth(client, msg[1])
+
+	case "NEG-OPEN":
+		s.handleNegOpen(client, msg[1:])
+
+	case "NEG-MSG":
+		s.handleNegMsg(client, msg[1:])
+
+	case "NEG-CLOSE":
+		if len(msg) < 2 {
+			s.sendNotice(client, "Missing subscription ID")
+			return
+		}
+		s.handleNegClose(client, msg[1])
+
 	default:
 		s.sendNotice(client, fmt.Sprintf("Unknown message type: %s", msgType))
 	}
@@ -259,6 +361,12 @@ func (s *Server) handleEvent(client *Client, eventData json.RawMessage) {
 		return
 	}
 
+	// Check the client may publish this kind
+	if reason := s.checkAccess(client, s.access.writeLevel(event.Kind), fmt.Sprintf("publishing kind %d", event.Kind)); reason != "" {
+		s.sendOK(client, event.ID, false, reason)
+		return
+	}
+
 	// Apply torrent policy
 	if event.Kind == 2003 { // Torrent event
 		if allowed, reason := s.policy.CheckEvent(&event); !allowed {
@@ -267,6 +375,17 @@ func (s *Server) handleEvent(client *Client, eventData json.RawMessage) {
 		}
 	}
 
+	// Honour NIP-09 deletion requests, and refuse events already deleted by their author
+	if event.Kind == 5 {
+		if _, err := s.storage.ApplyDeletion(&event); err != nil {
+			s.sendOK(client, event.ID, false, fmt.Sprintf("error: %v", err))
+			return
+		}
+	} else if s.storage.IsDeleted(&event) {
+		s.sendOK(client, event.ID, false, "blocked: event was deleted by its author")
+		return
+	}
+
 	// Store event
 	if err := s.storage.Save(&event); err != nil {
 		s.sendOK(client, event.ID, false, fmt.Sprintf("Storage error: %v", err))
@@ -288,6 +407,16 @@ func (s *Server) handleReq(client *Client, msg []json.RawMessage) {
 		return
 	}
 
+	if reason := s.checkAccess(client, s.access.Read, "reading"); reason != "" {
+		s.sendClosed(client, subID, reason)
+		return
+	}
+
+	if len(msg)-1 > maxFilters {
+		s.sendClosed(client, subID, fmt.Sprintf("error: at most %d filters per subscription", maxFilters))
+		return
+	}
+
 	// Parse filters
 	var filters []Filter
 	for _, filterData := range msg[1:] {
@@ -299,27 +428,38 @@ func (s *Server) handleReq(client *Client, msg []json.RawMessage) {
 		filters = append(filters, filter)
 	}
 
-	// Create subscription
-	sub := &Subscription{
-		ID:      subID,
-		Filters: filters,
-		Client:  client,
+	// A REQ with an open subscription ID replaces that subscription
+	if old, ok := client.subscriptions[subID]; ok {
+		s.subscriptions.remove(old)
+		delete(client.subscriptions, subID)
+	} else if len(client.subscriptions) >= maxSubscriptions {
+		s.sendClosed(client, subID, fmt.Sprintf("error: at most %d subscriptions per connection", maxSubscriptions))
+		return
 	}
 
-	client.subscriptions[subID] = sub
+	// Create subscription. It is indexed before the stored events are
+	// queried so no event is missed; live events are held back until EOSE.
+	sub := newSubscription(subID, filters, client)
 
-	s.mu.Lock()
-	s.subscriptions[subID] = sub
-	s.mu.Unlock()
+	client.subscriptions[subID] = sub
+	s.subscriptions.add(sub)
 
 	// Query stored events matching filters
-	events := s.storage.Query(filters)
+	events := s.query(filters)
+	replayed := make(map[string]bool, len(events))
 	for _, event := range events {
 		s.sendEvent(client, subID, event)
+		replayed[event.ID] = true
 	}
 
 	// Send EOSE (End of Stored Events)
 	s.sendEOSE(client, subID)
+
+	if !sub.goLive(replayed) {
+		s.subscriptions.remove(sub)
+		delete(client.subscriptions, subID)
+		s.sendClosed(client, subID, "error: too many new events while sending stored events, subscribe again")
+	}
 }
 
 // handleClose processes CLOSE messages
@@ -329,40 +469,29 @@ func (s *Server) handleClose(client *Client, subIDData json.RawMessage) {
 		return
 	}
 
-	delete(client.subscriptions, subID)
-
-	s.mu.Lock()
-	delete(s.subscriptions, subID)
-	s.mu.Unlock()
+	if sub, ok := client.subscriptions[subID]; ok {
+		s.subscriptions.remove(sub)
+		delete(client.subscriptions, subID)
+	}
 }
 
 // isEventAllowed checks if an event kind is allowed
 func (s *Server) isEventAllowed(event *Event) bool {
-	// Torrent-related kinds
-	allowedKinds := map[int]bool{
-		2003:  true, // Torrent
-		2004:  true, // Torrent comment
-		30175: true, // Verification decision
-		30173: true, // Trust policy
-	}
-
-	if s.mode == "public" {
+	if s.mode == "public" && event.Kind < 10000 {
 		// Public mode allows all standard kinds plus torrent kinds
-		return event.Kind < 10000 || allowedKinds[event.Kind]
+		return true
 	}
 
 	// Community mode only allows torrent-related kinds
-	return allowedKinds[event.Kind]
+	return slices.Contains(torrentKinds, event.Kind)
 }
 
-// broadcastEvent sends an event to all matching subscribers
+// broadcastEvent sends an event to all matching subscribers. Subscribers that
+// cannot keep up are disconnected rather than holding up the others.
 func (s *Server) broadcastEvent(event *Event) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-
-	for _, sub := range s.subscriptions {
+	for _, sub := range s.subscriptions.candidates(event) {
 		if matchesFilters(event, sub.Filters) {
-			s.sendEvent(sub.Client, sub.ID, event)
+			sub.deliver(event)
 		}
 	}
 }
@@ -370,25 +499,31 @@ func (s *Server) broadcastEvent(event *Event) {
 // sendEvent sends an EVENT message to a client
 func (s *Server) sendEvent(client *Client, subID string, event *Event) {
 	msg, _ := json.Marshal([]interface{}{"EVENT", subID, event})
-	client.conn.WriteMessage(websocket.TextMessage, msg)
+	client.send(msg)
 }
 
 // sendOK sends an OK message to a client
 func (s *Server) sendOK(client *Client, eventID string, success bool, message string) {
 	msg, _ := json.Marshal([]interface{}{"OK", eventID, success, message})
-	client.conn.WriteMessage(websocket.TextMessage, msg)
+	client.send(msg)
 }
 
 // sendNotice sends a NOTICE message to a client
 func (s *Server) sendNotice(client *Client, message string) {
 	msg, _ := json.Marshal([]interface{}{"NOTICE", message})
-	client.conn.WriteMessage(websocket.TextMessage, msg)
+	client.send(msg)
+}
+
+// sendClosed tells a client its subscription was refused or ended
+func (s *Server) sendClosed(client *Client, subID, reason string) {
+	msg, _ := json.Marshal([]interface{}{"CLOSED", subID, reason})
+	client.send(msg)
 }
 
 // sendEOSE sends an EOSE message to a client
 func (s *Server) sendEOSE(client *Client, subID string) {
 	msg, _ := json.Marshal([]interface{}{"EOSE", subID})
-	client.conn.WriteMessage(websocket.TextMessage, msg)
+	client.send(msg)
 }
 
 // handleHealth handles health check requests
@@ -417,16 +552,16 @@ func (s *Server) GetStats() RelayStats {
 		Running:     s.running,
 		ClientCount: len(s.clients),
 		EventCount:  s.storage.Count(),
-		SubCount:    len(s.subscriptions),
+		SubCount:    s.subscriptions.len(),
 	}
 }
 
 // RelayStats contains relay statistics
 type RelayStats struct {
-	Running     bool
-	ClientCount int
-	EventCount  int64
-	SubCount    int
+	Running     bool  `json:"running"`
+	ClientCount int   `json:"client_count"`
+	EventCount  int64 `json:"event_count"`
+	SubCount    int   `json:"subscription_count"`
 }
 
 // Global relay instance
@@ -447,6 +582,27 @@ func InitGlobal() error {
 		RequireCuration: cfg.Relay.RequireCuration,
 		SyncWith:        cfg.Relay.SyncWith,
 		EnableDiscovery: cfg.Relay.EnableDiscovery,
+		Name:            cfg.Relay.Name,
+		Description:     cfg.Relay.Description,
+		Contact:         cfg.Relay.Contact,
+	}
+
+	// The node identity operates the relay
+	if pubkey, err := nostr.NpubToHex(cfg.Nostr.Identity.Npub); err == nil {
+		relayCfg.Pubkey = pubkey
+	}
+
+	relayCfg.Access = AccessRules{
+		Read:  cfg.Relay.Access.Read,
+		Write: cfg.Relay.Access.Write,
+		Kinds: make(map[int]string, len(cfg.Relay.Access.Kinds)),
+	}
+	for kind, level := range cfg.Relay.Access.Kinds {
+		k, err := strconv.Atoi(kind)
+		if err != nil {
+			return fmt.Errorf("invalid kind %q in relay access rules", kind)
+		}
+		relayCfg.Access.Kinds[k] = level
 	}
 
 	var err error
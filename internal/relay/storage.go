@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"strconv"
 	"strings"
 	"sync"
 
@@ -140,7 +141,72 @@ func (s *EventStorage) Query(filters []Filter) []*Event {
 
 // queryFilter queries events for a single filter
 func (s *EventStorage) queryFilter(db *sql.DB, filter Filter) []*Event {
-	query := "SELECT raw_json FROM relay_events WHERE 1=1"
+	where, args := filterConditions(filter)
+	query := "SELECT raw_json FROM relay_events WHERE " + where
+
+	query += " ORDER BY created_at DESC"
+
+	limit := defaultLimit
+	if filter.Limit > 0 {
+		limit = min(filter.Limit, maxLimit)
+	}
+	query += fmt.Sprintf(" LIMIT %d", limit)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		log.Error().Err(err).Str("query", query).Msg("Query failed")
+		return nil
+	}
+	defer rows.Close()
+
+	var events []*Event
+	for rows.Next() {
+		var rawJSON string
+		if err := rows.Scan(&rawJSON); err != nil {
+			continue
+		}
+
+		var event Event
+		if err := json.Unmarshal([]byte(rawJSON), &event); err != nil {
+			continue
+		}
+
+		events = append(events, &event)
+	}
+
+	return events
+}
+
+// SyncItems returns the IDs and timestamps of all events matching a filter,
+// ignoring its limit. Fails with errTooManyRecords above maxRecords.
+func (s *EventStorage) SyncItems(filter Filter, maxRecords int) ([]SyncItem, error) {
+	where, args := filterConditions(filter)
+	args = append(args, maxRecords+1)
+
+	rows, err := database.Get().Query("SELECT event_id, created_at FROM relay_events WHERE "+where+" LIMIT ?", args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query sync items: %w", err)
+	}
+	defer rows.Close()
+
+	var items []SyncItem
+	for rows.Next() {
+		var item SyncItem
+		if err := rows.Scan(&item.ID, &item.CreatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	if len(items) > maxRecords {
+		return nil, errTooManyRecords
+	}
+
+	return items, rows.Err()
+}
+
+// filterConditions builds the WHERE clause and arguments for a filter
+func filterConditions(filter Filter) (string, []interface{}) {
+	query := "1=1"
 	args := []interface{}{}
 
 	// Build query based on filter
@@ -148,7 +214,7 @@ func (s *EventStorage) queryFilter(db *sql.DB, filter Filter) []*Event {
 		placeholders := make([]string, len(filter.IDs))
 		for i, id := range filter.IDs {
 			if len(id) == 64 {
-				placeholders[i] = "?"
+				placeholders[i] = "event_id = ?"
 				args = append(args, id)
 			} else {
 				// Prefix match
@@ -245,53 +311,138 @@ func (s *EventStorage) queryFilter(db *sql.DB, filter Filter) []*Event {
 		}
 	}
 
-	query += " ORDER BY created_at DESC"
+	return query, args
+}
 
-	if filter.Limit > 0 {
-		query += fmt.Sprintf(" LIMIT %d", filter.Limit)
-	} else {
-		query += " LIMIT 500" // Default limit
-	}
+// Delete deletes an event
+func (s *EventStorage) Delete(eventID string) error {
+	db := database.Get()
 
-	rows, err := db.Query(query, args...)
+	_, err := db.Exec("DELETE FROM relay_events WHERE event_id = ?", eventID)
 	if err != nil {
-		log.Error().Err(err).Str("query", query).Msg("Query failed")
-		return nil
+		return fmt.Errorf("failed to delete event: %w", err)
 	}
-	defer rows.Close()
 
-	var events []*Event
-	for rows.Next() {
-		var rawJSON string
-		if err := rows.Scan(&rawJSON); err != nil {
+	s.mu.Lock()
+	delete(s.cache, eventID)
+	s.mu.Unlock()
+
+	return nil
+}
+
+// ApplyDeletion removes the events a NIP-09 deletion request refers to.
+// Only events by the same author are removed; "a" tags remove versions of a
+// parameterized replaceable event up to the deletion's timestamp.
+func (s *EventStorage) ApplyDeletion(deletion *Event) (int64, error) {
+	db := database.Get()
+
+	var deleted int64
+	for _, tag := range deletion.Tags {
+		if len(tag) < 2 {
 			continue
 		}
 
-		var event Event
-		if err := json.Unmarshal([]byte(rawJSON), &event); err != nil {
+		var result sql.Result
+		var err error
+		switch tag[0] {
+		case "e":
+			result, err = db.Exec(`
+				DELETE FROM relay_events WHERE event_id = ? AND pubkey = ? AND kind != 5
+			`, tag[1], deletion.PubKey)
+		case "a":
+			kind, pubkey, dTag, ok := parseAddress(tag[1])
+			if !ok || pubkey != deletion.PubKey || kind == 5 {
+				continue
+			}
+			result, err = db.Exec(`
+				DELETE FROM relay_events
+				WHERE kind = ? AND pubkey = ? AND COALESCE(d_tag, '') = ? AND created_at <= ?
+			`, kind, pubkey, dTag, deletion.CreatedAt)
+		default:
 			continue
 		}
+		if err != nil {
+			return deleted, fmt.Errorf("failed to apply deletion: %w", err)
+		}
 
-		events = append(events, &event)
+		n, _ := result.RowsAffected()
+		deleted += n
 	}
 
-	return events
+	// Drop cached copies of anything that was removed
+	s.mu.Lock()
+	for id, event := range s.cache {
+		if event.PubKey == deletion.PubKey && event.Kind != 5 && deletionCovers(deletion, event) {
+			delete(s.cache, id)
+		}
+	}
+	s.mu.Unlock()
+
+	return deleted, nil
 }
 
-// Delete deletes an event
-func (s *EventStorage) Delete(eventID string) error {
+// IsDeleted reports whether a stored deletion request from the event's author covers it
+func (s *EventStorage) IsDeleted(event *Event) bool {
 	db := database.Get()
 
-	_, err := db.Exec("DELETE FROM relay_events WHERE event_id = ?", eventID)
+	rows, err := db.Query(`
+		SELECT raw_json FROM relay_events WHERE kind = 5 AND pubkey = ? AND (tags_json LIKE ? OR tags_json LIKE ?)
+	`, event.PubKey, `%["e","`+event.ID+`"%`, `%["a","`+fmt.Sprintf("%d:%s:", event.Kind, event.PubKey)+`%`)
 	if err != nil {
-		return fmt.Errorf("failed to delete event: %w", err)
+		return false
 	}
+	defer rows.Close()
 
-	s.mu.Lock()
-	delete(s.cache, eventID)
-	s.mu.Unlock()
+	for rows.Next() {
+		var rawJSON string
+		if err := rows.Scan(&rawJSON); err != nil {
+			continue
+		}
+		var deletion Event
+		if err := json.Unmarshal([]byte(rawJSON), &deletion); err != nil {
+			continue
+		}
+		if deletionCovers(&deletion, event) {
+			return true
+		}
+	}
 
-	return nil
+	return false
+}
+
+// deletionCovers reports whether a deletion request refers to an event
+func deletionCovers(deletion, event *Event) bool {
+	for _, tag := range deletion.Tags {
+		if len(tag) < 2 {
+			continue
+		}
+		switch tag[0] {
+		case "e":
+			if tag[1] == event.ID {
+				return true
+			}
+		case "a":
+			kind, pubkey, dTag, ok := parseAddress(tag[1])
+			if ok && kind == event.Kind && pubkey == event.PubKey &&
+				dTag == event.GetTagValue("d") && event.CreatedAt <= deletion.CreatedAt {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// parseAddress splits an event address of the form kind:pubkey:d-tag
+func parseAddress(address string) (int, string, string, bool) {
+	parts := strings.SplitN(address, ":", 3)
+	if len(parts) != 3 {
+		return 0, "", "", false
+	}
+	kind, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, "", "", false
+	}
+	return kind, parts[1], parts[2], true
 }
 
 // DeleteByPubkey deletes all events from a pubkey
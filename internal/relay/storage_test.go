@@ -0,0 +1,36 @@
+package relay
+
+import "testing"
+
+func TestDeletionCovers(t *testing.T) {
+	const author = "aa"
+	deletion := &Event{
+		PubKey:    author,
+		Kind:      5,
+		CreatedAt: 2000,
+		Tags: [][]string{
+			{"e", "event1"},
+			{"a", "30175:aa:decision"},
+		},
+	}
+
+	tests := []struct {
+		name  string
+		event *Event
+		want  bool
+	}{
+		{"referenced by id", &Event{ID: "event1", PubKey: author, Kind: 2003}, true},
+		{"other id", &Event{ID: "event2", PubKey: author, Kind: 2003}, false},
+		{"older address version", &Event{ID: "x", PubKey: author, Kind: 30175, CreatedAt: 1500, Tags: [][]string{{"d", "decision"}}}, true},
+		{"newer address version", &Event{ID: "y", PubKey: author, Kind: 30175, CreatedAt: 2500, Tags: [][]string{{"d", "decision"}}}, false},
+		{"other d tag", &Event{ID: "z", PubKey: author, Kind: 30175, CreatedAt: 1500, Tags: [][]string{{"d", "other"}}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := deletionCovers(deletion, tt.event); got != tt.want {
+				t.Errorf("deletionCovers() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,229 @@
+package relay
+
+import (
+	"encoding/json"
+	"strconv"
+	"sync"
+)
+
+// Limits on the subscriptions of a connection
+const (
+	// maxSubscriptions caps the open subscriptions of a connection
+	maxSubscriptions = 20
+	// maxFilters caps the filters of a subscription
+	maxFilters = 10
+)
+
+// Subscription represents a client subscription
+type Subscription struct {
+	ID      string
+	Filters []Filter
+	Client  *Client
+
+	// pending holds live events matched while stored events are being sent,
+	// so they follow EOSE instead of interleaving with the replay
+	mu        sync.Mutex
+	replaying bool
+	pending   []*Event
+	overflow  bool
+}
+
+// newSubscription creates a subscription that holds back live events until
+// its stored events are sent
+func newSubscription(id string, filters []Filter, client *Client) *Subscription {
+	return &Subscription{
+		ID:        id,
+		Filters:   filters,
+		Client:    client,
+		replaying: true,
+	}
+}
+
+// deliver sends a live event matching the subscription, or holds it back
+// while stored events are being sent
+func (sub *Subscription) deliver(event *Event) {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+
+	if sub.replaying {
+		if len(sub.pending) >= clientQueueSize {
+			sub.overflow = true
+		} else {
+			sub.pending = append(sub.pending, event)
+		}
+		return
+	}
+
+	msg, _ := json.Marshal([]interface{}{"EVENT", sub.ID, event})
+	sub.Client.trySend(msg)
+}
+
+// goLive sends the live events held back during the replay, skipping those
+// already replayed, and delivers live events directly from then on. Returns
+// false if more events arrived than could be held back.
+func (sub *Subscription) goLive(replayed map[string]bool) bool {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+
+	sub.replaying = false
+	pending := sub.pending
+	sub.pending = nil
+	if sub.overflow {
+		return false
+	}
+
+	for _, event := range pending {
+		if replayed[event.ID] {
+			continue
+		}
+		msg, _ := json.Marshal([]interface{}{"EVENT", sub.ID, event})
+		sub.Client.trySend(msg)
+	}
+	return true
+}
+
+// indexedTags are the tag filters subscriptions are indexed by
+var indexedTags = []string{"x", "e"}
+
+// subscriptionIndex holds the open subscriptions of all clients, indexed so a
+// new event is only matched against subscriptions that may want it
+type subscriptionIndex struct {
+	mu sync.RWMutex
+	// byKey holds subscriptions by the index keys of their filters
+	byKey map[string]map[*Subscription]struct{}
+	// unindexed holds subscriptions with a filter that has no indexable constraint
+	unindexed map[*Subscription]struct{}
+	count     int
+}
+
+// newSubscriptionIndex creates an empty subscription index
+func newSubscriptionIndex() *subscriptionIndex {
+	return &subscriptionIndex{
+		byKey:     make(map[string]map[*Subscription]struct{}),
+		unindexed: make(map[*Subscription]struct{}),
+	}
+}
+
+// filterKeys returns the index keys of a filter: the values of its most
+// selective constraint. Every event the filter matches has one of these keys.
+// Returns nil if the filter cannot be indexed.
+func filterKeys(filter Filter) []string {
+	var keys []string
+	for _, name := range indexedTags {
+		for _, value := range filter.Tags[name] {
+			keys = append(keys, tagKey(name, value))
+		}
+	}
+	if len(keys) > 0 {
+		return keys
+	}
+
+	// Author prefixes cannot be looked up
+	for _, author := range filter.Authors {
+		if len(author) != 64 {
+			keys = nil
+			break
+		}
+		keys = append(keys, "a:"+author)
+	}
+	if len(keys) > 0 {
+		return keys
+	}
+
+	for _, kind := range filter.Kinds {
+		keys = append(keys, kindKey(kind))
+	}
+	return keys
+}
+
+// eventKeys returns the index keys an event can be found under
+func eventKeys(event *Event) []string {
+	keys := []string{kindKey(event.Kind), "a:" + event.PubKey}
+	for _, tag := range event.Tags {
+		if len(tag) < 2 {
+			continue
+		}
+		for _, name := range indexedTags {
+			if tag[0] == name {
+				keys = append(keys, tagKey(name, tag[1]))
+			}
+		}
+	}
+	return keys
+}
+
+func kindKey(kind int) string {
+	return "k:" + strconv.Itoa(kind)
+}
+
+func tagKey(name, value string) string {
+	return "t:" + name + ":" + value
+}
+
+// add indexes a subscription
+func (idx *subscriptionIndex) add(sub *Subscription) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	for _, filter := range sub.Filters {
+		keys := filterKeys(filter)
+		if len(keys) == 0 {
+			idx.unindexed[sub] = struct{}{}
+			continue
+		}
+		for _, key := range keys {
+			if idx.byKey[key] == nil {
+				idx.byKey[key] = make(map[*Subscription]struct{})
+			}
+			idx.byKey[key][sub] = struct{}{}
+		}
+	}
+	idx.count++
+}
+
+// remove drops a subscription from the index
+func (idx *subscriptionIndex) remove(sub *Subscription) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	for _, filter := range sub.Filters {
+		for _, key := range filterKeys(filter) {
+			delete(idx.byKey[key], sub)
+			if len(idx.byKey[key]) == 0 {
+				delete(idx.byKey, key)
+			}
+		}
+	}
+	delete(idx.unindexed, sub)
+	idx.count--
+}
+
+// candidates returns the subscriptions that may match an event. Their filters
+// still have to be checked.
+func (idx *subscriptionIndex) candidates(event *Event) []*Subscription {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	seen := make(map[*Subscription]struct{}, len(idx.unindexed))
+	subs := make([]*Subscription, 0, len(idx.unindexed))
+	for sub := range idx.unindexed {
+		seen[sub] = struct{}{}
+		subs = append(subs, sub)
+	}
+	for _, key := range eventKeys(event) {
+		for sub := range idx.byKey[key] {
+			if _, ok := seen[sub]; !ok {
+				seen[sub] = struct{}{}
+				subs = append(subs, sub)
+			}
+		}
+	}
+	return subs
+}
+
+// len returns the number of open subscriptions
+func (idx *subscriptionIndex) len() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return idx.count
+}
@@ -0,0 +1,95 @@
+package relay
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSubscriptionIndex(t *testing.T) {
+	author := strings.Repeat("a", 64)
+	other := strings.Repeat("b", 64)
+	alice, bob := &Client{}, &Client{}
+
+	subs := map[string]*Subscription{
+		"torrents":      {ID: "sub1", Client: alice, Filters: []Filter{{Kinds: []int{2003}}}},
+		"same id":       {ID: "sub1", Client: bob, Filters: []Filter{{Kinds: []int{2003}}}},
+		"author":        {ID: "sub2", Client: alice, Filters: []Filter{{Authors: []string{author}}}},
+		"author prefix": {ID: "sub3", Client: alice, Filters: []Filter{{Authors: []string{"aaaa"}, Kinds: []int{2004}}}},
+		"infohash":      {ID: "sub4", Client: bob, Filters: []Filter{{Kinds: []int{2004}, Tags: map[string][]string{"x": {"hash"}}}}},
+		"everything":    {ID: "sub5", Client: bob, Filters: []Filter{{Since: 1}}},
+		"other author":  {ID: "sub6", Client: bob, Filters: []Filter{{Authors: []string{other}}, {Kinds: []int{1}}}},
+	}
+
+	idx := newSubscriptionIndex()
+	for _, sub := range subs {
+		idx.add(sub)
+	}
+
+	events := []*Event{
+		{PubKey: author, Kind: 2003, CreatedAt: 10},
+		{PubKey: author, Kind: 2004, CreatedAt: 10, Tags: [][]string{{"x", "hash"}}},
+		{PubKey: other, Kind: 2004, CreatedAt: 10, Tags: [][]string{{"x", "other"}}},
+		{PubKey: other, Kind: 1, CreatedAt: 10},
+	}
+
+	// Every matching subscription is a candidate
+	for _, event := range events {
+		candidates := make(map[*Subscription]bool)
+		for _, sub := range idx.candidates(event) {
+			candidates[sub] = true
+		}
+		for name, sub := range subs {
+			if matchesFilters(event, sub.Filters) && !candidates[sub] {
+				t.Errorf("subscription %q matches %+v but is not a candidate", name, event)
+			}
+		}
+	}
+
+	// Indexed subscriptions for other kinds and authors are skipped
+	for _, sub := range idx.candidates(events[0]) {
+		if sub == subs["infohash"] || sub == subs["other author"] {
+			t.Errorf("subscription %s should not be a candidate for %+v", sub.ID, events[0])
+		}
+	}
+
+	// Removing one client's subscription leaves the other's with the same ID
+	idx.remove(subs["torrents"])
+	found := false
+	for _, sub := range idx.candidates(events[0]) {
+		if sub == subs["torrents"] {
+			t.Error("a removed subscription should not be a candidate")
+		}
+		if sub == subs["same id"] {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("another client's subscription with the same ID should remain")
+	}
+	if got := idx.len(); got != len(subs)-1 {
+		t.Errorf("len() = %d, want %d", got, len(subs)-1)
+	}
+}
+
+func TestFilterKeys(t *testing.T) {
+	author := strings.Repeat("a", 64)
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []string
+	}{
+		{"tags first", Filter{Kinds: []int{2003}, Authors: []string{author}, Tags: map[string][]string{"e": {"id"}}}, []string{"t:e:id"}},
+		{"authors before kinds", Filter{Kinds: []int{2003}, Authors: []string{author}}, []string{"a:" + author}},
+		{"author prefix falls back to kinds", Filter{Kinds: []int{2003}, Authors: []string{author, "aa"}}, []string{"k:2003"}},
+		{"unindexed tag", Filter{Tags: map[string][]string{"t": {"movies"}}}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := filterKeys(tt.filter); strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("filterKeys() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
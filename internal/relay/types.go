@@ -210,6 +210,11 @@ type RelayInfo struct {
 	Software      string   `json:"software,omitempty"`
 	Version       string   `json:"version,omitempty"`
 	Limitation    *Limits  `json:"limitation,omitempty"`
+
+	// Lighthouse extensions
+	Mode            string        `json:"mode,omitempty"`
+	AcceptedKinds   []interface{} `json:"accepted_kinds,omitempty"`
+	RequireCuration bool          `json:"require_curation"`
 }
 
 // Limits represents relay limitations
@@ -217,11 +222,14 @@ type Limits struct {
 	MaxMessageLength   int   `json:"max_message_length,omitempty"`
 	MaxSubscriptions   int   `json:"max_subscriptions,omitempty"`
 	MaxFilters         int   `json:"max_filters,omitempty"`
+	MaxLimit           int   `json:"max_limit,omitempty"`
+	DefaultLimit       int   `json:"default_limit,omitempty"`
 	MaxEventTags       int   `json:"max_event_tags,omitempty"`
 	MaxContentLength   int   `json:"max_content_length,omitempty"`
 	MinPowDifficulty   int   `json:"min_pow_difficulty,omitempty"`
 	AuthRequired       bool  `json:"auth_required,omitempty"`
 	PaymentRequired    bool  `json:"payment_required,omitempty"`
+	RestrictedWrites   bool  `json:"restricted_writes,omitempty"`
 	CreatedAtLowerLimit int64 `json:"created_at_lower_limit,omitempty"`
 	CreatedAtUpperLimit int64 `json:"created_at_upper_limit,omitempty"`
 }
@@ -105,3 +105,27 @@ func TestNormalizeImdbID(t *testing.T) {
 		})
 	}
 }
+
+func TestParseDailyEpisode(t *testing.T) {
+	tests := []struct {
+		season   int
+		ep       string
+		expected string
+		ok       bool
+	}{
+		{2024, "05/01", "2024-05-01", true},
+		{2024, "5/1", "2024-05-01", true},
+		{2024, "13/01", "", false},
+		{1, "05/01", "", false},
+		{2024, "3", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ep, func(t *testing.T) {
+			result, ok := ParseDailyEpisode(tt.season, tt.ep)
+			if result != tt.expected || ok != tt.ok {
+				t.Errorf("ParseDailyEpisode(%d, %q) = %q, %v, want %q, %v", tt.season, tt.ep, result, ok, tt.expected, tt.ok)
+			}
+		})
+	}
+}
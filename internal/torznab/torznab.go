@@ -2,6 +2,7 @@ package torznab
 
 import (
 	"database/sql"
+	"fmt"
 	"strconv"
 	"strings"
 	"time"
@@ -23,7 +24,7 @@ func (s *Service) Search(params SearchParams) ([]SearchResult, int, error) {
 
 	query := `
 		SELECT t.id, t.info_hash, t.name, t.size, t.category, t.seeders, t.leechers,
-			   t.magnet_uri, t.title, t.year, t.tmdb_id, t.imdb_id, t.poster_url,
+			   t.magnet_uri, t.title, t.year, t.tmdb_id, t.imdb_id, t.tvdb_id, t.poster_url,
 			   t.overview, t.first_seen_at
 		FROM torrents t
 	`
@@ -36,7 +37,7 @@ func (s *Service) Search(params SearchParams) ([]SearchResult, int, error) {
 	if params.Query != "" {
 		query = `
 			SELECT t.id, t.info_hash, t.name, t.size, t.category, t.seeders, t.leechers,
-				   t.magnet_uri, t.title, t.year, t.tmdb_id, t.imdb_id, t.poster_url,
+				   t.magnet_uri, t.title, t.year, t.tmdb_id, t.imdb_id, t.tvdb_id, t.poster_url,
 				   t.overview, t.first_seen_at
 			FROM torrents t
 			JOIN torrents_fts fts ON t.id = fts.rowid
@@ -50,6 +51,25 @@ func (s *Service) Search(params SearchParams) ([]SearchResult, int, error) {
 		args = append(args, params.Query)
 	}
 
+	// Trust filter: only torrents uploaded by someone in the web of trust
+	trustedUploads := params.TrustedUploads
+	if trustedUploads == "" {
+		trustedUploads = "0"
+	}
+	conditions = append(conditions, `EXISTS (
+		SELECT 1 FROM torrent_uploads tu
+		WHERE tu.torrent_id = t.id
+		AND `+trustedUploads+`
+	)`)
+	args = append(args, params.TrustedUploadsArgs...)
+
+	// Hide torrents rejected by curators
+	if params.ShowBorderline {
+		conditions = append(conditions, "(t.curation_status IS NULL OR t.curation_status != 'rejected')")
+	} else {
+		conditions = append(conditions, "(t.curation_status IS NULL OR t.curation_status NOT IN ('rejected', 'borderline'))")
+	}
+
 	// Category filter
 	if len(params.Categories) > 0 {
 		catConditions := make([]string, len(params.Categories))
@@ -78,14 +98,25 @@ func (s *Service) Search(params SearchParams) ([]SearchResult, int, error) {
 		args = append(args, params.TmdbID)
 	}
 
+	// TVDB ID filter
+	if params.TvdbID > 0 {
+		conditions = append(conditions, "t.tvdb_id = ?")
+		args = append(args, params.TvdbID)
+	}
+
 	// Season/Episode filter (for TV)
-	if params.Season > 0 && params.Query != "" {
-		// Append season to search query
-		seasonStr := strconv.Itoa(params.Season)
-		if params.Season < 10 {
-			seasonStr = "0" + seasonStr
-		}
-		// This is a simplification - in production you'd want more sophisticated matching
+	if params.AirDate != "" {
+		// Daily shows are matched by air date
+		conditions = append(conditions, "t.air_date = ?")
+		args = append(args, params.AirDate)
+	} else if params.Season > 0 && params.Episode > 0 {
+		// Exact episode, or a multi-episode release that contains it
+		conditions = append(conditions, "t.season = ? AND t.episode <= ? AND COALESCE(t.episode_end, t.episode) >= ?")
+		args = append(args, params.Season, params.Episode, params.Episode)
+	} else if params.Season > 0 {
+		// Season packs, including multi-season packs covering the season
+		conditions = append(conditions, "t.episode IS NULL AND t.air_date IS NULL AND t.season <= ? AND COALESCE(t.season_end, t.season) >= ?")
+		args = append(args, params.Season, params.Season)
 	}
 
 	// Build WHERE clause
@@ -112,17 +143,18 @@ func (s *Service) Search(params SearchParams) ([]SearchResult, int, error) {
 	defer rows.Close()
 
 	var results []SearchResult
+	var ids []int64
 	for rows.Next() {
 		var r SearchResult
 		var id int64
 		var name string
 		var size, category, seeders, leechers sql.NullInt64
 		var title, imdbID, posterURL, overview sql.NullString
-		var year, tmdbID sql.NullInt64
+		var year, tmdbID, tvdbID sql.NullInt64
 		var firstSeenAt string
 
 		err := rows.Scan(&id, &r.InfoHash, &name, &size, &category, &seeders, &leechers,
-			&r.MagnetURI, &title, &year, &tmdbID, &imdbID, &posterURL, &overview, &firstSeenAt)
+			&r.MagnetURI, &title, &year, &tmdbID, &imdbID, &tvdbID, &posterURL, &overview, &firstSeenAt)
 		if err != nil {
 			continue
 		}
@@ -141,6 +173,7 @@ func (s *Service) Search(params SearchParams) ([]SearchResult, int, error) {
 		r.Year = int(year.Int64)
 		r.TmdbID = int(tmdbID.Int64)
 		r.ImdbID = imdbID.String
+		r.TvdbID = int(tvdbID.Int64)
 		r.PosterURL = posterURL.String
 		r.Description = overview.String
 
@@ -150,6 +183,11 @@ func (s *Service) Search(params SearchParams) ([]SearchResult, int, error) {
 		}
 
 		results = append(results, r)
+		ids = append(ids, id)
+	}
+
+	if err := loadTags(db, ids, results); err != nil {
+		return nil, 0, err
 	}
 
 	// Get total count
@@ -160,16 +198,64 @@ func (s *Service) Search(params SearchParams) ([]SearchResult, int, error) {
 	return results, total, nil
 }
 
+// loadTags fills in the content tags of search results
+func loadTags(db *sql.DB, ids []int64, results []SearchResult) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	index := make(map[int64]int, len(ids))
+	placeholders := make([]string, len(ids))
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		index[id] = i
+		placeholders[i] = "?"
+		args[i] = id
+	}
+
+	rows, err := db.Query(`
+		SELECT torrent_id, tag FROM torrent_tags
+		WHERE torrent_id IN (`+strings.Join(placeholders, ",")+`)
+		ORDER BY upload_count DESC, tag ASC
+	`, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var torrentID int64
+		var tag string
+		if err := rows.Scan(&torrentID, &tag); err != nil {
+			continue
+		}
+		if i, ok := index[torrentID]; ok {
+			results[i].Tags = append(results[i].Tags, tag)
+		}
+	}
+
+	return rows.Err()
+}
+
 // SearchParams represents Torznab search parameters
 type SearchParams struct {
 	Query      string
 	Categories []int
 	ImdbID     string
 	TmdbID     int
+	TvdbID     int
 	Season     int
 	Episode    int
+	AirDate    string // YYYY-MM-DD, for daily shows
 	Limit      int
 	Offset     int
+
+	// TrustedUploads restricts results to torrents with an upload matching this
+	// condition on torrent_uploads aliased tu. No results are returned without one.
+	TrustedUploads     string
+	TrustedUploadsArgs []interface{}
+	// ShowBorderline includes torrents rejected only for probabilistic reasons
+	ShowBorderline bool
 }
 
 // ParseCategories parses a comma-separated category string
@@ -203,3 +289,23 @@ func NormalizeImdbID(id string) string {
 	// Add "tt" prefix back
 	return "tt" + id
 }
+
+// ParseDailyEpisode converts a daily show episode ("MM/DD") and year season
+// into an air date (YYYY-MM-DD). Returns false if ep is not a daily episode.
+func ParseDailyEpisode(season int, ep string) (string, bool) {
+	parts := strings.Split(ep, "/")
+	if len(parts) != 2 || season < 1900 {
+		return "", false
+	}
+
+	month, err := strconv.Atoi(parts[0])
+	if err != nil || month < 1 || month > 12 {
+		return "", false
+	}
+	day, err := strconv.Atoi(parts[1])
+	if err != nil || day < 1 || day > 31 {
+		return "", false
+	}
+
+	return fmt.Sprintf("%04d-%02d-%02d", season, month, day), true
+}
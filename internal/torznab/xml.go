@@ -157,7 +157,7 @@ func NewCaps(baseURL string) *Caps {
 			},
 			TVSearch: CapsSearch{
 				Available:       "yes",
-				SupportedParams: "q,season,ep",
+				SupportedParams: "q,season,ep,imdbid,tvdbid",
 			},
 			MovieSearch: CapsSearch{
 				Available:       "yes",
@@ -218,12 +218,18 @@ func NewSearchResponse(baseURL string, results []SearchResult, offset, total int
 		if r.TmdbID > 0 {
 			items[i].Attributes = append(items[i].Attributes, Attr{Name: "tmdbid", Value: fmt.Sprintf("%d", r.TmdbID)})
 		}
+		if r.TvdbID > 0 {
+			items[i].Attributes = append(items[i].Attributes, Attr{Name: "tvdbid", Value: fmt.Sprintf("%d", r.TvdbID)})
+		}
 		if r.Year > 0 {
 			items[i].Attributes = append(items[i].Attributes, Attr{Name: "year", Value: fmt.Sprintf("%d", r.Year)})
 		}
 		if r.PosterURL != "" {
 			items[i].Attributes = append(items[i].Attributes, Attr{Name: "coverurl", Value: r.PosterURL})
 		}
+		for _, tag := range r.Tags {
+			items[i].Attributes = append(items[i].Attributes, Attr{Name: "tag", Value: tag})
+		}
 	}
 
 	return &RSS{
@@ -259,8 +265,10 @@ type SearchResult struct {
 	Description string
 	ImdbID      string
 	TmdbID      int
+	TvdbID      int
 	Year        int
 	PosterURL   string
+	Tags        []string
 }
 
 // ErrorResponse creates an error response
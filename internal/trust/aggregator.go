@@ -17,6 +17,7 @@ type Aggregator struct {
 	policy        *TrustPolicy
 	aggPolicy     *AggregationPolicy
 	policyStorage *PolicyStorage
+	localCurator  string // pubkey whose decisions are trusted without policy approval
 }
 
 // NewAggregator creates a new decision aggregator
@@ -37,6 +38,11 @@ func (a *Aggregator) SetAggregationPolicy(policy *AggregationPolicy) {
 	a.aggPolicy = policy
 }
 
+// SetLocalCurator marks a curator pubkey (usually this node's own) as implicitly approved
+func (a *Aggregator) SetLocalCurator(pubkey string) {
+	a.localCurator = pubkey
+}
+
 // Aggregate aggregates multiple curator decisions for an infohash
 func (a *Aggregator) Aggregate(infohash string, decisions []*decision.VerificationDecision) *decision.AggregatedDecision {
 	if len(decisions) == 0 {
@@ -113,14 +119,16 @@ func (a *Aggregator) filterApprovedCurators(decisions []*decision.VerificationDe
 
 	for _, d := range decisions {
 		// Check if curator is approved
-		approved, err := a.policyStorage.IsCuratorApproved(d.CuratorPubkey)
-		if err != nil {
-			log.Warn().Err(err).Str("curator", d.CuratorPubkey).Msg("Failed to check curator approval")
-			continue
-		}
-		if !approved {
-			log.Debug().Str("curator", d.CuratorPubkey).Msg("Skipping decision from unapproved curator")
-			continue
+		if a.localCurator == "" || d.CuratorPubkey != a.localCurator {
+			approved, err := a.policyStorage.IsCuratorApproved(d.CuratorPubkey)
+			if err != nil {
+				log.Warn().Err(err).Str("curator", d.CuratorPubkey).Msg("Failed to check curator approval")
+				continue
+			}
+			if !approved {
+				log.Debug().Str("curator", d.CuratorPubkey).Msg("Skipping decision from unapproved curator")
+				continue
+			}
 		}
 
 		// Optionally verify signature
@@ -1,8 +1,11 @@
 package trust
 
 import (
+	"database/sql"
+
 	"github.com/gmonarque/lighthouse/internal/database"
 	"github.com/gmonarque/lighthouse/internal/models"
+	"github.com/gmonarque/lighthouse/internal/nostr"
 	"github.com/rs/zerolog/log"
 )
 
@@ -14,59 +17,68 @@ func NewBlacklist() *Blacklist {
 	return &Blacklist{}
 }
 
-// Add adds an npub to the blacklist and removes all their content
-func (b *Blacklist) Add(npub, reason string) error {
+// Add adds an npub to the blacklist and removes their uploads within the
+// window. An entry without time bounds also takes the npub off the whitelist.
+// Returns the number of torrents deleted.
+func (b *Blacklist) Add(npub, reason string, window Window) (int64, error) {
+	if err := window.Validate(); err != nil {
+		return 0, err
+	}
+
+	// Uploads are stored by hex pubkey
+	pubkey, err := nostr.NpubToHex(npub)
+	if err != nil {
+		// Might already be hex
+		pubkey = npub
+	}
+
 	db := database.Get()
 
 	// Begin transaction
 	tx, err := db.Begin()
 	if err != nil {
-		return err
+		return 0, err
 	}
 	defer tx.Rollback()
 
 	// Add to blacklist
 	_, err = tx.Exec(`
-		INSERT INTO trust_blacklist (npub, reason)
-		VALUES (?, ?)
-		ON CONFLICT(npub) DO UPDATE SET reason = excluded.reason
-	`, npub, reason)
-	if err != nil {
-		return err
-	}
-
-	// Remove from whitelist if present
-	tx.Exec(`DELETE FROM trust_whitelist WHERE npub = ?`, npub)
-
-	// Delete torrents that only have this uploader
-	result, err := tx.Exec(`
-		DELETE FROM torrents
-		WHERE id IN (
-			SELECT torrent_id FROM torrent_uploads
-			GROUP BY torrent_id
-			HAVING COUNT(DISTINCT uploader_npub) = 1
-			AND MAX(uploader_npub) = ?
-		)
-	`, npub)
+		INSERT INTO trust_blacklist (npub, reason, effective_from, effective_until)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(npub) DO UPDATE SET
+			reason = excluded.reason,
+			effective_from = excluded.effective_from,
+			effective_until = excluded.effective_until,
+			source = 'manual',
+			list_id = NULL
+	`, npub, reason, nullableTime(window.From), nullableTime(window.Until))
 	if err != nil {
-		log.Error().Err(err).Msg("Failed to delete torrents from blacklisted user")
-	} else {
-		deleted, _ := result.RowsAffected()
-		if deleted > 0 {
-			log.Info().Int64("count", deleted).Str("npub", npub).Msg("Deleted torrents from blacklisted user")
+		return 0, err
+	}
+
+	// Remove from whitelist if present; a compromised key stays trusted for
+	// the events outside the window
+	if !window.Bounded() {
+		if _, err := tx.Exec(`DELETE FROM trust_whitelist WHERE npub = ?`, npub); err != nil {
+			return 0, err
 		}
 	}
 
-	// Remove upload records
-	tx.Exec(`DELETE FROM torrent_uploads WHERE uploader_npub = ?`, npub)
+	deleted, err := purgeUploads(tx, pubkey, window)
+	if err != nil {
+		return 0, err
+	}
 
 	// Commit transaction
 	if err := tx.Commit(); err != nil {
-		return err
+		return 0, err
 	}
 
+	if deleted > 0 {
+		log.Info().Int64("count", deleted).Str("npub", npub).Msg("Deleted torrents from blacklisted user")
+	}
 	database.LogActivity(models.ActivityBlacklistAdd, npub)
-	return nil
+	return deleted, nil
 }
 
 // Remove removes an npub from the blacklist
@@ -92,7 +104,7 @@ func (b *Blacklist) Contains(npub string) bool {
 func (b *Blacklist) GetAll() ([]models.TrustBlacklistEntry, error) {
 	db := database.Get()
 	rows, err := db.Query(`
-		SELECT id, npub, reason, added_at
+		SELECT id, npub, reason, added_at, COALESCE(effective_from, 0), COALESCE(effective_until, 0)
 		FROM trust_blacklist
 		ORDER BY added_at DESC
 	`)
@@ -105,7 +117,8 @@ func (b *Blacklist) GetAll() ([]models.TrustBlacklistEntry, error) {
 	for rows.Next() {
 		var entry models.TrustBlacklistEntry
 		var reason *string
-		if err := rows.Scan(&entry.ID, &entry.Npub, &reason, &entry.AddedAt); err != nil {
+		if err := rows.Scan(&entry.ID, &entry.Npub, &reason, &entry.AddedAt,
+			&entry.EffectiveFrom, &entry.EffectiveUntil); err != nil {
 			continue
 		}
 		if reason != nil {
@@ -124,28 +137,74 @@ func (b *Blacklist) Count() int {
 	return count
 }
 
-// PurgeContent removes all content from a blacklisted user
-func (b *Blacklist) PurgeContent(npub string) (int64, error) {
-	db := database.Get()
+// PurgeContent removes all content from a blacklisted user, by hex pubkey
+func (b *Blacklist) PurgeContent(pubkey string) (int64, error) {
+	return b.PurgeWindow(pubkey, Window{})
+}
+
+// PurgeWindow removes the uploads of a hex pubkey whose event was created
+// within the window. Torrents left without uploads are deleted. Returns the
+// number of torrents deleted.
+func (b *Blacklist) PurgeWindow(pubkey string, window Window) (int64, error) {
+	tx, err := database.Get().Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
 
-	// Delete torrents that only have this uploader
-	result, err := db.Exec(`
-		DELETE FROM torrents
-		WHERE id IN (
-			SELECT torrent_id FROM torrent_uploads
-			GROUP BY torrent_id
-			HAVING COUNT(DISTINCT uploader_npub) = 1
-			AND MAX(uploader_npub) = ?
-		)
-	`, npub)
+	deleted, err := purgeUploads(tx, pubkey, window)
 	if err != nil {
 		return 0, err
 	}
+	return deleted, tx.Commit()
+}
 
-	deleted, _ := result.RowsAffected()
+// purgeUploads removes the uploads of a hex pubkey within the window, deletes
+// the torrents they were the only uploads of and recounts the others
+func purgeUploads(tx *sql.Tx, pubkey string, window Window) (int64, error) {
+	inside, args := window.UploadCondition("torrent_uploads")
+	purged := "uploader_npub = ? AND " + inside
+	purgedArgs := append([]interface{}{pubkey}, args...)
 
-	// Remove upload records
-	db.Exec(`DELETE FROM torrent_uploads WHERE uploader_npub = ?`, npub)
+	rows, err := tx.Query("SELECT DISTINCT torrent_id FROM torrent_uploads WHERE "+purged, purgedArgs...)
+	if err != nil {
+		return 0, err
+	}
+	var affected []int64
+	for rows.Next() {
+		var torrentID int64
+		if rows.Scan(&torrentID) == nil {
+			affected = append(affected, torrentID)
+		}
+	}
+	rows.Close()
+
+	if _, err := tx.Exec("DELETE FROM torrent_uploads WHERE "+purged, purgedArgs...); err != nil {
+		return 0, err
+	}
+
+	// Delete torrents left without uploads, recount the others
+	var deleted int64
+	for _, torrentID := range affected {
+		result, err := tx.Exec(`
+			DELETE FROM torrents
+			WHERE id = ? AND NOT EXISTS (SELECT 1 FROM torrent_uploads WHERE torrent_id = ?)
+		`, torrentID, torrentID)
+		if err != nil {
+			return deleted, err
+		}
+		if n, _ := result.RowsAffected(); n > 0 {
+			deleted += n
+			continue
+		}
+
+		if _, err := tx.Exec(`
+			UPDATE torrents SET upload_count = (SELECT COUNT(DISTINCT uploader_npub) FROM torrent_uploads WHERE torrent_id = ?)
+			WHERE id = ?
+		`, torrentID, torrentID); err != nil {
+			return deleted, err
+		}
+	}
 
 	return deleted, nil
 }
@@ -0,0 +1,184 @@
+package trust
+
+import (
+	"database/sql"
+
+	"github.com/gmonarque/lighthouse/internal/config"
+	"github.com/gmonarque/lighthouse/internal/database"
+	"github.com/gmonarque/lighthouse/internal/nostr"
+)
+
+// Reasons an upload is or is not trusted
+const (
+	UploadTrusted       = "trusted"
+	UploadBlacklisted   = "blacklisted"
+	UploadOutsideWindow = "outside_whitelist_window"
+	UploadUntrusted     = "untrusted"
+)
+
+// Judge returns why an event of pubkey created at createdAt is trusted or not
+func (p *UploaderPolicy) Judge(pubkey string, createdAt int64) string {
+	switch {
+	case p.Blocks(pubkey, createdAt):
+		return UploadBlacklisted
+	case !p.Trusted[pubkey]:
+		return UploadUntrusted
+	}
+	if w, ok := p.Whitelisted[pubkey]; ok && !w.Contains(createdAt) {
+		return UploadOutsideWindow
+	}
+	return UploadTrusted
+}
+
+// Explanation lays out why a pubkey is trusted or not
+type Explanation struct {
+	Npub   string `json:"npub"`
+	Pubkey string `json:"pubkey"`
+	// Trusted reports whether the pubkey is a trusted uploader; entries with
+	// time bounds limit which of its events are
+	Trusted bool `json:"trusted"`
+	// Identity is set for the node's own identity, the root of the follow graph
+	Identity      bool        `json:"identity"`
+	Whitelist     *EntryState `json:"whitelist,omitempty"`
+	Blacklist     *EntryState `json:"blacklist,omitempty"`
+	Paths         []TrustPath `json:"paths"`
+	Score         *Score      `json:"score,omitempty"`
+	MeetsMinScore bool        `json:"meets_min_score"`
+	MinScore      float64     `json:"min_score"`
+	Depth         int         `json:"depth"`
+}
+
+// EntryState describes a whitelist or blacklist entry
+type EntryState struct {
+	Source string `json:"source"`
+	ListID int64  `json:"list_id,omitempty"`
+	Alias  string `json:"alias,omitempty"`
+	Notes  string `json:"notes,omitempty"`
+	Reason string `json:"reason,omitempty"`
+	Window
+	// InForce reports whether the entry applies to events created now
+	InForce bool `json:"in_force"`
+}
+
+// TrustPath is a chain of follows from a trust root to a pubkey
+type TrustPath struct {
+	Root string `json:"root"`
+	// RootType is "identity" for our own identity or "whitelist" for a whitelisted npub
+	RootType string   `json:"root_type"`
+	Path     []string `json:"path"` // npubs from the root to the pubkey
+	Depth    int      `json:"depth"`
+	// SourceEventID is the contact list the last follow was read from
+	SourceEventID string `json:"source_event_id,omitempty"`
+}
+
+// Explain gathers the whitelist and blacklist entries, follow paths and
+// propagated score that decide whether an npub is trusted
+func (w *WebOfTrust) Explain(npub string) (*Explanation, error) {
+	pubkey, err := nostr.NpubToHex(npub)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := config.Get()
+	db := database.Get()
+
+	policy, err := w.GetUploaderPolicy()
+	if err != nil {
+		return nil, err
+	}
+
+	e := &Explanation{
+		Npub:     npub,
+		Pubkey:   pubkey,
+		Trusted:  policy.Trusted[pubkey],
+		Identity: npub == cfg.Nostr.Identity.Npub,
+		Paths:    []TrustPath{},
+		MinScore: cfg.Trust.MinScore,
+		Depth:    cfg.Trust.Depth,
+	}
+
+	if e.Whitelist, err = queryEntryState(db, `
+		SELECT COALESCE(source, 'manual'), list_id, COALESCE(alias, ''), COALESCE(notes, ''), '',
+			COALESCE(effective_from, 0), COALESCE(effective_until, 0)
+		FROM trust_whitelist WHERE npub = ?`, npub); err != nil {
+		return nil, err
+	}
+	if e.Blacklist, err = queryEntryState(db, `
+		SELECT COALESCE(source, 'manual'), list_id, '', '', COALESCE(reason, ''),
+			COALESCE(effective_from, 0), COALESCE(effective_until, 0)
+		FROM trust_blacklist WHERE npub = ?`, npub); err != nil {
+		return nil, err
+	}
+
+	if cfg.Trust.Depth > 0 {
+		if e.Paths, err = queryTrustPaths(db, npub); err != nil {
+			return nil, err
+		}
+	}
+
+	if e.Score, err = w.GetScore(npub); err != nil {
+		return nil, err
+	}
+	e.MeetsMinScore = e.Score == nil || e.Score.MeetsMinScore
+
+	return e, nil
+}
+
+// queryEntryState reads the whitelist or blacklist entry selected by query, or nil if there is none
+func queryEntryState(db *sql.DB, query, npub string) (*EntryState, error) {
+	var entry EntryState
+	var listID sql.NullInt64
+	err := db.QueryRow(query, npub).Scan(&entry.Source, &listID, &entry.Alias, &entry.Notes, &entry.Reason,
+		&entry.From, &entry.Until)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	entry.ListID = listID.Int64
+	entry.InForce = entry.Window.Contains(now())
+	return &entry, nil
+}
+
+// queryTrustPaths returns the follow paths within the trust depth from a
+// trust root to an npub, shortest first
+func queryTrustPaths(db *sql.DB, npub string) ([]TrustPath, error) {
+	cfg := config.Get()
+	roots, args := followRoots()
+
+	rows, err := db.Query(`
+		SELECT follower_npub, COALESCE(root_npub, follower_npub), depth, COALESCE(source_event_id, '')
+		FROM trust_follows
+		WHERE followed_npub = ? AND depth <= ? AND `+roots+`
+		ORDER BY depth, follower_npub`,
+		append([]interface{}{npub, cfg.Trust.Depth}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	paths := []TrustPath{}
+	for rows.Next() {
+		var follower string
+		var p TrustPath
+		if err := rows.Scan(&follower, &p.Root, &p.Depth, &p.SourceEventID); err != nil {
+			continue
+		}
+
+		p.RootType = "whitelist"
+		if p.Root == cfg.Nostr.Identity.Npub {
+			p.RootType = "identity"
+		}
+
+		// At depth 2 the follower was reached through a follow of the root
+		p.Path = []string{p.Root}
+		if follower != p.Root {
+			p.Path = append(p.Path, follower)
+		}
+		p.Path = append(p.Path, npub)
+
+		paths = append(paths, p)
+	}
+	return paths, rows.Err()
+}
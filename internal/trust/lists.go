@@ -0,0 +1,350 @@
+package trust
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/gmonarque/lighthouse/internal/database"
+	"github.com/gmonarque/lighthouse/internal/nostr"
+	"github.com/rs/zerolog/log"
+)
+
+// Entry sources of the whitelist and blacklist
+const (
+	SourceManual = "manual"
+	SourceList   = "list"
+)
+
+// ListSubscription is a NIP-51 list synced into the whitelist or blacklist.
+// Mute lists feed the blacklist, follow sets the whitelist.
+type ListSubscription struct {
+	ID             int64  `json:"id"`
+	OwnerNpub      string `json:"owner_npub"`
+	Kind           int    `json:"kind"`
+	DTag           string `json:"d_tag,omitempty"`
+	Target         string `json:"target"`
+	EventID        string `json:"event_id,omitempty"`
+	EventCreatedAt int64  `json:"event_created_at,omitempty"`
+	EntryCount     int    `json:"entry_count"`
+	SyncedAt       string `json:"synced_at,omitempty"`
+	CreatedAt      string `json:"created_at"`
+}
+
+// ListSyncResult reports the changes a list update made to the whitelist and blacklist
+type ListSyncResult struct {
+	Applied bool     `json:"applied"`
+	Added   []string `json:"added"`
+	Removed []string `json:"removed"`
+}
+
+// ListSubscriptions manages NIP-51 lists synced into the whitelist and blacklist
+type ListSubscriptions struct{}
+
+// NewListSubscriptions creates a new ListSubscriptions manager
+func NewListSubscriptions() *ListSubscriptions {
+	return &ListSubscriptions{}
+}
+
+// ListTarget returns the trust list a NIP-51 list kind is synced into
+func ListTarget(kind int) (string, bool) {
+	switch kind {
+	case nostr.KindMuteList, nostr.KindMuteSet:
+		return "blacklist", true
+	case nostr.KindFollowSet:
+		return "whitelist", true
+	}
+	return "", false
+}
+
+// listLabels name the kind of list an imported entry came from
+var listLabels = map[int]string{
+	nostr.KindMuteList:  "Mute list of ",
+	nostr.KindMuteSet:   "Mute set of ",
+	nostr.KindFollowSet: "Follow set of ",
+}
+
+// Add subscribes to a NIP-51 list. Sets are identified by their d tag, mute
+// lists have none.
+func (l *ListSubscriptions) Add(ownerNpub string, kind int, dTag string) (*ListSubscription, error) {
+	if _, ok := ListTarget(kind); !ok {
+		return nil, errors.New("kind must be 10000 (mute list), 30000 (follow set) or 30007 (mute set)")
+	}
+	if kind == nostr.KindMuteList {
+		dTag = ""
+	} else if dTag == "" {
+		return nil, errors.New("sets need a d tag")
+	}
+
+	_, err := database.Get().Exec(`
+		INSERT INTO trust_lists (owner_npub, kind, d_tag)
+		VALUES (?, ?, ?)
+		ON CONFLICT(owner_npub, kind, d_tag) DO NOTHING
+	`, ownerNpub, kind, dTag)
+	if err != nil {
+		return nil, err
+	}
+
+	return l.Find(ownerNpub, kind, dTag)
+}
+
+// Remove unsubscribes from a list and drops the entries it imported.
+// Returns false if the list does not exist.
+func (l *ListSubscriptions) Remove(id int64) (bool, error) {
+	db := database.Get()
+
+	result, err := db.Exec("DELETE FROM trust_lists WHERE id = ?", id)
+	if err != nil {
+		return false, err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return false, nil
+	}
+
+	// The list's entries are removed by cascade
+	if _, err := syncListEntries(db); err != nil {
+		return true, err
+	}
+	return true, nil
+}
+
+// Find returns the subscription to a list, or nil if there is none
+func (l *ListSubscriptions) Find(ownerNpub string, kind int, dTag string) (*ListSubscription, error) {
+	lists, err := queryLists(" WHERE owner_npub = ? AND kind = ? AND d_tag = ?", ownerNpub, kind, dTag)
+	if err != nil || len(lists) == 0 {
+		return nil, err
+	}
+	return &lists[0], nil
+}
+
+// GetAll returns all list subscriptions
+func (l *ListSubscriptions) GetAll() ([]ListSubscription, error) {
+	return queryLists(" ORDER BY created_at DESC")
+}
+
+// queryLists returns the list subscriptions matching a WHERE/ORDER clause
+func queryLists(clause string, args ...interface{}) ([]ListSubscription, error) {
+	rows, err := database.Get().Query(`
+		SELECT id, owner_npub, kind, d_tag, event_id, event_created_at, entry_count, synced_at, created_at
+		FROM trust_lists`+clause, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lists := make([]ListSubscription, 0)
+	for rows.Next() {
+		var list ListSubscription
+		var eventID, syncedAt sql.NullString
+		var eventCreatedAt sql.NullInt64
+		if err := rows.Scan(&list.ID, &list.OwnerNpub, &list.Kind, &list.DTag, &eventID, &eventCreatedAt,
+			&list.EntryCount, &syncedAt, &list.CreatedAt); err != nil {
+			return nil, err
+		}
+		list.EventID = eventID.String
+		list.EventCreatedAt = eventCreatedAt.Int64
+		list.SyncedAt = syncedAt.String
+		list.Target, _ = ListTarget(list.Kind)
+		lists = append(lists, list)
+	}
+	return lists, rows.Err()
+}
+
+// Apply replaces the entries of a list with those of a list event and syncs
+// them into the whitelist or blacklist. Events not newer than the one already
+// applied are ignored. Npubs newly blacklisted lose their torrents.
+func (l *ListSubscriptions) Apply(list *ListSubscription, eventID string, createdAt int64, npubs []string) (ListSyncResult, error) {
+	var result ListSyncResult
+	if list.EventID != "" && createdAt <= list.EventCreatedAt {
+		return result, nil
+	}
+
+	db := database.Get()
+	tx, err := db.Begin()
+	if err != nil {
+		return result, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM trust_list_entries WHERE list_id = ?", list.ID); err != nil {
+		return result, err
+	}
+	for _, npub := range npubs {
+		if _, err := tx.Exec("INSERT OR IGNORE INTO trust_list_entries (list_id, npub) VALUES (?, ?)", list.ID, npub); err != nil {
+			return result, err
+		}
+	}
+	_, err = tx.Exec(`
+		UPDATE trust_lists SET
+			event_id = ?, event_created_at = ?, entry_count = ?, synced_at = CURRENT_TIMESTAMP
+		WHERE id = ?
+	`, eventID, createdAt, len(npubs), list.ID)
+	if err != nil {
+		return result, err
+	}
+	if err := tx.Commit(); err != nil {
+		return result, err
+	}
+
+	list.EventID = eventID
+	list.EventCreatedAt = createdAt
+	list.EntryCount = len(npubs)
+
+	changes, err := syncListEntries(db)
+	if err != nil {
+		return result, err
+	}
+
+	result.Applied = true
+	for _, c := range changes {
+		if c.Added {
+			result.Added = append(result.Added, c.Npub)
+		} else {
+			result.Removed = append(result.Removed, c.Npub)
+		}
+	}
+
+	database.LogActivity("trust_list_synced", fmt.Sprintf("%s list of %s: %d entries, %d added, %d removed",
+		list.Target, list.OwnerNpub, len(npubs), len(result.Added), len(result.Removed)))
+	return result, nil
+}
+
+// Resync reapplies the subscribed lists to the whitelist and blacklist, so
+// manual changes that lift or add a conflict take effect right away
+func (l *ListSubscriptions) Resync() error {
+	_, err := syncListEntries(database.Get())
+	return err
+}
+
+// listChange is an npub added to or removed from the whitelist or blacklist by a list sync
+type listChange struct {
+	Npub  string
+	Added bool
+}
+
+// syncListEntries brings the list-sourced entries of the whitelist and
+// blacklist in line with the entries of all subscribed lists. Manual entries
+// are never changed, and lists do not add npubs that are manually whitelisted
+// to the blacklist, or blacklisted npubs to the whitelist.
+func syncListEntries(db *sql.DB) ([]listChange, error) {
+	blacklist, err := syncListTarget(db, "trust_blacklist", "reason",
+		fmt.Sprintf("%d, %d", nostr.KindMuteList, nostr.KindMuteSet),
+		"SELECT npub FROM trust_whitelist WHERE source = 'manual'")
+	if err != nil {
+		return nil, err
+	}
+
+	// Npubs that just left the blacklist may now be whitelisted
+	whitelist, err := syncListTarget(db, "trust_whitelist", "notes",
+		strconv.Itoa(nostr.KindFollowSet), "SELECT npub FROM trust_blacklist")
+	if err != nil {
+		return nil, err
+	}
+
+	blacklisted := NewBlacklist()
+	for _, c := range blacklist {
+		if !c.Added {
+			continue
+		}
+		// Uploads are stored by hex pubkey
+		pubkey, err := nostr.NpubToHex(c.Npub)
+		if err != nil {
+			continue
+		}
+		if deleted, err := blacklisted.PurgeContent(pubkey); err != nil {
+			log.Error().Err(err).Str("npub", c.Npub).Msg("Failed to purge content of blacklisted user")
+		} else if deleted > 0 {
+			log.Info().Int64("count", deleted).Str("npub", c.Npub).Msg("Deleted torrents from blacklisted user")
+		}
+	}
+
+	return append(blacklist, whitelist...), nil
+}
+
+// syncListTarget syncs the entries of lists of the given kinds into a trust
+// table. excluded selects npubs the lists may not add. The note column names
+// the list an entry was imported from.
+func syncListTarget(db *sql.DB, table, noteColumn, kinds, excluded string) ([]listChange, error) {
+	var changes []listChange
+
+	listed := `
+		SELECT e.npub FROM trust_list_entries e
+		JOIN trust_lists l ON l.id = e.list_id
+		WHERE l.kind IN (` + kinds + `)`
+
+	// Drop entries no list contains anymore, or that are now excluded
+	stale, err := queryNpubs(db, `
+		SELECT npub FROM `+table+`
+		WHERE source = 'list' AND (npub NOT IN (`+listed+`) OR npub IN (`+excluded+`))`)
+	if err != nil {
+		return nil, err
+	}
+	for _, npub := range stale {
+		if _, err := db.Exec("DELETE FROM "+table+" WHERE npub = ? AND source = 'list'", npub); err != nil {
+			return changes, err
+		}
+		changes = append(changes, listChange{Npub: npub})
+	}
+
+	// Point entries of removed lists at a list that still contains them
+	_, err = db.Exec(`
+		UPDATE ` + table + ` SET list_id = (
+			SELECT MIN(e.list_id) FROM trust_list_entries e
+			JOIN trust_lists l ON l.id = e.list_id
+			WHERE l.kind IN (` + kinds + `) AND e.npub = ` + table + `.npub
+		)
+		WHERE source = 'list' AND list_id NOT IN (SELECT id FROM trust_lists)
+	`)
+	if err != nil {
+		return changes, err
+	}
+
+	// Add listed npubs that are not in the table yet
+	rows, err := db.Query(`
+		SELECT e.npub, MIN(l.id), l.owner_npub, l.kind FROM trust_list_entries e
+		JOIN trust_lists l ON l.id = e.list_id
+		WHERE l.kind IN (` + kinds + `)
+		AND e.npub NOT IN (SELECT npub FROM ` + table + `)
+		AND e.npub NOT IN (` + excluded + `)
+		GROUP BY e.npub
+	`)
+	if err != nil {
+		return changes, err
+	}
+
+	type entry struct {
+		npub, owner string
+		listID      int64
+		kind        int
+	}
+	var missing []entry
+	for rows.Next() {
+		var e entry
+		if err := rows.Scan(&e.npub, &e.listID, &e.owner, &e.kind); err != nil {
+			continue
+		}
+		missing = append(missing, e)
+	}
+	rows.Close()
+
+	for _, e := range missing {
+		_, err := db.Exec(`
+			INSERT OR IGNORE INTO `+table+` (npub, `+noteColumn+`, source, list_id)
+			VALUES (?, ?, 'list', ?)
+		`, e.npub, listLabels[e.kind]+e.owner, e.listID)
+		if err != nil {
+			return changes, err
+		}
+		changes = append(changes, listChange{Npub: e.npub, Added: true})
+	}
+
+	return changes, nil
+}
+
+// IsListEntry reports whether a whitelist or blacklist entry was imported from a list
+func IsListEntry(table, npub string) bool {
+	var source sql.NullString
+	database.Get().QueryRow("SELECT source FROM "+table+" WHERE npub = ?", npub).Scan(&source)
+	return source.String == SourceList
+}
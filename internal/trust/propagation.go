@@ -0,0 +1,251 @@
+package trust
+
+import (
+	"math"
+	"sort"
+)
+
+// Propagation parameters
+const (
+	trustDamping     = 0.85 // share of a user's trust passed on to the users they follow
+	trustIterations  = 50
+	trustTolerance   = 1e-12
+	maxContributions = 10 // contributions kept to explain a score
+)
+
+// Contribution kinds
+const (
+	ContributionSeed               = "seed"                // whitelisted or our own identity
+	ContributionFollow             = "follow"              // followed by Npub
+	ContributionMute               = "mute"                // muted by Npub
+	ContributionFollowsBlacklisted = "follows_blacklisted" // follows blacklisted Npub
+	ContributionBlacklisted        = "blacklisted"
+)
+
+// trustGraph is the input of trust propagation, keyed by npub
+type trustGraph struct {
+	Seeds       []string            // positive seeds
+	Blacklisted []string            // negative seeds
+	Follows     map[string][]string // follower -> followed
+	Mutes       map[string][]string // muter -> muted
+}
+
+// Contribution is one reason a pubkey gained or lost trust. Amounts are in
+// the same unit as Score.Trust.
+type Contribution struct {
+	Kind   string  `json:"kind"`
+	Npub   string  `json:"npub,omitempty"`
+	Amount float64 `json:"amount"`
+}
+
+// Score is the propagated trust of a pubkey
+type Score struct {
+	Npub          string         `json:"npub"`
+	Pubkey        string         `json:"pubkey"`
+	Score         float64        `json:"score"`
+	Trust         float64        `json:"trust"`
+	Distrust      float64        `json:"distrust"`
+	Seed          bool           `json:"seed"`
+	Blacklisted   bool           `json:"blacklisted"`
+	MeetsMinScore bool           `json:"meets_min_score"`
+	Contributions []Contribution `json:"contributions"`
+	ComputedAt    string         `json:"computed_at,omitempty"`
+}
+
+// propagateTrust computes a personalised PageRank from the seeds over the
+// follow graph. Blacklisted users are negative seeds: they score -100, pass
+// no trust on, and whoever follows them is charged the trust spent on them.
+// Mutes take away trust the same way follows give it.
+func propagateTrust(g trustGraph) map[string]*Score {
+	blacklisted := make(map[string]bool, len(g.Blacklisted))
+	for _, npub := range g.Blacklisted {
+		blacklisted[npub] = true
+	}
+
+	seen := make(map[string]bool)
+	var seeds []string
+	for _, npub := range g.Seeds {
+		if !blacklisted[npub] && !seen[npub] {
+			seen[npub] = true
+			seeds = append(seeds, npub)
+		}
+	}
+
+	follows := distinctEdges(g.Follows)
+	mutes := distinctEdges(g.Mutes)
+	rank := personalizedRank(seeds, follows, blacklisted)
+
+	scores := make(map[string]*Score)
+	get := func(npub string) *Score {
+		s, ok := scores[npub]
+		if !ok {
+			s = &Score{Npub: npub}
+			scores[npub] = s
+		}
+		return s
+	}
+
+	for npub, r := range rank {
+		get(npub).Trust = r
+	}
+	for _, npub := range seeds {
+		s := get(npub)
+		s.Seed = true
+		s.Contributions = append(s.Contributions, Contribution{Kind: ContributionSeed, Amount: 1})
+	}
+
+	for follower, followed := range follows {
+		r := rank[follower]
+		if r == 0 || blacklisted[follower] {
+			continue
+		}
+		share := trustDamping * r / float64(len(followed))
+		for _, npub := range followed {
+			get(npub).Contributions = append(get(npub).Contributions, Contribution{Kind: ContributionFollow, Npub: follower, Amount: share})
+
+			// Trust spent endorsing a blacklisted user is charged back
+			if blacklisted[npub] {
+				s := get(follower)
+				s.Distrust += share
+				s.Contributions = append(s.Contributions, Contribution{Kind: ContributionFollowsBlacklisted, Npub: npub, Amount: -share})
+			}
+		}
+	}
+
+	for muter, muted := range mutes {
+		r := rank[muter]
+		if r == 0 || blacklisted[muter] {
+			continue
+		}
+		share := trustDamping * r / float64(len(muted))
+		for _, npub := range muted {
+			s := get(npub)
+			s.Distrust += share
+			s.Contributions = append(s.Contributions, Contribution{Kind: ContributionMute, Npub: muter, Amount: -share})
+		}
+	}
+
+	for npub := range blacklisted {
+		s := get(npub)
+		s.Blacklisted = true
+		s.Contributions = append(s.Contributions, Contribution{Kind: ContributionBlacklisted, Amount: -1})
+	}
+
+	for _, s := range scores {
+		switch {
+		case s.Blacklisted:
+			s.Score = -100
+		case s.Seed:
+			s.Score = 100
+		default:
+			s.Score = scaleScore(s.Trust - s.Distrust)
+		}
+		s.Contributions = topContributions(s.Contributions)
+	}
+
+	return scores
+}
+
+// personalizedRank runs PageRank personalised to the seeds. Trust reaching a
+// user who follows nobody, or a blacklisted user, returns to the seeds. Ranks
+// are relative to the mean rank of the seeds.
+func personalizedRank(seeds []string, follows map[string][]string, blacklisted map[string]bool) map[string]float64 {
+	if len(seeds) == 0 {
+		return nil
+	}
+
+	share := 1 / float64(len(seeds))
+	rank := make(map[string]float64, len(seeds))
+	for _, npub := range seeds {
+		rank[npub] = share
+	}
+
+	for i := 0; i < trustIterations; i++ {
+		next := make(map[string]float64, len(rank))
+		returned := 0.0
+		for npub, r := range rank {
+			followed := follows[npub]
+			if len(followed) == 0 || blacklisted[npub] {
+				returned += r
+				continue
+			}
+			for _, f := range followed {
+				next[f] += trustDamping * r / float64(len(followed))
+			}
+			returned += (1 - trustDamping) * r
+		}
+		for _, npub := range seeds {
+			next[npub] += returned * share
+		}
+
+		delta := 0.0
+		for npub, r := range next {
+			delta += math.Abs(r - rank[npub])
+		}
+		for npub, r := range rank {
+			if _, ok := next[npub]; !ok {
+				delta += r
+			}
+		}
+
+		rank = next
+		if delta < trustTolerance {
+			break
+		}
+	}
+
+	seedRank := 0.0
+	for _, npub := range seeds {
+		seedRank += rank[npub] * share
+	}
+	for npub := range rank {
+		rank[npub] /= seedRank
+	}
+	return rank
+}
+
+// scaleScore maps a net rank onto -100 to 100. A seed's rank scores 100 and
+// every tenfold dilution of trust costs 10 points.
+func scaleScore(net float64) float64 {
+	if net == 0 {
+		return 0
+	}
+
+	magnitude := math.Max(0, math.Min(100, 100+10*math.Log10(math.Abs(net))))
+	magnitude = math.Round(magnitude*100) / 100
+	if net < 0 {
+		return -magnitude
+	}
+	return magnitude
+}
+
+// distinctEdges removes self references and duplicates from an adjacency list
+func distinctEdges(edges map[string][]string) map[string][]string {
+	result := make(map[string][]string, len(edges))
+	for from, targets := range edges {
+		seen := make(map[string]bool, len(targets))
+		for _, to := range targets {
+			if to == from || seen[to] {
+				continue
+			}
+			seen[to] = true
+			result[from] = append(result[from], to)
+		}
+	}
+	return result
+}
+
+// topContributions returns the largest contributions by magnitude
+func topContributions(contributions []Contribution) []Contribution {
+	sort.Slice(contributions, func(i, j int) bool {
+		a, b := math.Abs(contributions[i].Amount), math.Abs(contributions[j].Amount)
+		if a != b {
+			return a > b
+		}
+		return contributions[i].Npub < contributions[j].Npub
+	})
+	if len(contributions) > maxContributions {
+		contributions = contributions[:maxContributions]
+	}
+	return contributions
+}
@@ -0,0 +1,62 @@
+package trust
+
+import "testing"
+
+func TestPropagateTrust(t *testing.T) {
+	scores := propagateTrust(trustGraph{
+		Seeds:       []string{"seed"},
+		Blacklisted: []string{"spammer"},
+		Follows: map[string][]string{
+			"seed":  {"alice", "bob"},
+			"alice": {"carol", "spammer"},
+			"bob":   {"carol", "bob"},
+		},
+		Mutes: map[string][]string{
+			"seed": {"dave"},
+		},
+	})
+
+	if s := scores["seed"]; s == nil || s.Score != 100 || !s.Seed {
+		t.Fatalf("seed = %+v, want score 100", s)
+	}
+	if s := scores["spammer"]; s == nil || s.Score != -100 || !s.Blacklisted {
+		t.Errorf("spammer = %+v, want score -100", s)
+	}
+	if s := scores["dave"]; s == nil || s.Score >= 0 {
+		t.Errorf("dave = %+v, want a negative score for a user muted by the seed", s)
+	}
+
+	alice, bob, carol := scores["alice"], scores["bob"], scores["carol"]
+	if alice == nil || bob == nil || carol == nil {
+		t.Fatal("followed users should be scored")
+	}
+	if alice.Distrust == 0 || alice.Score >= bob.Score {
+		t.Errorf("alice = %.2f, bob = %.2f: following a blacklisted user should cost trust", alice.Score, bob.Score)
+	}
+	if carol.Score <= 0 || carol.Score >= 100 {
+		t.Errorf("carol = %.2f, want a positive score below the seed's", carol.Score)
+	}
+	if len(carol.Contributions) != 2 || carol.Contributions[0].Kind != ContributionFollow {
+		t.Errorf("carol contributions = %+v, want follows from alice and bob", carol.Contributions)
+	}
+}
+
+func TestScaleScore(t *testing.T) {
+	tests := []struct {
+		net  float64
+		want float64
+	}{
+		{2, 100},
+		{1, 100},
+		{0.1, 90},
+		{-0.01, -80},
+		{1e-12, 0},
+		{0, 0},
+	}
+
+	for _, tt := range tests {
+		if got := scaleScore(tt.net); got != tt.want {
+			t.Errorf("scaleScore(%g) = %g, want %g", tt.net, got, tt.want)
+		}
+	}
+}
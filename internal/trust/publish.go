@@ -0,0 +1,86 @@
+package trust
+
+import (
+	"database/sql"
+	"strconv"
+
+	"github.com/gmonarque/lighthouse/internal/database"
+	"github.com/gmonarque/lighthouse/internal/nostr"
+	gonostr "github.com/nbd-wtf/go-nostr"
+)
+
+// PublishedWhitelistDTag identifies the follow set the whitelist is published as
+const PublishedWhitelistDTag = "lighthouse-whitelist"
+
+// PublishedBlacklistDTag identifies the kind mute set the blacklist is
+// published as. NIP-51 kind mute sets are named after the muted kind.
+var PublishedBlacklistDTag = strconv.Itoa(nostr.KindTorrent)
+
+// PublishedLists returns unsigned NIP-51 events for the whitelist, as a follow
+// set, and the blacklist, as a mute set for torrent events. Aliases are
+// published as petnames, notes and reasons as the note of each entry. Lists
+// cannot carry time windows, so only entries currently in force are published.
+func PublishedLists() ([]*gonostr.Event, error) {
+	db := database.Get()
+
+	whitelist, err := queryListEntries(db, `
+		SELECT npub, alias, notes, COALESCE(effective_from, 0), COALESCE(effective_until, 0)
+		FROM trust_whitelist ORDER BY npub
+	`)
+	if err != nil {
+		return nil, err
+	}
+
+	blacklist, err := queryListEntries(db, `
+		SELECT npub, NULL, reason, COALESCE(effective_from, 0), COALESCE(effective_until, 0)
+		FROM trust_blacklist ORDER BY npub
+	`)
+	if err != nil {
+		return nil, err
+	}
+
+	return []*gonostr.Event{
+		nostr.CreateListEvent(nostr.KindFollowSet, PublishedWhitelistDTag, "Lighthouse whitelist",
+			"Uploaders trusted by this Lighthouse node", whitelist),
+		nostr.CreateListEvent(nostr.KindMuteSet, PublishedBlacklistDTag, "Lighthouse blacklist",
+			"Uploaders blocked by this Lighthouse node", blacklist),
+	}, nil
+}
+
+// queryListEntries reads npub, petname, note and window rows into the list
+// entries in force, keyed by hex pubkey
+func queryListEntries(db *sql.DB, query string) ([]nostr.ListEntry, error) {
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	at := now()
+	var entries []nostr.ListEntry
+	for rows.Next() {
+		var npub string
+		var petname, note sql.NullString
+		var window Window
+		if err := rows.Scan(&npub, &petname, &note, &window.From, &window.Until); err != nil {
+			continue
+		}
+		if entry, ok := publishedEntry(npub, petname.String, note.String, window, at); ok {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, rows.Err()
+}
+
+// publishedEntry returns the list entry for an npub, or false if the npub is
+// invalid or its entry is not in force at the given time
+func publishedEntry(npub, petname, note string, window Window, at int64) (nostr.ListEntry, bool) {
+	if !window.Contains(at) {
+		return nostr.ListEntry{}, false
+	}
+	pubkey, err := nostr.NpubToHex(npub)
+	if err != nil {
+		return nostr.ListEntry{}, false
+	}
+	return nostr.ListEntry{Pubkey: pubkey, Petname: petname, Note: note}, true
+}
@@ -0,0 +1,41 @@
+package trust
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gmonarque/lighthouse/internal/nostr"
+)
+
+func TestPublishedEntry(t *testing.T) {
+	pubkey := strings.Repeat("ab", 32)
+	npub, err := nostr.HexToNpub(pubkey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		npub   string
+		window Window
+		want   bool
+	}{
+		{"unbounded", npub, Window{}, true},
+		{"in force", npub, Window{From: 500, Until: 2000}, true},
+		{"expired", npub, Window{Until: 1000}, false},
+		{"not yet in force", npub, Window{From: 1001}, false},
+		{"invalid npub", "npub1invalid", Window{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry, ok := publishedEntry(tt.npub, "alias", "note", tt.window, 1000)
+			if ok != tt.want {
+				t.Fatalf("publishedEntry() published = %t, want %t", ok, tt.want)
+			}
+			if ok && (entry.Pubkey != pubkey || entry.Petname != "alias" || entry.Note != "note") {
+				t.Errorf("publishedEntry() = %+v", entry)
+			}
+		})
+	}
+}
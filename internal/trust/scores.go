@@ -0,0 +1,273 @@
+package trust
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/gmonarque/lighthouse/internal/config"
+	"github.com/gmonarque/lighthouse/internal/database"
+	"github.com/gmonarque/lighthouse/internal/nostr"
+	"github.com/rs/zerolog/log"
+)
+
+// scoreState tracks the inputs the stored trust scores were computed from
+var scoreState struct {
+	sync.Mutex
+	fingerprint string
+	generation  uint64
+}
+
+// RefreshScores recomputes the propagated trust scores if the whitelist,
+// blacklist, follow graph, mute graph or trust settings changed since they
+// were last computed. Returns the generation of the stored scores, which
+// increases every time they are recomputed.
+func (w *WebOfTrust) RefreshScores() (uint64, error) {
+	scoreState.Lock()
+	defer scoreState.Unlock()
+
+	fingerprint, err := scoreFingerprint()
+	if err != nil {
+		return scoreState.generation, err
+	}
+	if fingerprint == scoreState.fingerprint {
+		return scoreState.generation, nil
+	}
+
+	graph, err := loadTrustGraph()
+	if err != nil {
+		return scoreState.generation, err
+	}
+
+	scores := propagateTrust(graph)
+	if err := storeScores(scores); err != nil {
+		return scoreState.generation, err
+	}
+
+	scoreState.fingerprint = fingerprint
+	scoreState.generation++
+
+	log.Debug().Int("pubkeys", len(scores)).Msg("Trust scores recomputed")
+	return scoreState.generation, nil
+}
+
+// scoreFingerprint summarises everything the trust scores depend on
+func scoreFingerprint() (string, error) {
+	cfg := config.Get()
+
+	// Time bounded entries change the graph when they come into or go out of force
+	entries := "COUNT(*) || ':' || COALESCE(SUM(id), 0) || ':' || COALESCE(SUM(effective_from), 0) || ':' || " +
+		"COALESCE(SUM(effective_until), 0) || ':' || COALESCE(SUM(" + activeCondition + "), 0)"
+
+	now := now()
+	var whitelist, blacklist, follows, mutes string
+	err := database.Get().QueryRow(`
+		SELECT
+			(SELECT `+entries+` FROM trust_whitelist),
+			(SELECT `+entries+` FROM trust_blacklist),
+			(SELECT COUNT(*) || ':' || COALESCE(SUM(id), 0) || ':' || COALESCE(SUM(depth), 0) || ':' ||
+				COALESCE(MAX(updated_at), '') FROM trust_follows),
+			(SELECT COUNT(*) || ':' || COALESCE(SUM(id), 0) || ':' || COALESCE(MAX(updated_at), '') FROM trust_mutes)
+	`, now, now, now, now).Scan(&whitelist, &blacklist, &follows, &mutes)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s|%d|%t|%s|%s|%s|%s", cfg.Nostr.Identity.Npub, cfg.Trust.Depth, cfg.Trust.CrawlWhitelist,
+		whitelist, blacklist, follows, mutes), nil
+}
+
+// loadTrustGraph reads the seeds, blacklist, follows and mutes scores are computed from.
+// Seeds are our own identity and the whitelist entries in force; follows are
+// limited to the trust depth. Only blacklist entries without time bounds are
+// negative seeds, but the follows and mutes of npubs whose blacklist entry is
+// in force are ignored, as a compromised key no longer speaks for its owner.
+func loadTrustGraph() (trustGraph, error) {
+	cfg := config.Get()
+	db := database.Get()
+
+	graph := trustGraph{
+		Follows: make(map[string][]string),
+		Mutes:   make(map[string][]string),
+	}
+
+	if cfg.Nostr.Identity.Npub != "" {
+		graph.Seeds = append(graph.Seeds, cfg.Nostr.Identity.Npub)
+	}
+
+	now := now()
+	whitelist, err := queryNpubs(db, "SELECT npub FROM trust_whitelist WHERE "+activeCondition, now, now)
+	if err != nil {
+		return graph, err
+	}
+	graph.Seeds = append(graph.Seeds, whitelist...)
+
+	graph.Blacklisted, err = queryNpubs(db, `
+		SELECT npub FROM trust_blacklist
+		WHERE effective_from IS NULL AND effective_until IS NULL`)
+	if err != nil {
+		return graph, err
+	}
+
+	silenced, err := queryNpubs(db, "SELECT npub FROM trust_blacklist WHERE "+activeCondition, now, now)
+	if err != nil {
+		return graph, err
+	}
+
+	if cfg.Trust.Depth > 0 {
+		roots, args := followRoots()
+		err = queryEdges(db, graph.Follows, `
+			SELECT follower_npub, followed_npub FROM trust_follows
+			WHERE depth <= ? AND `+roots,
+			append([]interface{}{cfg.Trust.Depth}, args...)...)
+		if err != nil {
+			return graph, err
+		}
+	}
+
+	if err := queryEdges(db, graph.Mutes, "SELECT muter_npub, muted_npub FROM trust_mutes"); err != nil {
+		return graph, err
+	}
+
+	for _, npub := range silenced {
+		delete(graph.Follows, npub)
+		delete(graph.Mutes, npub)
+	}
+
+	return graph, nil
+}
+
+// queryNpubs returns the npubs selected by a single column query
+func queryNpubs(db *sql.DB, query string, args ...interface{}) ([]string, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var npubs []string
+	for rows.Next() {
+		var npub string
+		if err := rows.Scan(&npub); err != nil {
+			continue
+		}
+		npubs = append(npubs, npub)
+	}
+	return npubs, rows.Err()
+}
+
+// queryEdges adds the edges selected by a two column query to an adjacency list
+func queryEdges(db *sql.DB, edges map[string][]string, query string, args ...interface{}) error {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var from, to string
+		if err := rows.Scan(&from, &to); err != nil {
+			continue
+		}
+		edges[from] = append(edges[from], to)
+	}
+	return rows.Err()
+}
+
+// storeScores replaces the stored trust scores
+func storeScores(scores map[string]*Score) error {
+	tx, err := database.Get().Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM trust_scores"); err != nil {
+		return err
+	}
+
+	stmt, err := tx.Prepare(`
+		INSERT OR REPLACE INTO trust_scores (pubkey, npub, score, trust, distrust, is_seed, is_blacklisted, contributions)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for npub, s := range scores {
+		pubkey, err := nostr.NpubToHex(npub)
+		if err != nil {
+			continue
+		}
+		contributions, _ := json.Marshal(s.Contributions)
+		if _, err := stmt.Exec(pubkey, npub, s.Score, s.Trust, s.Distrust, s.Seed, s.Blacklisted, string(contributions)); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetScore returns the stored trust score of an npub, or nil if it has none
+func (w *WebOfTrust) GetScore(npub string) (*Score, error) {
+	rows, err := database.Get().Query(scoreQuery+" WHERE npub = ?", npub)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	scores, err := scanScores(rows)
+	if err != nil || len(scores) == 0 {
+		return nil, err
+	}
+	return &scores[0], nil
+}
+
+// GetScores returns stored trust scores from highest to lowest, and the total number of scored pubkeys
+func (w *WebOfTrust) GetScores(limit, offset int) ([]Score, int, error) {
+	db := database.Get()
+
+	var total int
+	if err := db.QueryRow("SELECT COUNT(*) FROM trust_scores").Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := db.Query(scoreQuery+" ORDER BY score DESC, npub LIMIT ? OFFSET ?", limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	scores, err := scanScores(rows)
+	return scores, total, err
+}
+
+const scoreQuery = `
+	SELECT pubkey, npub, score, trust, distrust, is_seed, is_blacklisted, contributions, computed_at
+	FROM trust_scores`
+
+// scanScores reads rows selected with scoreQuery
+func scanScores(rows *sql.Rows) ([]Score, error) {
+	minScore := config.Get().Trust.MinScore
+
+	scores := make([]Score, 0)
+	for rows.Next() {
+		var s Score
+		var contributions sql.NullString
+		if err := rows.Scan(&s.Pubkey, &s.Npub, &s.Score, &s.Trust, &s.Distrust, &s.Seed, &s.Blacklisted,
+			&contributions, &s.ComputedAt); err != nil {
+			return nil, err
+		}
+		if contributions.Valid {
+			json.Unmarshal([]byte(contributions.String), &s.Contributions)
+		}
+		if s.Contributions == nil {
+			s.Contributions = []Contribution{}
+		}
+		s.MeetsMinScore = s.Score > minScore
+		scores = append(scores, s)
+	}
+	return scores, rows.Err()
+}
@@ -13,16 +13,25 @@ func NewWhitelist() *Whitelist {
 	return &Whitelist{}
 }
 
-// Add adds an npub to the whitelist
-func (w *Whitelist) Add(npub, alias, notes string) error {
+// Add adds an npub to the whitelist. The window limits the events of the
+// npub that are trusted.
+func (w *Whitelist) Add(npub, alias, notes string, window Window) error {
+	if err := window.Validate(); err != nil {
+		return err
+	}
+
 	db := database.Get()
 	_, err := db.Exec(`
-		INSERT INTO trust_whitelist (npub, alias, notes)
-		VALUES (?, ?, ?)
+		INSERT INTO trust_whitelist (npub, alias, notes, effective_from, effective_until)
+		VALUES (?, ?, ?, ?, ?)
 		ON CONFLICT(npub) DO UPDATE SET
 			alias = excluded.alias,
-			notes = excluded.notes
-	`, npub, alias, notes)
+			notes = excluded.notes,
+			effective_from = excluded.effective_from,
+			effective_until = excluded.effective_until,
+			source = 'manual',
+			list_id = NULL
+	`, npub, alias, notes, nullableTime(window.From), nullableTime(window.Until))
 
 	if err == nil {
 		database.LogActivity(models.ActivityWhitelistAdd, npub)
@@ -53,7 +62,7 @@ func (w *Whitelist) Contains(npub string) bool {
 func (w *Whitelist) GetAll() ([]models.TrustWhitelistEntry, error) {
 	db := database.Get()
 	rows, err := db.Query(`
-		SELECT id, npub, alias, notes, added_at
+		SELECT id, npub, alias, notes, added_at, COALESCE(effective_from, 0), COALESCE(effective_until, 0)
 		FROM trust_whitelist
 		ORDER BY added_at DESC
 	`)
@@ -66,7 +75,8 @@ func (w *Whitelist) GetAll() ([]models.TrustWhitelistEntry, error) {
 	for rows.Next() {
 		var entry models.TrustWhitelistEntry
 		var alias, notes *string
-		if err := rows.Scan(&entry.ID, &entry.Npub, &alias, &notes, &entry.AddedAt); err != nil {
+		if err := rows.Scan(&entry.ID, &entry.Npub, &alias, &notes, &entry.AddedAt,
+			&entry.EffectiveFrom, &entry.EffectiveUntil); err != nil {
 			continue
 		}
 		if alias != nil {
@@ -0,0 +1,233 @@
+package trust
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gmonarque/lighthouse/internal/database"
+	"github.com/gmonarque/lighthouse/internal/nostr"
+)
+
+// Window bounds the events a whitelist or blacklist entry applies to by their
+// created_at, in unix seconds. A zero bound leaves that side open.
+type Window struct {
+	From  int64 `json:"effective_from,omitempty"`
+	Until int64 `json:"effective_until,omitempty"`
+}
+
+// Bounded reports whether the window restricts events at all
+func (w Window) Bounded() bool {
+	return w.From != 0 || w.Until != 0
+}
+
+// Contains reports whether an event created at createdAt falls inside the window
+func (w Window) Contains(createdAt int64) bool {
+	return (w.From == 0 || createdAt >= w.From) && (w.Until == 0 || createdAt < w.Until)
+}
+
+// Validate checks that the bounds are timestamps in order
+func (w Window) Validate() error {
+	if w.From < 0 || w.Until < 0 {
+		return errors.New("effective_from and effective_until must be unix timestamps")
+	}
+	if w.From != 0 && w.Until != 0 && w.Until <= w.From {
+		return errors.New("effective_until must be after effective_from")
+	}
+	return nil
+}
+
+// uploadCreatedAt is the created_at of the event behind an upload. Uploads
+// indexed before it was recorded fall back to the time they were indexed.
+const uploadCreatedAt = "COALESCE(%[1]s.event_created_at, CAST(strftime('%%s', %[1]s.uploaded_at) AS INTEGER))"
+
+// UploadCondition returns a condition selecting the uploads of torrent_uploads
+// aliased alias whose event falls inside the window
+func (w Window) UploadCondition(alias string) (string, []interface{}) {
+	createdAt := fmt.Sprintf(uploadCreatedAt, alias)
+
+	var conditions []string
+	var args []interface{}
+	if w.From != 0 {
+		conditions = append(conditions, createdAt+" >= ?")
+		args = append(args, w.From)
+	}
+	if w.Until != 0 {
+		conditions = append(conditions, createdAt+" < ?")
+		args = append(args, w.Until)
+	}
+	if len(conditions) == 0 {
+		return "1", nil
+	}
+	return strings.Join(conditions, " AND "), args
+}
+
+// activeCondition is a condition on a whitelist or blacklist row that is true
+// while the entry is in force. Its argument is the current unix time, twice.
+const activeCondition = "(effective_from IS NULL OR effective_from <= ?) AND (effective_until IS NULL OR effective_until > ?)"
+
+// UploaderPolicy decides which uploads are trusted, by hex pubkey and the
+// created_at of the upload event
+type UploaderPolicy struct {
+	// Trusted uploaders, including those trusted only within a window.
+	// Permanently blacklisted uploaders are left out.
+	Trusted map[string]bool
+	// Windows of uploaders trusted only through a bounded whitelist entry
+	Whitelisted map[string]Window
+	// Windows of blacklisted uploaders
+	Blacklisted map[string]Window
+}
+
+// Blocks reports whether a blacklist entry covers an event of pubkey created at createdAt
+func (p *UploaderPolicy) Blocks(pubkey string, createdAt int64) bool {
+	w, ok := p.Blacklisted[pubkey]
+	return ok && w.Contains(createdAt)
+}
+
+// Allows reports whether an event of pubkey created at createdAt is trusted
+func (p *UploaderPolicy) Allows(pubkey string, createdAt int64) bool {
+	return p.Judge(pubkey, createdAt) == UploadTrusted
+}
+
+// Pubkeys returns the sorted trusted uploaders
+func (p *UploaderPolicy) Pubkeys() []string {
+	pubkeys := make([]string, 0, len(p.Trusted))
+	for pubkey := range p.Trusted {
+		pubkeys = append(pubkeys, pubkey)
+	}
+	slices.Sort(pubkeys)
+	return pubkeys
+}
+
+// UploadCondition returns a condition on torrent_uploads aliased alias that is
+// true for trusted uploads. It is "0" if nobody is trusted.
+func (p *UploaderPolicy) UploadCondition(alias string) (string, []interface{}) {
+	pubkeys := p.Pubkeys()
+	if len(pubkeys) == 0 {
+		return "0", nil
+	}
+
+	args := make([]interface{}, len(pubkeys))
+	for i, pubkey := range pubkeys {
+		args[i] = pubkey
+	}
+	condition := alias + ".uploader_npub IN (" + strings.TrimSuffix(strings.Repeat("?,", len(pubkeys)), ",") + ")"
+
+	// Uploads outside the window of a bounded whitelist entry
+	for _, pubkey := range sortedKeys(p.Whitelisted) {
+		inside, windowArgs := p.Whitelisted[pubkey].UploadCondition(alias)
+		condition += " AND NOT (" + alias + ".uploader_npub = ? AND NOT (" + inside + "))"
+		args = append(append(args, pubkey), windowArgs...)
+	}
+
+	// Uploads inside the window of a bounded blacklist entry
+	for _, pubkey := range sortedKeys(p.Blacklisted) {
+		if !p.Trusted[pubkey] {
+			continue
+		}
+		inside, windowArgs := p.Blacklisted[pubkey].UploadCondition(alias)
+		condition += " AND NOT (" + alias + ".uploader_npub = ? AND " + inside + ")"
+		args = append(append(args, pubkey), windowArgs...)
+	}
+
+	return condition, args
+}
+
+// sortedKeys returns the keys of a window map in order
+func sortedKeys(windows map[string]Window) []string {
+	keys := make([]string, 0, len(windows))
+	for key := range windows {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// GetUploaderPolicy returns the trusted uploaders together with the time
+// bounds of their whitelist and blacklist entries
+func (w *WebOfTrust) GetUploaderPolicy() (*UploaderPolicy, error) {
+	whitelisted, followed, err := w.trustedUploaders()
+	if err != nil {
+		return nil, err
+	}
+
+	whitelistWindows, err := queryWindows("trust_whitelist")
+	if err != nil {
+		return nil, err
+	}
+	blacklistWindows, err := queryWindows("trust_blacklist")
+	if err != nil {
+		return nil, err
+	}
+
+	policy := &UploaderPolicy{
+		Trusted:     make(map[string]bool),
+		Whitelisted: make(map[string]Window),
+		Blacklisted: make(map[string]Window, len(blacklistWindows)),
+	}
+
+	for npub, window := range blacklistWindows {
+		if pubkey, err := nostr.NpubToHex(npub); err == nil {
+			policy.Blacklisted[pubkey] = window
+		}
+	}
+
+	isFollowed := make(map[string]bool, len(followed))
+	for _, npub := range followed {
+		isFollowed[npub] = true
+	}
+
+	for _, npub := range append(whitelisted, followed...) {
+		pubkey, err := nostr.NpubToHex(npub)
+		if err != nil {
+			continue
+		}
+		if window, ok := policy.Blacklisted[pubkey]; ok && !window.Bounded() {
+			continue
+		}
+		policy.Trusted[pubkey] = true
+
+		// Follows trust an uploader regardless of the whitelist bounds
+		if window := whitelistWindows[npub]; window.Bounded() && !isFollowed[npub] {
+			policy.Whitelisted[pubkey] = window
+		}
+	}
+
+	return policy, nil
+}
+
+// queryWindows returns the time bounds of the entries of the whitelist or blacklist, by npub
+func queryWindows(table string) (map[string]Window, error) {
+	rows, err := database.Get().Query(`
+		SELECT npub, COALESCE(effective_from, 0), COALESCE(effective_until, 0) FROM ` + table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	windows := make(map[string]Window)
+	for rows.Next() {
+		var npub string
+		var window Window
+		if err := rows.Scan(&npub, &window.From, &window.Until); err != nil {
+			continue
+		}
+		windows[npub] = window
+	}
+	return windows, rows.Err()
+}
+
+// nullableTime stores an open window bound as NULL
+func nullableTime(t int64) interface{} {
+	if t == 0 {
+		return nil
+	}
+	return t
+}
+
+// now returns the current unix time entries are checked against
+func now() int64 {
+	return time.Now().Unix()
+}
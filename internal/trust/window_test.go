@@ -0,0 +1,55 @@
+package trust
+
+import "testing"
+
+func TestWindow(t *testing.T) {
+	w := Window{From: 100, Until: 200}
+
+	for createdAt, want := range map[int64]bool{99: false, 100: true, 199: true, 200: false} {
+		if got := w.Contains(createdAt); got != want {
+			t.Errorf("Contains(%d) = %t, want %t", createdAt, got, want)
+		}
+	}
+	if !(Window{}).Contains(0) || (Window{}).Bounded() {
+		t.Error("an empty window should be unbounded")
+	}
+
+	if err := (Window{From: 200, Until: 100}).Validate(); err == nil {
+		t.Error("a window ending before it starts should be invalid")
+	}
+	if err := (Window{Until: 100}).Validate(); err != nil {
+		t.Errorf("an open start should be valid: %v", err)
+	}
+}
+
+func TestUploaderPolicy(t *testing.T) {
+	policy := &UploaderPolicy{
+		Trusted:     map[string]bool{"followed": true, "compromised": true, "probation": true},
+		Whitelisted: map[string]Window{"probation": {From: 500}},
+		Blacklisted: map[string]Window{"compromised": {From: 1000}, "spammer": {}},
+	}
+
+	tests := []struct {
+		pubkey    string
+		createdAt int64
+		want      bool
+	}{
+		{"followed", 0, true},
+		{"compromised", 999, true},
+		{"compromised", 1000, false},
+		{"probation", 499, false},
+		{"probation", 500, true},
+		{"spammer", 0, false},
+		{"stranger", 0, false},
+	}
+
+	for _, tt := range tests {
+		if got := policy.Allows(tt.pubkey, tt.createdAt); got != tt.want {
+			t.Errorf("Allows(%s, %d) = %t, want %t", tt.pubkey, tt.createdAt, got, tt.want)
+		}
+	}
+
+	if got := policy.Pubkeys(); len(got) != 3 || got[0] != "compromised" {
+		t.Errorf("Pubkeys() = %v, want the sorted trusted uploaders", got)
+	}
+}
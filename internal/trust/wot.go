@@ -1,8 +1,13 @@
 package trust
 
 import (
+	"database/sql"
+	"fmt"
+	"math"
+
 	"github.com/gmonarque/lighthouse/internal/config"
 	"github.com/gmonarque/lighthouse/internal/database"
+	"github.com/rs/zerolog/log"
 )
 
 // WebOfTrust manages the trust graph
@@ -13,138 +18,138 @@ func NewWebOfTrust() *WebOfTrust {
 	return &WebOfTrust{}
 }
 
-// IsTrusted checks if an npub is trusted based on the current trust depth setting
+// followRoots returns a condition restricting trust_follows to edges reached
+// from a trust root: our own identity, and whitelisted users if they are crawled.
+// Edges imported before roots were recorded are rooted at their follower.
+func followRoots() (string, []interface{}) {
+	cfg := config.Get()
+
+	condition := "COALESCE(root_npub, follower_npub) = ?"
+	if cfg.Trust.CrawlWhitelist {
+		condition = "(" + condition + " OR COALESCE(root_npub, follower_npub) IN (SELECT npub FROM trust_whitelist))"
+	}
+	return condition, []interface{}{cfg.Nostr.Identity.Npub}
+}
+
+// IsTrusted checks if an npub is trusted now based on the current trust depth
+// setting and the minimum propagated trust score. Whitelist and blacklist
+// entries only count while they are in force.
 func (w *WebOfTrust) IsTrusted(npub string) bool {
 	cfg := config.Get()
 	db := database.Get()
+	now := now()
 
 	// Check blacklist first
 	var blacklisted int
-	err := db.QueryRow("SELECT COUNT(*) FROM trust_blacklist WHERE npub = ?", npub).Scan(&blacklisted)
+	err := db.QueryRow("SELECT COUNT(*) FROM trust_blacklist WHERE npub = ? AND "+activeCondition, npub, now, now).Scan(&blacklisted)
 	if err == nil && blacklisted > 0 {
 		return false
 	}
 
+	if !meetsMinScore(db, npub) {
+		return false
+	}
+
 	// Depth 0: Whitelist only
 	if cfg.Trust.Depth == 0 {
 		var whitelisted int
-		err := db.QueryRow("SELECT COUNT(*) FROM trust_whitelist WHERE npub = ?", npub).Scan(&whitelisted)
+		err := db.QueryRow("SELECT COUNT(*) FROM trust_whitelist WHERE npub = ? AND "+activeCondition, npub, now, now).Scan(&whitelisted)
 		return err == nil && whitelisted > 0
 	}
 
 	// Check whitelist (always trusted at any depth)
 	var whitelisted int
-	err = db.QueryRow("SELECT COUNT(*) FROM trust_whitelist WHERE npub = ?", npub).Scan(&whitelisted)
+	err = db.QueryRow("SELECT COUNT(*) FROM trust_whitelist WHERE npub = ? AND "+activeCondition, npub, now, now).Scan(&whitelisted)
 	if err == nil && whitelisted > 0 {
 		return true
 	}
 
 	// Depth 1+: Check follows
-	ownNpub := cfg.Nostr.Identity.Npub
-	if ownNpub == "" {
-		return false
-	}
-
-	var followDepth int
+	roots, args := followRoots()
+	var followed int
 	err = db.QueryRow(`
-		SELECT depth FROM trust_follows
-		WHERE follower_npub = ? AND followed_npub = ? AND depth <= ?
-	`, ownNpub, npub, cfg.Trust.Depth).Scan(&followDepth)
+		SELECT COUNT(*) FROM trust_follows
+		WHERE followed_npub = ? AND depth <= ? AND `+roots,
+		append([]interface{}{npub, cfg.Trust.Depth}, args...)...).Scan(&followed)
 
-	return err == nil && followDepth <= cfg.Trust.Depth
+	return err == nil && followed > 0
+}
+
+// meetsMinScore reports whether an npub's propagated trust score is above
+// trust.min_score. Npubs that have not been scored yet pass.
+func meetsMinScore(db *sql.DB, npub string) bool {
+	var score float64
+	if err := db.QueryRow("SELECT score FROM trust_scores WHERE npub = ?", npub).Scan(&score); err != nil {
+		return true
+	}
+	return score > config.Get().Trust.MinScore
 }
 
-// GetTrustScore calculates the trust score for an npub
+// scoreFilter is a condition excluding npubs whose propagated trust score is
+// at or below trust.min_score
+const scoreFilter = "NOT EXISTS (SELECT 1 FROM trust_scores s WHERE s.npub = %s AND s.score <= ?)"
+
+// GetTrustScore returns the propagated trust score of an npub, from -100 to 100.
+// Npubs whose blacklist entry is in force score -1000, npubs outside the trust graph 0.
 func (w *WebOfTrust) GetTrustScore(npub string) int {
-	cfg := config.Get()
 	db := database.Get()
-	score := 0
+	now := now()
 
-	// Blacklisted = -1000
 	var blacklisted int
-	err := db.QueryRow("SELECT COUNT(*) FROM trust_blacklist WHERE npub = ?", npub).Scan(&blacklisted)
+	err := db.QueryRow("SELECT COUNT(*) FROM trust_blacklist WHERE npub = ? AND "+activeCondition, npub, now, now).Scan(&blacklisted)
 	if err == nil && blacklisted > 0 {
 		return -1000
 	}
 
-	// Whitelisted = +100
-	var whitelisted int
-	err = db.QueryRow("SELECT COUNT(*) FROM trust_whitelist WHERE npub = ?", npub).Scan(&whitelisted)
-	if err == nil && whitelisted > 0 {
-		score += 100
-	}
-
-	// Direct follow = +50
-	ownNpub := cfg.Nostr.Identity.Npub
-	if ownNpub != "" {
-		var depth int
-		err := db.QueryRow(`
-			SELECT depth FROM trust_follows
-			WHERE follower_npub = ? AND followed_npub = ?
-		`, ownNpub, npub).Scan(&depth)
-		if err == nil {
-			switch depth {
-			case 1:
-				score += 50
-			case 2:
-				score += 20
-			}
-		}
+	var score float64
+	if err := db.QueryRow("SELECT score FROM trust_scores WHERE npub = ?", npub).Scan(&score); err != nil {
+		return 0
 	}
-
-	return score
+	return int(math.Round(score))
 }
 
 // GetTrustedUploaders returns all uploaders trusted at the current depth
+// whose propagated trust score is above trust.min_score. Whitelisted uploaders
+// are included even if their entry only covers some of their events.
 func (w *WebOfTrust) GetTrustedUploaders() ([]string, error) {
+	whitelisted, followed, err := w.trustedUploaders()
+	return append(whitelisted, followed...), err
+}
+
+// trustedUploaders returns the whitelisted uploaders and those trusted through
+// follows at the current depth, whose propagated trust score is above trust.min_score
+func (w *WebOfTrust) trustedUploaders() (whitelisted, followed []string, err error) {
 	cfg := config.Get()
 	db := database.Get()
 
-	var uploaders []string
+	// Keep scores in line with the trust graph
+	if _, err := w.RefreshScores(); err != nil {
+		log.Error().Err(err).Msg("Failed to refresh trust scores")
+	}
 
 	// Always include whitelist
-	rows, err := db.Query("SELECT npub FROM trust_whitelist")
+	whitelisted, err = queryNpubs(db, `
+		SELECT npub FROM trust_whitelist
+		WHERE `+fmt.Sprintf(scoreFilter, "trust_whitelist.npub"), cfg.Trust.MinScore)
 	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var npub string
-		if err := rows.Scan(&npub); err != nil {
-			continue
-		}
-		uploaders = append(uploaders, npub)
+		return nil, nil, err
 	}
 
 	if cfg.Trust.Depth == 0 {
-		return uploaders, nil
+		return whitelisted, nil, nil
 	}
 
 	// Include follows at configured depth
-	ownNpub := cfg.Nostr.Identity.Npub
-	if ownNpub == "" {
-		return uploaders, nil
-	}
-
-	rows, err = db.Query(`
-		SELECT followed_npub FROM trust_follows
-		WHERE follower_npub = ? AND depth <= ?
-	`, ownNpub, cfg.Trust.Depth)
+	roots, args := followRoots()
+	followed, err = queryNpubs(db, `
+		SELECT DISTINCT followed_npub FROM trust_follows
+		WHERE depth <= ? AND `+roots+` AND `+fmt.Sprintf(scoreFilter, "trust_follows.followed_npub"),
+		append(append([]interface{}{cfg.Trust.Depth}, args...), cfg.Trust.MinScore)...)
 	if err != nil {
-		return uploaders, nil
-	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var npub string
-		if err := rows.Scan(&npub); err != nil {
-			continue
-		}
-		uploaders = append(uploaders, npub)
+		return whitelisted, nil, nil
 	}
 
-	return uploaders, nil
+	return whitelisted, followed, nil
 }
 
 // AddFollow adds a follow relationship